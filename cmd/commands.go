@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"brok/internal/models"
+	"brok/internal/services"
+	"brok/internal/storage"
+)
+
+// commandDeps зависимости, доступные CLI-командам
+type commandDeps struct {
+	storage               storage.Storage
+	exchangeRateService   *services.ExchangeRateService
+	reconciliationService *services.ReconciliationService
+	instrumentService     *services.InstrumentService
+}
+
+// runCommand выполняет CLI-команду вместо запуска сервера
+func runCommand(ctx context.Context, deps commandDeps, name string, args []string) error {
+	switch name {
+	case "backfill-rates":
+		return backfillRatesCommand(ctx, deps, args)
+	case "reconcile":
+		return reconcileCommand(ctx, deps, args)
+	case "seed-instruments":
+		return seedInstrumentsCommand(ctx, deps, args)
+	}
+	return fmt.Errorf("unknown command: %s", name)
+}
+
+// backfillRatesCommand загружает историю курсов за период:
+//
+//	brok backfill-rates -from 2024-01-01 -to 2024-03-31 [-currencies USD,EUR,RUB] [-file eurofxref-hist.csv]
+func backfillRatesCommand(ctx context.Context, deps commandDeps, args []string) error {
+	flags := flag.NewFlagSet("backfill-rates", flag.ContinueOnError)
+	fromStr := flags.String("from", "", "начальная дата (YYYY-MM-DD)")
+	toStr := flags.String("to", time.Now().UTC().Format("2006-01-02"), "конечная дата (YYYY-MM-DD)")
+	currenciesStr := flags.String("currencies", "", "валюты через запятую (по умолчанию — все поддерживаемые)")
+	filePath := flags.String("file", "", "CSV-файл с историей курсов вместо источника")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	from, err := time.Parse("2006-01-02", *fromStr)
+	if err != nil {
+		return fmt.Errorf("invalid -from, use YYYY-MM-DD: %w", err)
+	}
+	to, err := time.Parse("2006-01-02", *toStr)
+	if err != nil {
+		return fmt.Errorf("invalid -to, use YYYY-MM-DD: %w", err)
+	}
+
+	var currencies []string
+	for _, currency := range strings.Split(*currenciesStr, ",") {
+		if currency = strings.ToUpper(strings.TrimSpace(currency)); currency != "" {
+			currencies = append(currencies, currency)
+		}
+	}
+
+	var report *models.BackfillReport
+	if *filePath != "" {
+		file, err := os.Open(*filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		tables, err := services.ParseRateHistoryCSV(file, services.ProviderCSV)
+		if err != nil {
+			return err
+		}
+		source := services.ProviderCSV + ":" + filepath.Base(*filePath)
+		report, err = deps.exchangeRateService.ImportRateTables(ctx, from, to, currencies, tables, source)
+		if err != nil {
+			return err
+		}
+	} else {
+		report, err = deps.exchangeRateService.BackfillRates(ctx, from, to, currencies)
+		if err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// reconcileCommand сверяет балансы активов с журналом транзакций:
+//
+//	brok reconcile [-user <id>] [-asset <id>] [-mode report|fix|adjust]
+func reconcileCommand(ctx context.Context, deps commandDeps, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	userID := flags.String("user", "", "ID пользователя (по умолчанию — все пользователи)")
+	assetID := flags.String("asset", "", "ID актива пользователя (требует -user)")
+	mode := flags.String("mode", models.ReconcileModeReport, "report — только отчет, fix — исправить баланс, adjust — записать переоценку")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *assetID != "" && *userID == "" {
+		return fmt.Errorf("-asset requires -user")
+	}
+
+	var report *models.ReconciliationReport
+	var err error
+	if *userID != "" {
+		report, err = deps.reconciliationService.ReconcileUser(ctx, *userID, *assetID, *mode)
+	} else {
+		report, err = deps.reconciliationService.ReconcileAll(ctx, *mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// seedInstrumentsCommand загружает справочник инструментов из CSV:
+//
+//	brok seed-instruments -file instruments.csv [-dry-run]
+func seedInstrumentsCommand(ctx context.Context, deps commandDeps, args []string) error {
+	flags := flag.NewFlagSet("seed-instruments", flag.ContinueOnError)
+	filePath := flags.String("file", "", "CSV-файл: symbol,isin,name,asset_class,exchange,currency,lot_size")
+	dryRun := flags.Bool("dry-run", false, "только проверить файл, ничего не сохранять")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *filePath == "" {
+		return fmt.Errorf("-file is required")
+	}
+
+	file, err := os.Open(*filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	report, err := deps.instrumentService.Import(ctx, file, *dryRun)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(report); encodeErr != nil {
+			return encodeErr
+		}
+	}
+	return err
+}
@@ -7,12 +7,15 @@ import (
 	"os"
 	"runtime/debug"
 	"time"
+	_ "time/tzdata" // часовые пояса профиля не зависят от tzdata в образе
 
 	"brok/db"
 	"brok/internal/handler"
+	"brok/internal/middleware"
 	"brok/internal/routes"
 	"brok/internal/services"
 	"brok/internal/storage"
+	"brok/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -35,8 +38,78 @@ func main() {
 
 	storage := storage.New(db)
 
+	// Источники курсов валют (EXCHANGE_RATE_PROVIDERS)
+	rateProvider, err := services.NewRateProviderFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Неверная настройка источников курсов валют: %v", err)
+	}
+	log.Printf("💱 Источники курсов валют: %s", rateProvider.Name())
+
+	// Источники истории курсов (ecb, cbr) для загрузки прошлых дат
+	historyProvider, err := services.NewHistoricalRateProviderFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Неверная настройка источников курсов валют: %v", err)
+	}
+
+	// Поиск курса: допустимая давность и промежуточные валюты для кросс-курса
+	rateResolution, err := services.RateResolutionFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Неверная настройка поиска курсов валют: %v", err)
+	}
+
+	// Источники цен инструментов (PRICE_PROVIDERS); nil — загрузка цен отключена
+	priceProvider, err := services.NewPriceProviderFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Неверная настройка источников цен: %v", err)
+	}
+
+	// Сроки действия токенов (ACCESS_TOKEN_TTL, REFRESH_TOKEN_TTL)
+	sessionConfig, err := services.SessionConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Неверная настройка сессий: %v", err)
+	}
+
+	// Отправка писем (MAILER: smtp, log, file)
+	mailer, err := services.NewMailerFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Неверная настройка отправки писем: %v", err)
+	}
+	log.Printf("✉️  Отправка писем: %s", mailer.Name())
+
 	// Инициализация сервисов
-	exchangeRateService := services.NewExchangeRateService(storage)
+	exchangeRateService := services.NewExchangeRateService(storage, rateProvider, historyProvider, rateResolution)
+	portfolioService := services.NewPortfolioService(storage, exchangeRateService)
+	archiveService := services.NewArchiveService(storage)
+	reconciliationService := services.NewReconciliationService(storage)
+	scheduleService := services.NewScheduleService(storage, exchangeRateService)
+	priceService := services.NewPriceService(storage, priceProvider, exchangeRateService)
+	instrumentService := services.NewInstrumentService(storage)
+	sessionService := services.NewSessionService(storage, sessionConfig)
+	twoFactorService := services.NewTwoFactorService(storage, sessionService)
+	accountService := services.NewAccountService(storage, mailer, twoFactorService)
+
+	// CLI-команды выполняются вместо запуска сервера: brok <команда> [флаги]
+	if len(os.Args) > 1 {
+		deps := commandDeps{
+			storage:               storage,
+			exchangeRateService:   exchangeRateService,
+			reconciliationService: reconciliationService,
+		}
+		if err := runCommand(context.Background(), deps, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("❌ %s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// Ключи подписи JWT (JWT_SIGNING_KEY_FILE, JWT_VERIFY_KEY_FILES или JWT_SECRET):
+	// без ключа или со слабым секретом сервер не запускается
+	jwtKeys, err := utils.LoadJWTKeysFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Неверная настройка ключей JWT: %v", err)
+	}
+	utils.SetJWTKeys(jwtKeys)
+	log.Printf("🔑 Подпись JWT: %s (kid %s), ключей проверки: %d",
+		jwtKeys.SigningKey().Method.Alg(), jwtKeys.SigningKey().ID, jwtKeys.VerifyKeys())
 
 	// Проверяем и обновляем курсы валют при запуске (если прошло больше часа)
 	updateInterval := 1 * time.Hour
@@ -56,6 +129,59 @@ func main() {
 		}
 	}()
 
+	// Создание транзакций по расписаниям: при запуске догоняем пропущенные наступления,
+	// затем проверяем каждые SCHEDULE_INTERVAL (по умолчанию 15 минут)
+	scheduleInterval := 15 * time.Minute
+	if value := os.Getenv("SCHEDULE_INTERVAL"); value != "" {
+		scheduleInterval, err = time.ParseDuration(value)
+		if err != nil || scheduleInterval <= 0 {
+			log.Fatalf("❌ Неверный SCHEDULE_INTERVAL: %q", value)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+
+		for {
+			created, err := scheduleService.RunDue(context.Background(), time.Now())
+			if err != nil {
+				log.Printf("⚠️  Не удалось выполнить расписания: %v", err)
+			} else if created > 0 {
+				log.Printf("🔁 Создано транзакций по расписаниям: %d", created)
+			}
+			<-ticker.C
+		}
+	}()
+
+	// Загрузка цен закрытия и переоценка привязанных активов: при запуске
+	// и затем каждые PRICE_UPDATE_INTERVAL (по умолчанию раз в сутки)
+	if priceProvider != nil {
+		log.Printf("📈 Источники цен: %s", priceProvider.Name())
+
+		priceInterval := 24 * time.Hour
+		if value := os.Getenv("PRICE_UPDATE_INTERVAL"); value != "" {
+			priceInterval, err = time.ParseDuration(value)
+			if err != nil || priceInterval <= 0 {
+				log.Fatalf("❌ Неверный PRICE_UPDATE_INTERVAL: %q", value)
+			}
+		}
+		go func() {
+			ticker := time.NewTicker(priceInterval)
+			defer ticker.Stop()
+
+			for {
+				report, err := priceService.Refresh(context.Background())
+				if err != nil {
+					log.Printf("⚠️  Не удалось обновить цены: %v", err)
+				} else if len(report.Symbols) > 0 {
+					log.Printf("📈 Цены обновлены: %d из %d инструментов, переоценено активов: %d",
+						report.Saved, len(report.Symbols), len(report.Marked))
+				}
+				<-ticker.C
+			}
+		}()
+	}
+
 	// Настройка Gin
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode == "" {
@@ -63,10 +189,19 @@ func main() {
 	}
 	gin.SetMode(ginMode)
 
-	authHandler := handler.NewAuthHandler(storage)
-	assetHandler := handler.NewAssetHandler(storage)
-	transactionHandler := handler.NewTransactionHandler(storage)
+	authHandler := handler.NewAuthHandler(storage, sessionService, twoFactorService, accountService)
+	assetHandler := handler.NewAssetHandler(storage, priceService)
+	transactionHandler := handler.NewTransactionHandler(storage, exchangeRateService)
+	transferHandler := handler.NewTransferHandler(storage, exchangeRateService)
 	exchangeRateHandler := handler.NewExchangeRateHandler(exchangeRateService)
+	portfolioHandler := handler.NewPortfolioHandler(portfolioService)
+	archiveHandler := handler.NewArchiveHandler(archiveService)
+	reconciliationHandler := handler.NewReconciliationHandler(reconciliationService)
+	scheduleHandler := handler.NewScheduleHandler(storage)
+	priceHandler := handler.NewPriceHandler(storage, priceService)
+	instrumentHandler := handler.NewInstrumentHandler(storage, instrumentService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService)
+	accountHandler := handler.NewAccountHandler(accountService)
 	r := gin.Default()
 
 	// This route serves our static swagger.yaml file
@@ -92,7 +227,9 @@ func main() {
 	})
 
 	// Регистрируем маршруты
-	routes.RegisterRoutes(r, authHandler, assetHandler, transactionHandler, exchangeRateHandler)
+	jwtAuth := middleware.JWTAuth(storage)
+	adminAuth := middleware.RequireAdmin(storage)
+	routes.RegisterRoutes(r, authHandler, assetHandler, transactionHandler, transferHandler, exchangeRateHandler, portfolioHandler, archiveHandler, reconciliationHandler, scheduleHandler, priceHandler, instrumentHandler, twoFactorHandler, accountHandler, jwtAuth, adminAuth)
 
 	// Запуск сервера
 	port := os.Getenv("PORT")
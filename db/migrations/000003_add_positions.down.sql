@@ -0,0 +1,9 @@
+-- Удаляем позиционный учет из транзакций
+DROP INDEX IF EXISTS idx_transactions_asset_ticker;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_price;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_quantity;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS price;
+ALTER TABLE transactions DROP COLUMN IF EXISTS quantity;
+ALTER TABLE transactions DROP COLUMN IF EXISTS ticker;
@@ -0,0 +1,15 @@
+-- Добавляем позиционный учет в транзакции
+ALTER TABLE transactions ADD COLUMN ticker VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN quantity NUMERIC(20,8) NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN price NUMERIC(20,8) NOT NULL DEFAULT 0;
+
+-- Количество и цена не могут быть отрицательными
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_quantity CHECK (quantity >= 0);
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_price CHECK (price >= 0);
+
+-- Индекс для расчета позиций по инструментам
+CREATE INDEX IF NOT EXISTS idx_transactions_asset_ticker ON transactions(asset_id, ticker);
+
+COMMENT ON COLUMN transactions.ticker IS 'Тикер инструмента (для buy/sell)';
+COMMENT ON COLUMN transactions.quantity IS 'Количество бумаг в сделке';
+COMMENT ON COLUMN transactions.price IS 'Цена за единицу в валюте транзакции';
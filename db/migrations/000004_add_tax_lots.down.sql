@@ -0,0 +1,8 @@
+-- Удаляем сопоставления лотов
+DROP TABLE IF EXISTS lot_matches;
+
+ALTER TABLE assets DROP CONSTRAINT IF EXISTS check_asset_lot_method;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS check_user_lot_method;
+
+ALTER TABLE assets DROP COLUMN IF EXISTS lot_method;
+ALTER TABLE users DROP COLUMN IF EXISTS lot_method;
@@ -0,0 +1,31 @@
+-- Метод подбора лотов при продаже: на уровне пользователя и (опционально) актива
+ALTER TABLE users ADD COLUMN lot_method VARCHAR(20) NOT NULL DEFAULT 'fifo';
+ALTER TABLE assets ADD COLUMN lot_method VARCHAR(20);
+
+ALTER TABLE users ADD CONSTRAINT check_user_lot_method CHECK (lot_method IN ('fifo', 'lifo', 'hifo'));
+ALTER TABLE assets ADD CONSTRAINT check_asset_lot_method CHECK (lot_method IS NULL OR lot_method IN ('fifo', 'lifo', 'hifo'));
+
+-- Сопоставления продаж с лотами покупок
+CREATE TABLE IF NOT EXISTS lot_matches (
+    id VARCHAR(36) PRIMARY KEY,
+    asset_id VARCHAR(36) NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    sell_transaction_id VARCHAR(36) NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    buy_transaction_id VARCHAR(36) NOT NULL REFERENCES transactions(id),
+    ticker VARCHAR(20) NOT NULL DEFAULT '',
+    method VARCHAR(20) NOT NULL,
+    quantity NUMERIC(20,8) NOT NULL CHECK (quantity > 0),
+    cost_basis NUMERIC(20,8) NOT NULL,
+    proceeds NUMERIC(20,8) NOT NULL,
+    realized_gain NUMERIC(20,8) NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL,
+    disposed_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_lot_matches_asset_id ON lot_matches(asset_id);
+CREATE INDEX IF NOT EXISTS idx_lot_matches_sell ON lot_matches(sell_transaction_id);
+CREATE INDEX IF NOT EXISTS idx_lot_matches_buy ON lot_matches(buy_transaction_id);
+
+COMMENT ON COLUMN users.lot_method IS 'Метод подбора лотов по умолчанию (fifo, lifo, hifo)';
+COMMENT ON COLUMN assets.lot_method IS 'Метод подбора лотов для актива (переопределяет метод пользователя)';
+COMMENT ON TABLE lot_matches IS 'Какие лоты покупок закрыты каждой продажей';
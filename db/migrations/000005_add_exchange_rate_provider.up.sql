@@ -0,0 +1,4 @@
+-- Источник, из которого получен курс
+ALTER TABLE exchange_rates ADD COLUMN provider VARCHAR(50) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN exchange_rates.provider IS 'Источник курса (exchangerate-api, ecb, cbr, file)';
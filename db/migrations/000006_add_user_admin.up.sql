@@ -0,0 +1,4 @@
+-- Администраторы (назначаются вручную)
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN users.is_admin IS 'Доступ к административным эндпоинтам';
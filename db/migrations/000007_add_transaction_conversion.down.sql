@@ -0,0 +1,7 @@
+-- Удаляем данные о конвертации транзакций
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_exchange_rate;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_original_currency_format;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS exchange_rate;
+ALTER TABLE transactions DROP COLUMN IF EXISTS original_currency;
+ALTER TABLE transactions DROP COLUMN IF EXISTS original_amount;
@@ -0,0 +1,11 @@
+-- Исходная сумма и курс для транзакций в валюте, отличной от валюты актива
+ALTER TABLE transactions ADD COLUMN original_amount NUMERIC(10,2);
+ALTER TABLE transactions ADD COLUMN original_currency VARCHAR(3);
+ALTER TABLE transactions ADD COLUMN exchange_rate NUMERIC(20,10);
+
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_original_currency_format CHECK (original_currency ~ '^[A-Z]{3}$');
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_exchange_rate CHECK (exchange_rate > 0);
+
+COMMENT ON COLUMN transactions.original_amount IS 'Сумма в исходной валюте до конвертации';
+COMMENT ON COLUMN transactions.original_currency IS 'Исходная валюта транзакции (ISO 4217 код)';
+COMMENT ON COLUMN transactions.exchange_rate IS 'Курс original_currency -> currency, по которому сконвертирована сумма';
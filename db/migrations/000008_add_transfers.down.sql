@@ -0,0 +1,6 @@
+-- Удаляем переводы между активами
+DROP INDEX IF EXISTS idx_transactions_transfer_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS transfer_id;
+
+DROP INDEX IF EXISTS idx_transfers_user_id;
+DROP TABLE IF EXISTS transfers;
@@ -0,0 +1,27 @@
+-- Переводы между активами пользователя: связанные вывод и ввод средств
+CREATE TABLE IF NOT EXISTS transfers (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    target_amount NUMERIC(10,2) NOT NULL CHECK (target_amount > 0),
+    target_currency VARCHAR(3) NOT NULL CHECK (target_currency ~ '^[A-Z]{3}$'),
+    exchange_rate NUMERIC(20,10) NOT NULL CHECK (exchange_rate > 0),
+    fee NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    description TEXT NOT NULL DEFAULT '',
+    timestamp TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_user_id ON transfers(user_id);
+
+-- Ноги перевода удаляются вместе с ним
+ALTER TABLE transactions ADD COLUMN transfer_id VARCHAR(36) REFERENCES transfers(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer_id ON transactions(transfer_id);
+
+COMMENT ON TABLE transfers IS 'Переводы между активами пользователя';
+COMMENT ON COLUMN transfers.amount IS 'Сумма перевода в валюте актива-источника (без комиссии)';
+COMMENT ON COLUMN transfers.target_amount IS 'Зачисленная сумма в валюте актива-получателя';
+COMMENT ON COLUMN transfers.exchange_rate IS 'Курс currency -> target_currency';
+COMMENT ON COLUMN transfers.fee IS 'Комиссия в валюте актива-источника, списывается вместе с суммой';
+COMMENT ON COLUMN transactions.transfer_id IS 'Перевод, частью которого является транзакция';
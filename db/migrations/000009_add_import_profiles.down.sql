@@ -0,0 +1,2 @@
+-- Удаляем профили импорта выписок
+DROP TABLE IF EXISTS import_profiles;
@@ -0,0 +1,21 @@
+-- Профили сопоставления колонок CSV-выписок брокера с полями транзакций
+CREATE TABLE IF NOT EXISTS import_profiles (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    delimiter VARCHAR(1) NOT NULL DEFAULT ',',
+    date_column VARCHAR(100) NOT NULL,
+    date_format VARCHAR(50) NOT NULL DEFAULT '2006-01-02',
+    type_column VARCHAR(100) NOT NULL DEFAULT '',
+    amount_column VARCHAR(100) NOT NULL,
+    currency_column VARCHAR(100) NOT NULL DEFAULT '',
+    description_column VARCHAR(100) NOT NULL DEFAULT '',
+    type_map JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE(user_id, name)
+);
+
+COMMENT ON TABLE import_profiles IS 'Сопоставление колонок CSV-выписки с полями транзакций';
+COMMENT ON COLUMN import_profiles.date_format IS 'Формат даты в нотации Go (2006-01-02 15:04:05)';
+COMMENT ON COLUMN import_profiles.type_column IS 'Колонка типа; если пусто, тип определяется по знаку суммы';
+COMMENT ON COLUMN import_profiles.type_map IS 'Значения колонки типа из выписки -> тип транзакции';
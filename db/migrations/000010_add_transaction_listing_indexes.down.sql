@@ -0,0 +1,3 @@
+-- Удаляем индексы постраничного вывода транзакций
+DROP INDEX IF EXISTS idx_transactions_asset_amount;
+DROP INDEX IF EXISTS idx_transactions_asset_timestamp;
@@ -0,0 +1,3 @@
+-- Индексы для постраничного вывода транзакций (ключ сортировки + id)
+CREATE INDEX IF NOT EXISTS idx_transactions_asset_timestamp ON transactions(asset_id, timestamp, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_asset_amount ON transactions(asset_id, amount, id);
@@ -0,0 +1,5 @@
+-- Удаляем расписания регулярных транзакций
+DROP TABLE IF EXISTS schedule_runs;
+DROP INDEX IF EXISTS idx_schedules_active;
+DROP INDEX IF EXISTS idx_schedules_user_id;
+DROP TABLE IF EXISTS schedules;
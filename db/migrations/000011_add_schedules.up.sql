@@ -0,0 +1,35 @@
+-- Регулярные транзакции пользователя по расписанию
+CREATE TABLE IF NOT EXISTS schedules (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    asset_id VARCHAR(36) NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    amount NUMERIC(10,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    type VARCHAR(50) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    frequency VARCHAR(100) NOT NULL,
+    start_date TIMESTAMPTZ NOT NULL,
+    end_date TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT true,
+    last_occurrence TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (end_date IS NULL OR end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_user_id ON schedules(user_id);
+CREATE INDEX IF NOT EXISTS idx_schedules_active ON schedules(active) WHERE active;
+
+-- Выполненные наступления расписания: запись не дает создать транзакцию повторно,
+-- даже если пользователь удалил созданную транзакцию
+CREATE TABLE IF NOT EXISTS schedule_runs (
+    schedule_id VARCHAR(36) NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    occurrence TIMESTAMPTZ NOT NULL,
+    transaction_id VARCHAR(36) REFERENCES transactions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (schedule_id, occurrence)
+);
+
+COMMENT ON TABLE schedules IS 'Расписания регулярных транзакций';
+COMMENT ON COLUMN schedules.frequency IS 'Правило повторения в стиле RRULE: FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=15';
+COMMENT ON COLUMN schedules.last_occurrence IS 'Последнее обработанное наступление';
+COMMENT ON TABLE schedule_runs IS 'Наступления расписаний, по которым уже созданы транзакции';
@@ -0,0 +1,3 @@
+-- Удаляем цены инструментов
+ALTER TABLE assets DROP COLUMN IF EXISTS symbol;
+DROP TABLE IF EXISTS prices;
@@ -0,0 +1,17 @@
+-- Дневные цены закрытия инструментов
+CREATE TABLE IF NOT EXISTS prices (
+    symbol VARCHAR(20) NOT NULL,
+    date DATE NOT NULL,
+    close NUMERIC(20,8) NOT NULL CHECK (close >= 0),
+    currency VARCHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    provider VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (symbol, date)
+);
+
+-- Инструмент, по цене которого оценивается актив
+ALTER TABLE assets ADD COLUMN symbol VARCHAR(20);
+
+COMMENT ON TABLE prices IS 'Цены закрытия инструментов по дням';
+COMMENT ON COLUMN prices.provider IS 'Источник цены';
+COMMENT ON COLUMN assets.symbol IS 'Тикер инструмента для оценки актива по рыночной цене';
@@ -0,0 +1,8 @@
+-- Удаляем справочник инструментов; нераспознанные типы активов восстанавливаются из legacy_type
+ALTER TABLE assets DROP CONSTRAINT IF EXISTS check_asset_type;
+UPDATE assets SET type = legacy_type WHERE legacy_type IS NOT NULL;
+ALTER TABLE assets DROP COLUMN IF EXISTS legacy_type;
+ALTER TABLE transactions DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE assets DROP COLUMN IF EXISTS instrument_id;
+DROP INDEX IF EXISTS idx_instruments_symbol;
+DROP TABLE IF EXISTS instruments;
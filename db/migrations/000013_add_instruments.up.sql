@@ -0,0 +1,52 @@
+-- Справочник инструментов
+CREATE TABLE IF NOT EXISTS instruments (
+    id VARCHAR(36) PRIMARY KEY,
+    symbol VARCHAR(20) NOT NULL,
+    isin CHAR(12) UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    asset_class VARCHAR(20) NOT NULL CHECK (asset_class IN
+        ('stock', 'bond', 'etf', 'fund', 'deposit', 'cash', 'crypto', 'commodity', 'real_estate', 'other')),
+    exchange VARCHAR(20) NOT NULL DEFAULT '',
+    currency VARCHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    lot_size INTEGER NOT NULL DEFAULT 1 CHECK (lot_size > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (symbol, exchange)
+);
+
+CREATE INDEX IF NOT EXISTS idx_instruments_symbol ON instruments(symbol);
+
+-- Ссылки активов и транзакций на инструмент
+ALTER TABLE assets ADD COLUMN instrument_id VARCHAR(36) REFERENCES instruments(id) ON DELETE SET NULL;
+ALTER TABLE transactions ADD COLUMN instrument_id VARCHAR(36) REFERENCES instruments(id) ON DELETE SET NULL;
+
+-- Приводим свободные типы активов к классам активов; нераспознанный тип
+-- сохраняется в legacy_type, а актив получает класс other
+ALTER TABLE assets ADD COLUMN legacy_type VARCHAR(50);
+UPDATE assets SET legacy_type = type;
+
+UPDATE assets SET type = CASE lower(trim(type))
+    WHEN 'stock' THEN 'stock' WHEN 'stocks' THEN 'stock' WHEN 'share' THEN 'stock' WHEN 'shares' THEN 'stock'
+    WHEN 'equity' THEN 'stock' WHEN 'акции' THEN 'stock' WHEN 'акция' THEN 'stock'
+    WHEN 'bond' THEN 'bond' WHEN 'bonds' THEN 'bond' WHEN 'облигации' THEN 'bond' WHEN 'облигация' THEN 'bond'
+    WHEN 'etf' THEN 'etf' WHEN 'etfs' THEN 'etf'
+    WHEN 'fund' THEN 'fund' WHEN 'funds' THEN 'fund' WHEN 'mutual_fund' THEN 'fund' WHEN 'пиф' THEN 'fund'
+    WHEN 'deposit' THEN 'deposit' WHEN 'deposits' THEN 'deposit' WHEN 'вклад' THEN 'deposit' WHEN 'депозит' THEN 'deposit'
+    WHEN 'cash' THEN 'cash' WHEN 'account' THEN 'cash' WHEN 'наличные' THEN 'cash' WHEN 'счет' THEN 'cash'
+    WHEN 'crypto' THEN 'crypto' WHEN 'cryptocurrency' THEN 'crypto' WHEN 'криптовалюта' THEN 'crypto'
+    WHEN 'commodity' THEN 'commodity' WHEN 'commodities' THEN 'commodity' WHEN 'metal' THEN 'commodity'
+    WHEN 'gold' THEN 'commodity' WHEN 'золото' THEN 'commodity'
+    WHEN 'real_estate' THEN 'real_estate' WHEN 'realty' THEN 'real_estate' WHEN 'недвижимость' THEN 'real_estate'
+    ELSE 'other'
+END;
+
+UPDATE assets SET legacy_type = NULL WHERE type <> 'other' OR lower(trim(legacy_type)) = 'other';
+
+ALTER TABLE assets ADD CONSTRAINT check_asset_type CHECK (type IN
+    ('stock', 'bond', 'etf', 'fund', 'deposit', 'cash', 'crypto', 'commodity', 'real_estate', 'other'));
+
+COMMENT ON TABLE instruments IS 'Справочник инструментов: тикеры, ISIN, классы активов';
+COMMENT ON COLUMN instruments.lot_size IS 'Размер лота (минимальное количество в заявке)';
+COMMENT ON COLUMN assets.legacy_type IS 'Исходный тип актива, не приведенный к классу активов';
+COMMENT ON COLUMN assets.instrument_id IS 'Инструмент из справочника';
+COMMENT ON COLUMN transactions.instrument_id IS 'Инструмент из справочника';
@@ -0,0 +1,11 @@
+-- Удаляем данные о доходах
+DROP INDEX IF EXISTS idx_transactions_income;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_withholding_tax;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_income_kind;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS pay_date;
+ALTER TABLE transactions DROP COLUMN IF EXISTS ex_date;
+ALTER TABLE transactions DROP COLUMN IF EXISTS withholding_tax;
+ALTER TABLE transactions DROP COLUMN IF EXISTS gross_amount;
+ALTER TABLE transactions DROP COLUMN IF EXISTS income_kind;
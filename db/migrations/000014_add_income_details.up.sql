@@ -0,0 +1,21 @@
+-- Доходы (dividend): вид дохода, начисление до налога, удержанный налог, даты отсечки и выплаты.
+-- amount для таких транзакций — сумма к получению (gross_amount - withholding_tax).
+ALTER TABLE transactions ADD COLUMN income_kind VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN gross_amount NUMERIC(10,2);
+ALTER TABLE transactions ADD COLUMN withholding_tax NUMERIC(10,2);
+ALTER TABLE transactions ADD COLUMN ex_date DATE;
+ALTER TABLE transactions ADD COLUMN pay_date DATE;
+
+UPDATE transactions SET income_kind = 'dividend' WHERE type = 'dividend';
+
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_income_kind CHECK (income_kind IN ('', 'dividend', 'coupon'));
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_withholding_tax CHECK (withholding_tax >= 0);
+
+-- Индекс для отчета о доходах
+CREATE INDEX IF NOT EXISTS idx_transactions_income ON transactions(asset_id, timestamp) WHERE type = 'dividend';
+
+COMMENT ON COLUMN transactions.income_kind IS 'Вид дохода: dividend или coupon';
+COMMENT ON COLUMN transactions.gross_amount IS 'Начисленный доход до удержания налога';
+COMMENT ON COLUMN transactions.withholding_tax IS 'Налог, удержанный у источника';
+COMMENT ON COLUMN transactions.ex_date IS 'Дата отсечки';
+COMMENT ON COLUMN transactions.pay_date IS 'Дата выплаты';
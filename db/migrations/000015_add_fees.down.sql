@@ -0,0 +1,7 @@
+-- Удаляем комиссии по сделкам
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_original_fee_currency_format;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_fee;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS original_fee_currency;
+ALTER TABLE transactions DROP COLUMN IF EXISTS original_fee;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fee;
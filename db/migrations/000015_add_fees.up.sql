@@ -0,0 +1,12 @@
+-- Комиссии по сделкам: fee в валюте актива, исходная сумма и валюта, если комиссия
+-- указана в другой валюте. Прочие расходы записываются транзакциями с типом fee.
+ALTER TABLE transactions ADD COLUMN fee NUMERIC(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN original_fee NUMERIC(10,2);
+ALTER TABLE transactions ADD COLUMN original_fee_currency VARCHAR(3);
+
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_fee CHECK (fee >= 0);
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_original_fee_currency_format CHECK (original_fee_currency ~ '^[A-Z]{3}$');
+
+COMMENT ON COLUMN transactions.fee IS 'Комиссия по сделке в валюте актива: увеличивает стоимость покупки и уменьшает выручку продажи';
+COMMENT ON COLUMN transactions.original_fee IS 'Комиссия в исходной валюте до конвертации';
+COMMENT ON COLUMN transactions.original_fee_currency IS 'Исходная валюта комиссии (ISO 4217 код)';
@@ -0,0 +1,3 @@
+-- Удаляем сессии и отозванные токены
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS sessions;
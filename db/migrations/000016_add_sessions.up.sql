@@ -0,0 +1,32 @@
+-- Сессии пользователя: refresh-токен хранится только в виде SHA-256 хэша.
+-- Предыдущий хэш нужен, чтобы распознать повторное использование уже замененного токена.
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash CHAR(64) NOT NULL UNIQUE,
+    previous_token_hash CHAR(64),
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token_hash ON sessions(previous_token_hash);
+
+-- Отозванные access-токены (по jti) до истечения их срока действия
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(36) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+COMMENT ON TABLE sessions IS 'Сессии пользователя с ротируемыми refresh-токенами';
+COMMENT ON COLUMN sessions.refresh_token_hash IS 'SHA-256 текущего refresh-токена (hex)';
+COMMENT ON COLUMN sessions.previous_token_hash IS 'SHA-256 предыдущего refresh-токена: его повторное предъявление отзывает сессию';
+COMMENT ON COLUMN sessions.user_agent IS 'Устройство (User-Agent), с которого открыта или последний раз продлена сессия';
+COMMENT ON COLUMN sessions.ip IS 'IP-адрес последнего входа или продления';
+COMMENT ON TABLE revoked_tokens IS 'Отозванные до истечения срока access-токены';
@@ -0,0 +1,34 @@
+-- Двухфакторная аутентификация (TOTP): секрет записывается при подключении
+-- и начинает действовать после подтверждения первым кодом (totp_enabled_at).
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN users.totp_secret IS 'Секрет TOTP (base32); без totp_enabled_at — подключение не подтверждено';
+COMMENT ON COLUMN users.totp_enabled_at IS 'Время подтверждения 2FA; NULL — 2FA выключена';
+COMMENT ON COLUMN users.totp_last_step IS 'Последний принятый шаг TOTP: код нельзя использовать повторно';
+
+-- Одноразовые коды восстановления: хранится только SHA-256
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, code_hash)
+);
+
+-- Незавершенные входы: пароль проверен, ожидается код 2FA
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_expires_at ON two_factor_challenges(expires_at);
+
+COMMENT ON TABLE recovery_codes IS 'Одноразовые коды восстановления 2FA';
+COMMENT ON TABLE two_factor_challenges IS 'Токены второго шага входа с ограничением числа попыток';
@@ -0,0 +1,5 @@
+-- Удаляем подтверждение email
+COMMENT ON TABLE revoked_tokens IS 'Отозванные до истечения срока access-токены';
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
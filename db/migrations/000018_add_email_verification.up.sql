@@ -0,0 +1,8 @@
+-- Подтверждение email и время смены пароля: ссылки на сброс пароля,
+-- выданные до смены, перестают действовать
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN users.email_verified_at IS 'Время подтверждения текущего email; NULL — не подтвержден';
+COMMENT ON COLUMN users.password_changed_at IS 'Время последней смены пароля';
+COMMENT ON TABLE revoked_tokens IS 'Отозванные до истечения срока access-токены и использованные токены из писем';
@@ -0,0 +1,8 @@
+-- Профиль пользователя: отображаемое имя, язык и часовой пояс
+ALTER TABLE users ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+COMMENT ON COLUMN users.display_name IS 'Отображаемое имя';
+COMMENT ON COLUMN users.locale IS 'Язык интерфейса (тег BCP 47, например ru-RU); пусто — не задан';
+COMMENT ON COLUMN users.timezone IS 'Часовой пояс IANA (например Europe/Moscow)';
@@ -0,0 +1,3 @@
+-- Удаляем часовой пояс и время возобновления расписаний
+ALTER TABLE schedules DROP COLUMN IF EXISTS resumed_at;
+ALTER TABLE schedules DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,9 @@
+-- Часовой пояс расписания: наступления считаются в нем, а не в UTC, в котором
+-- даты возвращаются из базы. Для существующих расписаний берется пояс пользователя.
+ALTER TABLE schedules ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE schedules ADD COLUMN resumed_at TIMESTAMPTZ;
+
+UPDATE schedules s SET timezone = u.timezone FROM users u WHERE u.id = s.user_id;
+
+COMMENT ON COLUMN schedules.timezone IS 'Часовой пояс IANA, в котором считаются наступления';
+COMMENT ON COLUMN schedules.resumed_at IS 'Время последнего возобновления: более ранние пропущенные наступления не создаются';
@@ -0,0 +1,7 @@
+-- Неверные коды 2FA считаются по пользователю, а не только по токену входа:
+-- новый вход с паролем не сбрасывает счетчик
+ALTER TABLE users ADD COLUMN totp_failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN totp_locked_until TIMESTAMPTZ;
+
+COMMENT ON COLUMN users.totp_failed_attempts IS 'Неверные коды 2FA подряд; сбрасывается верным кодом и при блокировке';
+COMMENT ON COLUMN users.totp_locked_until IS 'До этого времени коды 2FA не принимаются';
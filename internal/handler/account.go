@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brok/internal/models"
+	"brok/internal/services"
+)
+
+// AccountHandler обработчик учетной записи: профиль, подтверждение email, пароль и удаление
+type AccountHandler struct {
+	Service *services.AccountService
+}
+
+// NewAccountHandler создает новый обработчик учетной записи
+func NewAccountHandler(service *services.AccountService) *AccountHandler {
+	return &AccountHandler{Service: service}
+}
+
+// ForgotPassword отправляет ссылку сброса пароля; ответ одинаков для известных и неизвестных адресов
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.Service.ForgotPassword(c, req.Email); err != nil {
+		log.Printf("Error requesting password reset: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to request password reset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword задает новый пароль по токену из письма
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	err := h.Service.ResetPassword(c, req.Token, req.Password)
+	if errors.Is(err, services.ErrInvalidActionToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error resetting password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+// VerifyEmail подтверждает email по токену из письма
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	err := h.Service.VerifyEmail(c, req.Token)
+	if errors.Is(err, services.ErrInvalidActionToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error verifying email: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// ResendVerification повторно отправляет письмо подтверждения email
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	err := h.Service.SendVerification(c, userIDStr)
+	if errors.Is(err, services.ErrEmailVerified) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error sending verification email: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
+
+// ChangePassword меняет пароль вошедшего пользователя; остальные сессии завершаются
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	userID, claims, ok := sessionFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	err := h.Service.ChangePassword(c, userID, claims.SessionID, req.CurrentPassword, req.NewPassword)
+	if errors.Is(err, services.ErrInvalidPassword) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error changing password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
+
+// UpdateProfile меняет email, базовую валюту, имя, язык и часовой пояс пользователя
+func (h *AccountHandler) UpdateProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	user, err := h.Service.UpdateProfile(c, userIDStr, req)
+	if errors.Is(err, services.ErrInvalidProfile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidPassword) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "current_password is required to change email"})
+		return
+	}
+	if errors.Is(err, services.ErrEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating profile: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteAccount удаляет учетную запись пользователя со всеми данными
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	err := h.Service.DeleteAccount(c, userIDStr, req.Password, req.Code)
+	if errors.Is(err, services.ErrInvalidPassword) || errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrTwoFactorLocked) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrLastAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
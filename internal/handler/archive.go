@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"brok/internal/models"
+	"brok/internal/services"
+)
+
+// ArchiveHandler обработчик выгрузки и восстановления данных пользователя
+type ArchiveHandler struct {
+	archiveService *services.ArchiveService
+}
+
+// NewArchiveHandler создает новый обработчик архивов
+func NewArchiveHandler(archiveService *services.ArchiveService) *ArchiveHandler {
+	return &ArchiveHandler{
+		archiveService: archiveService,
+	}
+}
+
+// Export выгружает все данные пользователя в версионированный JSON-архив
+func (h *ArchiveHandler) Export(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	archive, err := h.archiveService.Export(c, userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export data"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="brok-export-`+archive.ExportedAt.Format("20060102")+`.json"`)
+	c.JSON(http.StatusOK, archive)
+}
+
+// Import восстанавливает данные из архива под текущим пользователем
+func (h *ArchiveHandler) Import(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var archive models.Archive
+	if err := c.ShouldBindJSON(&archive); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid archive: " + err.Error()})
+		return
+	}
+
+	// Настройки профиля из архива применяются только по явному запросу
+	applySettings, err := strconv.ParseBool(c.DefaultQuery("apply_settings", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid apply_settings value"})
+		return
+	}
+
+	report, err := h.archiveService.Import(c, userIDStr, archive, applySettings)
+	if errors.Is(err, services.ErrIncompatibleArchive) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidArchive) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import archive"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
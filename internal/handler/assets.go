@@ -1,8 +1,14 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,16 +16,19 @@ import (
 	"github.com/maksim77/goxirr"
 
 	"brok/internal/models"
+	"brok/internal/services"
 	"brok/internal/storage"
 )
 
 type AssetHandler struct {
-	Storage storage.Storage
+	Storage      storage.Storage
+	priceService *services.PriceService
 }
 
-func NewAssetHandler(s storage.Storage) *AssetHandler {
+func NewAssetHandler(s storage.Storage, priceService *services.PriceService) *AssetHandler {
 	return &AssetHandler{
-		Storage: s,
+		Storage:      s,
+		priceService: priceService,
 	}
 }
 
@@ -48,55 +57,10 @@ func (h *AssetHandler) GetAssets(c *gin.Context) {
 		if err != nil {
 			continue // если не удалось получить транзакции, пропускаем XIRR
 		}
-		var cashflows goxirr.Transactions
-		var profit float64
-		var totalDeposits float64
-		var totalWithdrawals float64
-		var totalDividends float64
-
-		for _, tx := range transactions {
-			amount := tx.Amount
-			switch tx.Type {
-			case "deposit":
-				cashflows = append(cashflows, goxirr.Transaction{
-					Date: tx.Timestamp,
-					Cash: -amount, // вложения — отрицательный поток для XIRR
-				})
-				totalDeposits += amount
-			case "withdrawal":
-				cashflows = append(cashflows, goxirr.Transaction{
-					Date: tx.Timestamp,
-					Cash: amount, // выводы — положительный поток для XIRR
-				})
-				totalWithdrawals += amount
-			case "buy":
-				cashflows = append(cashflows, goxirr.Transaction{
-					Date: tx.Timestamp,
-					Cash: -amount, // покупки — отрицательный поток для XIRR
-				})
-			case "sell":
-				cashflows = append(cashflows, goxirr.Transaction{
-					Date: tx.Timestamp,
-					Cash: amount, // продажи — положительный поток для XIRR
-				})
-			case "dividend":
-				cashflows = append(cashflows, goxirr.Transaction{
-					Date: tx.Timestamp,
-					Cash: amount, // дивиденды — положительный поток для XIRR
-				})
-				totalDividends += amount
-			case "revaluation":
-				cashflows = append(cashflows, goxirr.Transaction{
-					Date: tx.Timestamp,
-					Cash: amount, // может быть + или -
-				})
-			}
-		}
-
-		// Чистая прибыль = Текущий баланс - Сумма вложений + Сумма выводов + Дивиденды
-		profit = assets[i].Balance - totalDeposits + totalWithdrawals + totalDividends
-		if len(cashflows) > 1 {
-			xirr := goxirr.Xirr(cashflows)
+		metrics := services.CalculateAssetMetrics(assets[i].Balance, transactions)
+		profit := metrics.Profit
+		if len(metrics.Cashflows) > 1 {
+			xirr := goxirr.Xirr(metrics.Cashflows)
 			assets[i].Xirr = &xirr
 			// APY = XIRR (эффективная годовая ставка)
 			apy := xirr
@@ -111,6 +75,50 @@ func (h *AssetHandler) GetAssets(c *gin.Context) {
 		}
 		// Добавляем прибыль в отдельное поле
 		assets[i].Profit = &profit
+		if metrics.Fees > 0 {
+			fees := metrics.Fees
+			assets[i].Fees = &fees
+		}
+
+		// TWR с первой операции до текущего момента
+		if len(transactions) > 0 {
+			sorted := services.SortTransactions(transactions)
+			if twr, ok := services.AssetTWR(sorted, sorted[0].Timestamp, time.Now()); ok {
+				assets[i].Twr = &twr
+			}
+		}
+
+		// Позиции по инструментам, купленным с указанием количества
+		matches, err := h.Storage.GetLotMatchesByAssetID(c, assets[i].ID)
+		if err != nil {
+			continue
+		}
+		positions := services.CalculatePositions(transactions, matches)
+		// Позиции с известной рыночной ценой оцениваются по ней, а не по цене последней сделки;
+		// если цены получить не удалось, остаются цены сделок, а позиции помечаются price_stale
+		if err := h.priceService.ApplyMarketPrices(c, positions, assets[i].Currency); err != nil {
+			log.Printf("Error applying market prices to asset %s: %v", assets[i].ID, err)
+		}
+		if len(positions) > 0 {
+			var realized, unrealized float64
+			for _, p := range positions {
+				realized += p.RealizedProfit
+				unrealized += p.UnrealizedProfit
+			}
+			assets[i].Positions = positions
+			assets[i].RealizedProfit = &realized
+			assets[i].UnrealizedProfit = &unrealized
+		}
+
+		// Рыночная стоимость позиции по инструменту, к которому привязан актив
+		if assets[i].Symbol != nil {
+			for _, p := range positions {
+				if p.Ticker == *assets[i].Symbol && p.PriceDate != nil {
+					marketValue := p.MarketValue
+					assets[i].MarketValue = &marketValue
+				}
+			}
+		}
 	}
 
 	c.JSON(http.StatusOK, assets)
@@ -130,9 +138,22 @@ func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 		return
 	}
 
-	asset := models.Asset{
-		ID:     assetID,
-		UserID: userID.(string),
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	// Изменяем только переданные поля существующего актива
+	owned, err := h.Storage.IsAssetOwnedByUser(c, assetID, userIDStr)
+	if err != nil || !owned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "asset not found or not owned by user"})
+		return
+	}
+	asset, err := h.Storage.AssetByID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve asset"})
+		return
 	}
 
 	if req.Name != nil {
@@ -140,22 +161,110 @@ func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 	}
 
 	if req.Type != nil {
-		asset.Type = *req.Type
+		class, ok := models.NormalizeAssetClass(*req.Type)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": invalidAssetTypeMessage(*req.Type)})
+			return
+		}
+		asset.Type = class
+		asset.LegacyType = nil
 	}
 
 	if req.Balance != nil {
 		asset.Balance = *req.Balance
 	}
 
-	err := h.Storage.AssetSet(c, asset)
+	if req.Currency != nil {
+		if !models.IsCurrencySupported(*req.Currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported currency: " + *req.Currency})
+			return
+		}
+		// Суммы операций хранятся в валюте актива, поэтому смена валюты без пересчета
+		// исказила бы историю; менять валюту можно только у актива без транзакций
+		if *req.Currency != asset.Currency {
+			transactions, err := h.Storage.GetTransactionsByAssetID(c, assetID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve transactions"})
+				return
+			}
+			if len(transactions) > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "cannot change currency of an asset with transactions"})
+				return
+			}
+		}
+		asset.Currency = *req.Currency
+	}
+
+	// Инструмент из справочника: пустая строка отвязывает актив
+	var instrument *models.Instrument
+	if req.InstrumentID != nil && *req.InstrumentID != "" {
+		instrument, err = h.Storage.InstrumentByID(c, *req.InstrumentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "instrument not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve instrument"})
+			return
+		}
+	}
+
+	err = h.Storage.AssetSet(c, *asset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update asset"})
 		return
 	}
 
+	// Метод подбора лотов: пустая строка сбрасывает к методу пользователя
+	if req.LotMethod != nil {
+		var method *string
+		if *req.LotMethod != "" {
+			method = req.LotMethod
+		}
+		if err := h.Storage.SetAssetLotMethod(c, assetID, method); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update asset"})
+			return
+		}
+	}
+
+	if req.InstrumentID != nil {
+		var instrumentID *string
+		if instrument != nil {
+			instrumentID = &instrument.ID
+		}
+		if err := h.Storage.SetAssetInstrument(c, assetID, instrumentID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update asset"})
+			return
+		}
+		// Без явного symbol актив оценивается по тикеру инструмента
+		if req.Symbol == nil && instrument != nil {
+			if err := h.Storage.SetAssetSymbol(c, assetID, &instrument.Symbol); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update asset"})
+				return
+			}
+		}
+	}
+
+	// Инструмент для оценки по рынку: пустая строка отвязывает актив
+	if req.Symbol != nil {
+		var symbol *string
+		if normalized := strings.ToUpper(strings.TrimSpace(*req.Symbol)); normalized != "" {
+			symbol = &normalized
+		}
+		if err := h.Storage.SetAssetSymbol(c, assetID, symbol); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update asset"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "asset updated successfully"})
 }
 
+// invalidAssetTypeMessage сообщение о недопустимом классе актива со списком допустимых
+func invalidAssetTypeMessage(value string) string {
+	return "invalid asset type " + strconv.Quote(value) + ", use: " + strings.Join(models.GetAssetClasses(), ", ")
+}
+
 func (h *AssetHandler) CreateAsset(c *gin.Context) {
 	// Извлекаем user_id из контекста, который был установлен в middleware
 	userID, exists := c.Get("user_id")
@@ -187,16 +296,57 @@ func (h *AssetHandler) CreateAsset(c *gin.Context) {
 		return
 	}
 
+	// Инструмент из справочника задает класс актива и тикер по умолчанию
+	var instrument *models.Instrument
+	if req.InstrumentID != nil && *req.InstrumentID != "" {
+		var err error
+		instrument, err = h.Storage.InstrumentByID(c, *req.InstrumentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "instrument not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve instrument"})
+			return
+		}
+	}
+
+	// Тип актива — один из классов активов
+	assetType := req.Type
+	if assetType == "" && instrument != nil {
+		assetType = instrument.AssetClass
+	}
+	class, ok := models.NormalizeAssetClass(assetType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidAssetTypeMessage(assetType)})
+		return
+	}
+
+	// Инструмент для оценки по рынку
+	var symbol *string
+	if req.Symbol != nil {
+		if normalized := strings.ToUpper(strings.TrimSpace(*req.Symbol)); normalized != "" {
+			symbol = &normalized
+		}
+	} else if instrument != nil {
+		symbol = &instrument.Symbol
+	}
+
 	// Данные для сохранения в БД
 	asset := models.Asset{
 		ID:        assetID,
 		UserID:    userIDStr, //c.MustGet("user_id").(string), // Извлекаем user_id из контекста
 		Name:      req.Name,
-		Type:      req.Type,
+		Type:      class,
 		Currency:  req.Currency,
+		LotMethod: req.LotMethod,
+		Symbol:    symbol,
 		Balance:   0.0, // Начальный баланс
 		CreatedAt: time.Now(),
 	}
+	if instrument != nil {
+		asset.InstrumentID = &instrument.ID
+	}
 
 	// Вставляем новый актив в базу данных
 	err := h.Storage.AssetSet(c, asset)
@@ -232,15 +382,46 @@ func (h *AssetHandler) DeleteAsset(c *gin.Context) {
 		return
 	}
 
-	err = h.Storage.DeleteTransactionsByAssetID(c, assetID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete related transactions"})
-		return
-	}
+	err = h.Storage.Transaction(c, func(ctx context.Context, tx storage.Tx) error {
+		transactions, err := h.Storage.GetTransactionsByAssetIDTx(ctx, tx, assetID)
+		if err != nil {
+			return err
+		}
+
+		// Переводы удаляются целиком: нога на другом активе иначе осталась бы
+		// с transfer_id и продолжала бы менять его баланс
+		deleted := map[string]bool{}
+		for _, transaction := range transactions {
+			if transaction.TransferID == nil || deleted[*transaction.TransferID] {
+				continue
+			}
+			transferID := *transaction.TransferID
+			deleted[transferID] = true
+
+			legs, err := h.Storage.GetTransactionsByTransferIDTx(ctx, tx, transferID)
+			if err != nil {
+				return err
+			}
+			if err := h.Storage.DeleteTransferTx(ctx, tx, transferID); err != nil {
+				return err
+			}
+			for _, leg := range legs {
+				if leg.AssetID == assetID {
+					continue
+				}
+				if err := h.Storage.UpdateAssetBalanceTx(ctx, tx, leg.AssetID, -services.BalanceEffect(leg)); err != nil {
+					return err
+				}
+			}
+		}
 
-	// Удаляем актив
-	err = h.Storage.DeleteAsset(c, assetID)
+		if err := h.Storage.DeleteTransactionsByAssetIDTx(ctx, tx, assetID); err != nil {
+			return err
+		}
+		return h.Storage.DeleteAssetTx(ctx, tx, assetID)
+	})
 	if err != nil {
+		log.Printf("Error deleting asset %s: %v", assetID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete asset"})
 		return
 	}
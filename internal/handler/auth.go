@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
@@ -9,17 +11,24 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"brok/internal/models"
+	"brok/internal/services"
 	"brok/internal/storage"
 	"brok/internal/utils"
 )
 
 type AuthHandler struct {
-	Storage storage.Storage
+	Storage   storage.Storage
+	Sessions  *services.SessionService
+	TwoFactor *services.TwoFactorService
+	Account   *services.AccountService
 }
 
-func NewAuthHandler(s storage.Storage) *AuthHandler {
+func NewAuthHandler(s storage.Storage, sessions *services.SessionService, twoFactor *services.TwoFactorService, account *services.AccountService) *AuthHandler {
 	return &AuthHandler{
-		Storage: s,
+		Storage:   s,
+		Sessions:  sessions,
+		TwoFactor: twoFactor,
+		Account:   account,
 	}
 }
 
@@ -52,6 +61,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Проверяем, поддерживается ли базовая валюта
+	if !models.IsCurrencySupported(req.BaseCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported currency: " + req.BaseCurrency})
+		return
+	}
+
 	// Проверка на существующий email
 	exists, err := h.Storage.IsUsersMailExist(c, req.Email)
 	if err != nil {
@@ -73,10 +88,17 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	userID := uuid.New().String()
 	createdAt := time.Now()
 
+	lotMethod := req.LotMethod
+	if lotMethod == "" {
+		lotMethod = models.LotMethodFIFO
+	}
+
 	newUser := &models.UserWithPassword{
 		ID:           userID,
 		Email:        req.Email,
 		PasswordHash: string(hash),
+		BaseCurrency: req.BaseCurrency,
+		LotMethod:    lotMethod,
 		CreatedAt:    createdAt,
 	}
 
@@ -87,14 +109,20 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Генерация JWT
-	token, err := utils.GenerateJWT(userID)
+	// Письмо со ссылкой подтверждения email; регистрация не зависит от его отправки
+	if err := h.Account.SendVerification(c, userID); err != nil {
+		log.Printf("Error sending verification email: %v", err)
+	}
+
+	// Открываем сессию и выдаем пару токенов
+	tokens, err := h.Sessions.Start(c, userID, c.GetHeader("User-Agent"), c.ClientIP())
 	if err != nil {
+		log.Printf("Error starting session: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.LoginResponse{Token: token})
+	c.JSON(http.StatusOK, tokens)
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -117,12 +145,161 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Генерация JWT токена
-	token, err := utils.GenerateJWT(user.ID) // Генерация JWT с userID
+	// С включенной 2FA вместо сессии выдаем токен второго шага (/auth/login/2fa)
+	twoFactor, err := h.TwoFactor.Enabled(c, user.ID)
+	if err != nil {
+		log.Printf("Error checking two-factor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		return
+	}
+	if twoFactor {
+		challenge, err := h.TwoFactor.StartChallenge(c, user.ID)
+		if err != nil {
+			log.Printf("Error starting two-factor challenge: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	// Открываем сессию и выдаем пару токенов
+	tokens, err := h.Sessions.Start(c, user.ID, c.GetHeader("User-Agent"), c.ClientIP())
 	if err != nil {
+		log.Printf("Error starting session: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.LoginResponse{Token: token})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов; старый refresh-токен больше не действует
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	tokens, err := h.Sessions.Refresh(c, req.RefreshToken, c.GetHeader("User-Agent"), c.ClientIP())
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error refreshing session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout завершает текущую сессию и отзывает access-токен запроса
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, claims, ok := sessionFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Sessions.Logout(c, userID, claims); err != nil {
+		log.Printf("Error logging out: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// LogoutAll завершает все сессии пользователя на всех устройствах
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, claims, ok := sessionFromContext(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.Sessions.LogoutAll(c, userID, claims)
+	if err != nil {
+		log.Printf("Error logging out all sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out", "revoked": revoked})
+}
+
+// GetSessions возвращает активные сессии пользователя
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID, claims, ok := sessionFromContext(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.Sessions.Sessions(c, userID, claims.SessionID)
+	if err != nil {
+		log.Printf("Error fetching sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession завершает одну из сессий пользователя
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, _, ok := sessionFromContext(c)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Param("id")
+	if _, err := uuid.Parse(sessionID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	err := h.Sessions.Revoke(c, userID, sessionID)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error revoking session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// GetJWKS публикует открытые ключи проверки токенов для других сервисов
+func (h *AuthHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
+
+// sessionFromContext достает пользователя и claims токена, положенные JWTAuth;
+// при их отсутствии отвечает 401
+func sessionFromContext(c *gin.Context) (string, *utils.Claims, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return "", nil, false
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return "", nil, false
+	}
+
+	value, _ := c.Get("token_claims")
+	claims, ok := value.(*utils.Claims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "session not found in context"})
+		return "", nil, false
+	}
+
+	return userIDStr, claims, true
 }
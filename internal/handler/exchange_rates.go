@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -53,7 +55,7 @@ func (h *ExchangeRateHandler) GetExchangeRate(c *gin.Context) {
 		return
 	}
 
-	var rate float64
+	var resolution *models.RateResolution
 	var err error
 
 	if dateStr != "" {
@@ -64,10 +66,10 @@ func (h *ExchangeRateHandler) GetExchangeRate(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
 			return
 		}
-		rate, err = h.exchangeService.GetExchangeRate(c, fromCurrency, toCurrency, date)
+		resolution, err = h.exchangeService.ResolveExchangeRate(c, fromCurrency, toCurrency, date)
 	} else {
 		// Используем последний курс
-		rate, err = h.exchangeService.GetLatestExchangeRate(c, fromCurrency, toCurrency)
+		resolution, err = h.exchangeService.ResolveLatestExchangeRate(c, fromCurrency, toCurrency)
 	}
 
 	if err != nil {
@@ -78,8 +80,10 @@ func (h *ExchangeRateHandler) GetExchangeRate(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"from_currency": fromCurrency,
 		"to_currency":   toCurrency,
-		"rate":          h.exchangeService.RoundAmount(rate),
+		"rate":          h.exchangeService.RoundAmount(resolution.Rate),
 		"date":          dateStr,
+		"pivot":         resolution.Pivot,
+		"legs":          resolution.Legs,
 	})
 }
 
@@ -182,3 +186,65 @@ func (h *ExchangeRateHandler) ConvertAmount(c *gin.Context) {
 		"date":             dateStr,
 	})
 }
+
+// BackfillExchangeRates загружает историю курсов за период.
+// Принимает JSON (загрузка из источника истории) или multipart/form-data
+// с CSV-файлом в поле file (формат date,from_currency,to_currency,rate или выгрузка ЕЦБ).
+func (h *ExchangeRateHandler) BackfillExchangeRates(c *gin.Context) {
+	var req models.BackfillRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date format, use YYYY-MM-DD"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date format, use YYYY-MM-DD"})
+		return
+	}
+
+	// В форме валюты можно передать одной строкой через запятую
+	var currencies []string
+	for _, value := range req.Currencies {
+		for _, currency := range strings.Split(value, ",") {
+			if currency = strings.ToUpper(strings.TrimSpace(currency)); currency != "" {
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+
+	var report *models.BackfillReport
+	if fileHeader, fileErr := c.FormFile("file"); fileErr == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open uploaded file"})
+			return
+		}
+		defer file.Close()
+
+		tables, err := services.ParseRateHistoryCSV(file, services.ProviderCSV)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		report, err = h.exchangeService.ImportRateTables(c, from, to, currencies, tables, services.ProviderCSV+":"+fileHeader.Filename)
+	} else {
+		report, err = h.exchangeService.BackfillRates(c, from, to, currencies)
+	}
+
+	if errors.Is(err, services.ErrInvalidPeriod) || errors.Is(err, services.ErrNoHistoryProvider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to backfill exchange rates: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,337 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/services"
+	"brok/internal/storage"
+)
+
+// GetImportProfiles возвращает профили импорта выписок пользователя
+func (h *TransactionHandler) GetImportProfiles(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	profiles, err := h.Storage.ImportProfilesByUserID(c, userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve import profiles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+// CreateImportProfile сохраняет профиль сопоставления колонок выписки
+func (h *TransactionHandler) CreateImportProfile(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.CreateImportProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Имя профиля уникально в пределах пользователя
+	profiles, err := h.Storage.ImportProfilesByUserID(c, userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve import profiles"})
+		return
+	}
+	for _, existing := range profiles {
+		if existing.Name == req.Name {
+			c.JSON(http.StatusConflict, gin.H{"error": "import profile with this name already exists"})
+			return
+		}
+	}
+
+	profile := models.ImportProfile{
+		ID:                uuid.New().String(),
+		UserID:            userIDStr,
+		Name:              req.Name,
+		Delimiter:         req.Delimiter,
+		DateColumn:        req.DateColumn,
+		DateFormat:        req.DateFormat,
+		TypeColumn:        req.TypeColumn,
+		AmountColumn:      req.AmountColumn,
+		CurrencyColumn:    req.CurrencyColumn,
+		DescriptionColumn: req.DescriptionColumn,
+		TypeMap:           req.TypeMap,
+		CreatedAt:         time.Now(),
+	}
+	if profile.Delimiter == "" {
+		profile.Delimiter = ","
+	}
+	if profile.DateFormat == "" {
+		profile.DateFormat = "2006-01-02"
+	}
+	if profile.TypeMap == nil {
+		profile.TypeMap = models.TypeMap{}
+	}
+
+	if err := h.Storage.CreateImportProfile(c, profile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create import profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteImportProfile удаляет профиль импорта
+func (h *TransactionHandler) DeleteImportProfile(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	deleted, err := h.Storage.DeleteImportProfile(c, c.Param("id"), userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete import profile"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "import profile not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "import profile deleted successfully"})
+}
+
+// ImportTransactions импортирует транзакции из CSV-выписки брокера по профилю.
+// Каждая строка проверяется как CreateTransactionRequest, дубликаты уже существующих
+// транзакций пропускаются, файл записывается целиком или не записывается совсем.
+func (h *TransactionHandler) ImportTransactions(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	// Получаем asset_id из параметра
+	assetID := c.Param("id")
+
+	owned, err := h.Storage.IsAssetOwnedByUser(c, assetID, userIDStr)
+	if err != nil || !owned {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "asset not found or doesn't belong to user"})
+		return
+	}
+
+	// Предпросмотр: проверить файл, ничего не записывая
+	dryRun, err := strconv.ParseBool(c.DefaultPostForm("dry_run", c.DefaultQuery("dry_run", "false")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run value"})
+		return
+	}
+
+	profileID := c.DefaultPostForm("profile_id", c.Query("profile_id"))
+	if profileID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required parameter: profile_id"})
+		return
+	}
+	profile, err := h.Storage.ImportProfileByID(c, profileID, userIDStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "import profile not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve import profile"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing statement file"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	rows, err := services.ParseStatementCSV(file, *profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	asset, err := h.Storage.AssetByID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve asset"})
+		return
+	}
+
+	// Уже существующие транзакции для поиска дубликатов
+	existing, err := h.Storage.GetTransactionsByAssetID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve transactions"})
+		return
+	}
+	report := models.ImportReport{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Rows:   make([]models.ImportRowResult, 0, len(rows)),
+	}
+	var transactions []models.Transaction
+	var rowIndexes []int // индекс строки отчета для каждой валидной транзакции
+
+	for _, row := range rows {
+		result := models.ImportRowResult{Row: row.Row}
+
+		transaction, err := h.importRow(c, row, asset)
+		if err != nil {
+			result.Error = err.Error()
+			report.Invalid++
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		result.Transaction = transaction
+		report.Valid++
+		transactions = append(transactions, *transaction)
+		rowIndexes = append(rowIndexes, len(report.Rows))
+		report.Rows = append(report.Rows, result)
+	}
+
+	// markDuplicates отмечает в отчете строки, уже записанные в актив, и возвращает их признаки
+	markDuplicates := func(stored []models.Transaction) []bool {
+		duplicates := services.ImportDuplicates(stored, transactions)
+		report.Duplicates = 0
+		for i, duplicate := range duplicates {
+			report.Rows[rowIndexes[i]].Duplicate = duplicate
+			if duplicate {
+				report.Duplicates++
+			}
+		}
+		return duplicates
+	}
+	markDuplicates(existing)
+
+	if dryRun {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	if report.Invalid > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "statement contains invalid rows, nothing imported", "report": report})
+		return
+	}
+
+	// Весь файл записывается в одной транзакции. Дубликаты перепроверяются под блокировкой
+	// актива, чтобы параллельный импорт той же выписки не записал строки повторно.
+	err = h.Storage.Transaction(c, func(ctx context.Context, tx storage.Tx) error {
+		if _, err := h.Storage.AssetBalanceForUpdateTx(ctx, tx, assetID); err != nil {
+			return err
+		}
+		current, err := h.Storage.GetTransactionsByAssetIDTx(ctx, tx, assetID)
+		if err != nil {
+			return err
+		}
+		duplicates := markDuplicates(current)
+
+		var balanceChange float64
+		for i, transaction := range transactions {
+			if duplicates[i] {
+				continue
+			}
+			if err := h.Storage.CreateTransactionTx(ctx, tx, transaction); err != nil {
+				return err
+			}
+			balanceChange += services.BalanceEffect(transaction)
+			report.Imported++
+		}
+		return h.Storage.UpdateAssetBalanceTx(ctx, tx, assetID, balanceChange)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// importRow проверяет строку выписки по правилам CreateTransactionRequest
+// и превращает ее в транзакцию в валюте актива
+func (h *TransactionHandler) importRow(ctx context.Context, row models.ImportRow, asset *models.Asset) (*models.Transaction, error) {
+	if row.Err != nil {
+		return nil, row.Err
+	}
+
+	req := row.Request
+	if req.Currency == "" {
+		req.Currency = asset.Currency
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, err
+	}
+	if !models.IsCurrencySupported(req.Currency) {
+		return nil, errors.New("unsupported currency: " + req.Currency)
+	}
+
+	transaction := models.Transaction{
+		ID:          uuid.New().String(),
+		AssetID:     asset.ID,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Type:        req.Type,
+		Description: req.Description,
+		Timestamp:   *req.Timestamp,
+	}
+	if transaction.Type == "dividend" {
+		transaction.IncomeKind = models.IncomeKindDividend
+	}
+
+	if err := h.convertToAsset(ctx, &transaction, asset.Currency, nil); err != nil {
+		if errors.Is(err, services.ErrRateNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get exchange rate: " + err.Error())
+	}
+
+	return &transaction, nil
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/services"
+	"brok/internal/storage"
+)
+
+const (
+	defaultInstrumentLimit = 50
+	maxInstrumentLimit     = 500
+)
+
+// InstrumentHandler обработчик справочника инструментов
+type InstrumentHandler struct {
+	Storage           storage.Storage
+	instrumentService *services.InstrumentService
+}
+
+// NewInstrumentHandler создает новый обработчик справочника инструментов
+func NewInstrumentHandler(s storage.Storage, instrumentService *services.InstrumentService) *InstrumentHandler {
+	return &InstrumentHandler{
+		Storage:           s,
+		instrumentService: instrumentService,
+	}
+}
+
+// GetAssetClasses возвращает допустимые классы активов
+func (h *InstrumentHandler) GetAssetClasses(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"asset_classes": models.GetAssetClasses()})
+}
+
+// GetInstruments ищет инструменты: q — подстрока тикера, ISIN или названия; class, exchange, limit
+func (h *InstrumentHandler) GetInstruments(c *gin.Context) {
+	filter := models.InstrumentFilter{
+		Query:    strings.TrimSpace(c.Query("q")),
+		Exchange: strings.ToUpper(c.Query("exchange")),
+		Limit:    defaultInstrumentLimit,
+	}
+
+	if value := c.Query("class"); value != "" {
+		class, ok := models.NormalizeAssetClass(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown asset class: " + value})
+			return
+		}
+		filter.AssetClass = class
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxInstrumentLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be 1..500"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	instruments, err := h.Storage.Instruments(c, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve instruments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, instruments)
+}
+
+// GetInstrument возвращает инструмент по ID
+func (h *InstrumentHandler) GetInstrument(c *gin.Context) {
+	instrument, err := h.Storage.InstrumentByID(c, c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "instrument not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve instrument"})
+		return
+	}
+
+	c.JSON(http.StatusOK, instrument)
+}
+
+// CreateInstrument добавляет инструмент в справочник (только для администраторов)
+func (h *InstrumentHandler) CreateInstrument(c *gin.Context) {
+	var req models.CreateInstrumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	instrument := models.Instrument{
+		ID:         uuid.New().String(),
+		Symbol:     req.Symbol,
+		ISIN:       req.ISIN,
+		Name:       req.Name,
+		AssetClass: req.AssetClass,
+		Exchange:   req.Exchange,
+		Currency:   req.Currency,
+		LotSize:    req.LotSize,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := services.NormalizeInstrument(&instrument); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conflict, err := h.Storage.IsInstrumentConflict(c, instrument)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check instrument"})
+		return
+	}
+	if conflict {
+		c.JSON(http.StatusConflict, gin.H{"error": "instrument with this symbol and exchange or ISIN already exists"})
+		return
+	}
+
+	if err := h.Storage.CreateInstrument(c, instrument); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create instrument"})
+		return
+	}
+
+	c.JSON(http.StatusOK, instrument)
+}
+
+// UpdateInstrument частично изменяет инструмент (только для администраторов)
+func (h *InstrumentHandler) UpdateInstrument(c *gin.Context) {
+	var req models.UpdateInstrumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	instrument, err := h.Storage.InstrumentByID(c, c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "instrument not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve instrument"})
+		return
+	}
+
+	if req.Symbol != nil {
+		instrument.Symbol = *req.Symbol
+	}
+	if req.ISIN != nil {
+		instrument.ISIN = req.ISIN
+	}
+	if req.Name != nil {
+		instrument.Name = *req.Name
+	}
+	if req.AssetClass != nil {
+		instrument.AssetClass = *req.AssetClass
+	}
+	if req.Exchange != nil {
+		instrument.Exchange = *req.Exchange
+	}
+	if req.Currency != nil {
+		instrument.Currency = *req.Currency
+	}
+	if req.LotSize != nil {
+		instrument.LotSize = *req.LotSize
+	}
+	instrument.UpdatedAt = time.Now()
+
+	if err := services.NormalizeInstrument(instrument); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conflict, err := h.Storage.IsInstrumentConflict(c, *instrument)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check instrument"})
+		return
+	}
+	if conflict {
+		c.JSON(http.StatusConflict, gin.H{"error": "instrument with this symbol and exchange or ISIN already exists"})
+		return
+	}
+
+	if err := h.Storage.UpdateInstrument(c, *instrument); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update instrument"})
+		return
+	}
+
+	c.JSON(http.StatusOK, instrument)
+}
+
+// DeleteInstrument удаляет инструмент; активы и транзакции теряют ссылку на него (только для администраторов)
+func (h *InstrumentHandler) DeleteInstrument(c *gin.Context) {
+	deleted, err := h.Storage.DeleteInstrument(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete instrument"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "instrument not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "instrument deleted successfully"})
+}
+
+// ImportInstruments загружает справочник из CSV-файла (только для администраторов)
+func (h *InstrumentHandler) ImportInstruments(c *gin.Context) {
+	// Предпросмотр: проверить файл, ничего не записывая
+	dryRun, err := strconv.ParseBool(c.DefaultPostForm("dry_run", c.DefaultQuery("dry_run", "false")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run value"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing instruments file"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.instrumentService.Import(c, file, dryRun)
+	if errors.Is(err, services.ErrInvalidInstrumentFile) {
+		response := gin.H{"error": err.Error()}
+		if report != nil {
+			response["report"] = report
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import instruments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"brok/internal/models"
+	"brok/internal/services"
+)
+
+// GetAssetLots возвращает открытые лоты актива
+func (h *AssetHandler) GetAssetLots(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	assetID := c.Param("id")
+
+	existsAndOwned, err := h.Storage.IsAssetOwnedByUser(c, assetID, userIDStr)
+	if err != nil || !existsAndOwned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "asset not found or not owned by user"})
+		return
+	}
+
+	transactions, err := h.Storage.GetTransactionsByAssetID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve transactions"})
+		return
+	}
+
+	matches, err := h.Storage.GetLotMatchesByAssetID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve lot matches"})
+		return
+	}
+
+	lots := services.OpenLots(transactions, matches)
+
+	// Фильтр по тикеру (опционально)
+	if ticker := c.Query("ticker"); ticker != "" {
+		filtered := make([]models.Lot, 0, len(lots))
+		for _, lot := range lots {
+			if lot.Ticker == ticker {
+				filtered = append(filtered, lot)
+			}
+		}
+		lots = filtered
+	}
+
+	c.JSON(http.StatusOK, lots)
+}
+
+// GetRealizedGains возвращает реализованную прибыль по закрытым лотам актива
+func (h *AssetHandler) GetRealizedGains(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	assetID := c.Param("id")
+
+	existsAndOwned, err := h.Storage.IsAssetOwnedByUser(c, assetID, userIDStr)
+	if err != nil || !existsAndOwned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "asset not found or not owned by user"})
+		return
+	}
+
+	// Период продаж (опционально)
+	var from, to time.Time
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date format, use YYYY-MM-DD"})
+			return
+		}
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date format, use YYYY-MM-DD"})
+			return
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	transactions, err := h.Storage.GetTransactionsByAssetID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve transactions"})
+		return
+	}
+
+	matches, err := h.Storage.GetLotMatchesByAssetID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve lot matches"})
+		return
+	}
+
+	// Учитываем и продажи, созданные до появления учета лотов
+	_, realized := services.ReplayLots(transactions, matches)
+
+	response := models.RealizedGainsResponse{Matches: []models.LotMatch{}}
+	for _, m := range realized {
+		if !from.IsZero() && m.DisposedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !m.DisposedAt.Before(to) {
+			continue
+		}
+
+		services.FillHoldingPeriod(&m)
+		response.Matches = append(response.Matches, m)
+		response.TotalRealized += m.RealizedGain
+		response.TotalProceeds += m.Proceeds
+		response.TotalCostBasis += m.CostBasis
+		if m.LongTerm {
+			response.LongTermGain += m.RealizedGain
+		} else {
+			response.ShortTermGain += m.RealizedGain
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
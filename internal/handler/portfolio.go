@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"brok/internal/models"
+	"brok/internal/services"
+)
+
+// PortfolioHandler обработчик для отчетов по портфелю целиком
+type PortfolioHandler struct {
+	portfolioService *services.PortfolioService
+}
+
+// NewPortfolioHandler создает новый обработчик портфеля
+func NewPortfolioHandler(portfolioService *services.PortfolioService) *PortfolioHandler {
+	return &PortfolioHandler{
+		portfolioService: portfolioService,
+	}
+}
+
+// GetSummary возвращает сводку по портфелю в базовой валюте пользователя
+func (h *PortfolioHandler) GetSummary(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	summary, err := h.portfolioService.Summary(c, userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build portfolio summary: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetHistory возвращает историю стоимости портфеля в базовой валюте
+func (h *PortfolioHandler) GetHistory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	// По умолчанию — последний месяц по дням
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -1, 0)
+	interval := c.DefaultQuery("interval", models.HistoryIntervalDay)
+
+	var err error
+	if toStr := c.Query("to"); toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date format, use YYYY-MM-DD"})
+			return
+		}
+		if c.Query("from") == "" {
+			from = to.AddDate(0, -1, 0)
+		}
+	}
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date format, use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	history, err := h.portfolioService.History(c, userIDStr, from, to, interval)
+	if errors.Is(err, services.ErrInvalidPeriod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build portfolio history: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetPerformance возвращает доходность, взвешенную по времени, по активам и портфелю
+func (h *PortfolioHandler) GetPerformance(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	period := c.DefaultQuery("period", models.PeriodInception)
+
+	// Произвольный период задается парой from/to
+	var from, to time.Time
+	var err error
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr != "" || toStr != "" {
+		if fromStr == "" || toStr == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be set together"})
+			return
+		}
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date format, use YYYY-MM-DD"})
+			return
+		}
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date format, use YYYY-MM-DD"})
+			return
+		}
+		// Конечная дата включительно
+		to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	performance, err := h.portfolioService.Performance(c, userIDStr, period, from, to)
+	if errors.Is(err, services.ErrInvalidPeriod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate performance: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, performance)
+}
+
+// GetIncome возвращает доходы (дивиденды и купоны) за период в базовой валюте
+func (h *PortfolioHandler) GetIncome(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	// По умолчанию — последний год
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(-1, 0, 1)
+
+	var err error
+	if toStr := c.Query("to"); toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date format, use YYYY-MM-DD"})
+			return
+		}
+		if c.Query("from") == "" {
+			from = to.AddDate(-1, 0, 1)
+		}
+	}
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date format, use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	report, err := h.portfolioService.Income(c, userIDStr, from, to, now)
+	if errors.Is(err, services.ErrInvalidPeriod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build income report: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetFees возвращает комиссии и расходы по активам за период в базовой валюте
+func (h *PortfolioHandler) GetFees(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	// По умолчанию — последний год
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(-1, 0, 1)
+
+	var err error
+	if toStr := c.Query("to"); toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date format, use YYYY-MM-DD"})
+			return
+		}
+		if c.Query("from") == "" {
+			from = to.AddDate(-1, 0, 1)
+		}
+	}
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date format, use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	report, err := h.portfolioService.Fees(c, userIDStr, from, to)
+	if errors.Is(err, services.ErrInvalidPeriod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build fee report: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
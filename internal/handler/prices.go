@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"brok/internal/services"
+	"brok/internal/storage"
+)
+
+// PriceHandler обработчик цен инструментов и переоценки активов по рынку
+type PriceHandler struct {
+	Storage      storage.Storage
+	priceService *services.PriceService
+}
+
+// NewPriceHandler создает новый обработчик цен
+func NewPriceHandler(s storage.Storage, priceService *services.PriceService) *PriceHandler {
+	return &PriceHandler{
+		Storage:      s,
+		priceService: priceService,
+	}
+}
+
+// GetPrices возвращает цены закрытия инструмента за период (по умолчанию — за последний год)
+func (h *PriceHandler) GetPrices(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date format, use YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(-1, 0, 0)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date format, use YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	prices, err := h.Storage.GetPrices(c, symbol, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve prices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prices)
+}
+
+// MarkToMarket переоценивает актив пользователя по последней цене его инструмента
+func (h *PriceHandler) MarkToMarket(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	assetID := c.Param("id")
+	owned, err := h.Storage.IsAssetOwnedByUser(c, assetID, userIDStr)
+	if err != nil || !owned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "asset not found or not owned by user"})
+		return
+	}
+
+	asset, err := h.Storage.AssetByID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve asset"})
+		return
+	}
+
+	result, err := h.priceService.MarkToMarket(c, *asset)
+	if errors.Is(err, services.ErrAssetNotPriced) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrPriceNotFound) || errors.Is(err, services.ErrRateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark asset to market"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// UpdatePrices загружает цены и переоценивает все привязанные активы (только для администраторов)
+func (h *PriceHandler) UpdatePrices(c *gin.Context) {
+	report, err := h.priceService.Refresh(c)
+	if errors.Is(err, services.ErrNoPriceProvider) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brok/internal/models"
+	"brok/internal/services"
+)
+
+// ReconciliationHandler обработчик сверки балансов с журналом транзакций
+type ReconciliationHandler struct {
+	reconciliationService *services.ReconciliationService
+}
+
+// NewReconciliationHandler создает новый обработчик сверки
+func NewReconciliationHandler(reconciliationService *services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reconciliationService: reconciliationService,
+	}
+}
+
+// GetReconciliation сообщает о расхождениях балансов активов пользователя с журналом, ничего не меняя
+func (h *ReconciliationHandler) GetReconciliation(c *gin.Context) {
+	h.reconcileUser(c, models.ReconcileModeReport)
+}
+
+// Reconcile устраняет расхождения: mode=fix приводит баланс к журналу,
+// mode=adjust записывает корректирующую переоценку
+func (h *ReconciliationHandler) Reconcile(c *gin.Context) {
+	mode := c.Query("mode")
+	if mode != models.ReconcileModeFix && mode != models.ReconcileModeAdjust {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mode, use: fix, adjust"})
+		return
+	}
+	h.reconcileUser(c, mode)
+}
+
+// ReconcileAll сверяет активы всех пользователей (только для администраторов)
+func (h *ReconciliationHandler) ReconcileAll(c *gin.Context) {
+	mode := c.DefaultQuery("mode", models.ReconcileModeReport)
+	if !models.IsReconcileModeSupported(mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mode, use: report, fix, adjust"})
+		return
+	}
+
+	report, err := h.reconciliationService.ReconcileAll(c, mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reconcile balances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// reconcileUser сверяет активы текущего пользователя (или один актив из ?asset_id)
+func (h *ReconciliationHandler) reconcileUser(c *gin.Context, mode string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	assetID := c.Query("asset_id")
+
+	report, err := h.reconciliationService.ReconcileUser(c, userIDStr, assetID, mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reconcile balances"})
+		return
+	}
+	if assetID != "" && report.Checked == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "asset not found or not owned by user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
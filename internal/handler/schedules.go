@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/services"
+	"brok/internal/storage"
+)
+
+// ScheduleHandler обработчик расписаний регулярных транзакций
+type ScheduleHandler struct {
+	Storage storage.Storage
+}
+
+// NewScheduleHandler создает новый обработчик расписаний
+func NewScheduleHandler(s storage.Storage) *ScheduleHandler {
+	return &ScheduleHandler{Storage: s}
+}
+
+// GetSchedules возвращает расписания пользователя
+func (h *ScheduleHandler) GetSchedules(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	schedules, err := h.Storage.SchedulesByUserID(c, userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve schedules"})
+		return
+	}
+
+	for i := range schedules {
+		withNextOccurrence(&schedules[i])
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// GetSchedule возвращает расписание пользователя
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	schedule, err := h.Storage.ScheduleByID(c, c.Param("id"), userIDStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve schedule"})
+		return
+	}
+
+	withNextOccurrence(schedule)
+	c.JSON(http.StatusOK, schedule)
+}
+
+// CreateSchedule создает расписание регулярной транзакции
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	owned, err := h.Storage.IsAssetOwnedByUser(c, req.AssetID, userIDStr)
+	if err != nil || !owned {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "asset not found or doesn't belong to user"})
+		return
+	}
+
+	// По умолчанию наступления считаются в часовом поясе пользователя
+	timezone := strings.TrimSpace(req.Timezone)
+	if timezone == "" {
+		user, err := h.Storage.UserByID(c, userIDStr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve user"})
+			return
+		}
+		timezone = user.Timezone
+	}
+
+	schedule := models.Schedule{
+		ID:          uuid.New().String(),
+		UserID:      userIDStr,
+		AssetID:     req.AssetID,
+		Amount:      req.Amount,
+		Currency:    strings.ToUpper(req.Currency),
+		Type:        req.Type,
+		Description: req.Description,
+		Frequency:   strings.ToUpper(strings.TrimSpace(req.Frequency)),
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		Timezone:    timezone,
+		Active:      true,
+		CreatedAt:   time.Now(),
+	}
+	if err := validateSchedule(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Storage.CreateSchedule(c, schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create schedule"})
+		return
+	}
+
+	withNextOccurrence(&schedule)
+	c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateSchedule частично изменяет расписание. Уже созданные транзакции не меняются,
+// новые параметры применяются к следующим наступлениям.
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.Storage.ScheduleByID(c, c.Param("id"), userIDStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve schedule"})
+		return
+	}
+
+	if req.Amount != nil {
+		schedule.Amount = *req.Amount
+	}
+	if req.Currency != nil {
+		schedule.Currency = strings.ToUpper(*req.Currency)
+	}
+	if req.Type != nil {
+		schedule.Type = *req.Type
+	}
+	if req.Description != nil {
+		schedule.Description = *req.Description
+	}
+	if req.Frequency != nil {
+		schedule.Frequency = strings.ToUpper(strings.TrimSpace(*req.Frequency))
+	}
+	if req.ClearEnd {
+		schedule.EndDate = nil
+	} else if req.EndDate != nil {
+		schedule.EndDate = req.EndDate
+	}
+	if req.Timezone != nil {
+		schedule.Timezone = strings.TrimSpace(*req.Timezone)
+	}
+	if req.Active != nil {
+		// Возобновленное расписание не догоняет наступления, пропущенные на паузе
+		if *req.Active && !schedule.Active {
+			now := time.Now()
+			schedule.ResumedAt = &now
+		}
+		schedule.Active = *req.Active
+	}
+
+	if err := validateSchedule(*schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Storage.UpdateSchedule(c, *schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update schedule"})
+		return
+	}
+
+	withNextOccurrence(schedule)
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteSchedule удаляет расписание; созданные по нему транзакции остаются
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	deleted, err := h.Storage.DeleteSchedule(c, c.Param("id"), userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete schedule"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "schedule deleted successfully"})
+}
+
+// validateSchedule проверяет валюту, правило повторения, часовой пояс и даты расписания
+func validateSchedule(schedule models.Schedule) error {
+	if !models.IsCurrencySupported(schedule.Currency) {
+		return errors.New("unsupported currency: " + schedule.Currency)
+	}
+	if _, err := services.ParseRecurrence(schedule.Frequency); err != nil {
+		return err
+	}
+	if _, err := services.ScheduleLocation(schedule); err != nil || schedule.Timezone == "Local" {
+		return errors.New("invalid timezone: " + schedule.Timezone)
+	}
+	if schedule.EndDate != nil && schedule.EndDate.Before(schedule.StartDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+	return nil
+}
+
+// withNextOccurrence заполняет следующее наступление активного расписания
+func withNextOccurrence(schedule *models.Schedule) {
+	if !schedule.Active {
+		return
+	}
+	schedule.NextOccurrence, _ = services.NextOccurrence(*schedule)
+}
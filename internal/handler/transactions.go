@@ -2,23 +2,45 @@ package handler
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"brok/internal/models"
+	"brok/internal/services"
 	"brok/internal/storage"
 )
 
+var (
+	// errLotMatched покупку нельзя удалить, пока ее лот закрыт продажами
+	errLotMatched = errors.New("lot is already matched by sells, delete those sells first")
+	// errTradeType сделку с количеством можно превратить только в другую сделку
+	errTradeType = errors.New("trades with quantity can only be changed between buy and sell")
+	// errFeeType комиссия допустима только у сделок
+	errFeeType = errors.New("fee is only allowed for buy and sell, record other costs as a fee transaction")
+	// errSameCurrencyRate курс указан для транзакции в валюте актива
+	errSameCurrencyRate = errors.New("exchange_rate is only allowed when currency differs from the asset currency")
+	// errIncomeEdit у дохода с начислением и налогом можно менять только описание
+	errIncomeEdit = errors.New("only description can be changed for income with withholding tax, delete and recreate the income instead")
+	// errTransferLegEdit ногу перевода нельзя менять отдельно от второй ноги
+	errTransferLegEdit = errors.New("transfer legs cannot be edited, delete the transfer and create it again")
+)
+
 type TransactionHandler struct {
-	Storage storage.Storage
+	Storage         storage.Storage
+	exchangeService *services.ExchangeRateService
 }
 
-func NewTransactionHandler(s storage.Storage) *TransactionHandler {
+func NewTransactionHandler(s storage.Storage, exchangeService *services.ExchangeRateService) *TransactionHandler {
 	return &TransactionHandler{
-		Storage: s,
+		Storage:         s,
+		exchangeService: exchangeService,
 	}
 }
 
@@ -30,7 +52,7 @@ func (h *TransactionHandler) GetTransactionsByAsset(c *gin.Context) {
 		return
 	}
 
-	_, ok := userID.(string)
+	userIDStr, ok := userID.(string)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
 		return
@@ -39,14 +61,137 @@ func (h *TransactionHandler) GetTransactionsByAsset(c *gin.Context) {
 	// Получаем asset_id из параметра
 	assetID := c.Param("id")
 
-	// Получаем транзакции для указанного актива
-	transactions, err := h.Storage.GetTransactionsByAssetID(c, assetID)
+	owned, err := h.Storage.IsAssetOwnedByUser(c, assetID, userIDStr)
+	if err != nil || !owned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "asset not found or not owned by user"})
+		return
+	}
+
+	h.listTransactions(c, userIDStr, assetID)
+}
+
+// ListTransactions возвращает транзакции по всем активам пользователя
+func (h *TransactionHandler) ListTransactions(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	h.listTransactions(c, userIDStr, "")
+}
+
+// listTransactions разбирает фильтры, сортировку и курсор и отдает страницу транзакций
+func (h *TransactionHandler) listTransactions(c *gin.Context, userID, assetID string) {
+	var req models.ListTransactionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := models.TransactionFilter{
+		UserID:      userID,
+		AssetID:     assetID,
+		Types:       req.Types,
+		MinAmount:   req.MinAmount,
+		MaxAmount:   req.MaxAmount,
+		Description: req.Description,
+		SortField:   models.TransactionSortTimestamp,
+		Descending:  true,
+		Limit:       models.DefaultTransactionPageSize,
+	}
+	for _, currency := range req.Currencies {
+		filter.Currencies = append(filter.Currencies, strings.ToUpper(currency))
+	}
+	if req.Limit > 0 {
+		filter.Limit = req.Limit
+	}
+
+	// Транзакции актива без limit и cursor отдаются, как раньше, массивом целиком
+	// в хронологическом порядке; постраничный ответ — только по явному запросу
+	whole := assetID != "" && req.Limit == 0 && req.Cursor == ""
+	if whole {
+		filter.Limit = 0
+		filter.Descending = false
+	}
+
+	// Сортировка: поле, с "-" — по убыванию; по умолчанию сначала новые
+	if req.Sort != "" {
+		filter.Descending = strings.HasPrefix(req.Sort, "-")
+		filter.SortField = strings.TrimPrefix(req.Sort, "-")
+	}
+
+	if req.From != "" {
+		from, err := parseFilterTime(req.From, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, use YYYY-MM-DD or RFC3339"})
+			return
+		}
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, err := parseFilterTime(req.To, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, use YYYY-MM-DD or RFC3339"})
+			return
+		}
+		filter.To = &to
+	}
+
+	if req.Cursor != "" {
+		cursor, err := models.DecodeTransactionCursor(req.Cursor, filter.SortField)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.After = cursor
+	}
+
+	if whole {
+		transactions, err := h.Storage.ListTransactions(c, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve transactions"})
+			return
+		}
+		c.JSON(http.StatusOK, transactions)
+		return
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	pageSize := filter.Limit
+	filter.Limit++
+
+	transactions, err := h.Storage.ListTransactions(c, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve transactions"})
 		return
 	}
 
-	c.JSON(http.StatusOK, transactions)
+	page := models.TransactionPage{Items: transactions}
+	if len(transactions) > pageSize {
+		page.Items = transactions[:pageSize]
+		page.NextCursor = models.NewTransactionCursor(filter.SortField, page.Items[pageSize-1]).Encode()
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseFilterTime разбирает дату фильтра: YYYY-MM-DD (для конца периода — конец дня) или RFC3339
+func parseFilterTime(value string, endOfDay bool) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			return date.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+		}
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
 
 func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
@@ -103,42 +248,200 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		Type:        req.Type,
 		Description: req.Description,
 		Timestamp:   timestamp,
+		Ticker:      strings.ToUpper(strings.TrimSpace(req.Ticker)),
+	}
+
+	// Количество и цена имеют смысл только для сделок
+	if req.Quantity != nil || req.Price != nil {
+		if req.Type != "buy" && req.Type != "sell" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity and price are only allowed for buy and sell"})
+			return
+		}
+		if req.Quantity == nil || req.Price == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity and price must be set together"})
+			return
+		}
+
+		transaction.Quantity = *req.Quantity
+		transaction.Price = *req.Price
+
+		// Если сумма не указана, считаем ее по количеству и цене
+		if transaction.Amount == 0 {
+			transaction.Amount = transaction.Quantity * transaction.Price
+		}
+	}
+
+	// Доход: сумма к получению выводится из начисления и налога, дата выплаты задает дату транзакции
+	err = services.ApplyIncomeDetails(&transaction, services.IncomeDetails{
+		Kind:           req.IncomeKind,
+		GrossAmount:    req.GrossAmount,
+		WithholdingTax: req.WithholdingTax,
+		ExDate:         req.ExDate,
+		PayDate:        req.PayDate,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.PayDate != nil && req.Timestamp == nil {
+		transaction.Timestamp = *req.PayDate
+	}
+
+	// Транзакцию в чужой валюте переводим в валюту актива
+	asset, err := h.Storage.AssetByID(c, assetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve asset"})
+		return
+	}
+	// Инструмент из справочника задает тикер сделки
+	if req.InstrumentID != nil && *req.InstrumentID != "" {
+		instrument, err := h.Storage.InstrumentByID(c, *req.InstrumentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "instrument not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve instrument"})
+			return
+		}
+		if transaction.Ticker == "" {
+			transaction.Ticker = instrument.Symbol
+		} else if transaction.Ticker != instrument.Symbol {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ticker does not match instrument symbol " + instrument.Symbol})
+			return
+		}
+		transaction.InstrumentID = &instrument.ID
+	}
+
+	// Сделка или доход без тикера в активе, привязанном к инструменту, относится к этому инструменту
+	bySymbol := transaction.Quantity > 0 || transaction.Type == "dividend"
+	if bySymbol && transaction.Ticker == "" && asset.Symbol != nil {
+		transaction.Ticker = *asset.Symbol
+	}
+	if transaction.InstrumentID == nil && bySymbol && asset.InstrumentID != nil &&
+		asset.Symbol != nil && transaction.Ticker == *asset.Symbol {
+		transaction.InstrumentID = asset.InstrumentID
+	}
+
+	if transaction.Currency == asset.Currency {
+		if req.ExchangeRate != nil && *req.ExchangeRate != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "exchange_rate is only allowed when currency differs from the asset currency"})
+			return
+		}
+	} else if err := h.convertToAsset(c, &transaction, asset.Currency, req.ExchangeRate); err != nil {
+		if errors.Is(err, services.ErrRateNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error() + ", specify exchange_rate explicitly"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get exchange rate"})
+		return
+	}
+
+	// Комиссия по сделке переводится в валюту актива: по курсу суммы, если валюта та же,
+	// иначе по курсу на дату транзакции
+	if req.Fee != nil && *req.Fee > 0 {
+		if req.Type != "buy" && req.Type != "sell" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fee is only allowed for buy and sell, record other costs as a fee transaction"})
+			return
+		}
+		feeCurrency := strings.ToUpper(req.FeeCurrency)
+		if feeCurrency == "" {
+			feeCurrency = req.Currency
+		}
+		if !models.IsCurrencySupported(feeCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported fee currency: " + feeCurrency})
+			return
+		}
+
+		rate := 1.0
+		if feeCurrency != asset.Currency {
+			if feeCurrency == req.Currency && transaction.ExchangeRate != nil {
+				rate = *transaction.ExchangeRate
+			} else {
+				rate, err = h.exchangeService.TransactionRate(c, feeCurrency, asset.Currency, transaction.Timestamp, nil)
+				if errors.Is(err, services.ErrRateNotFound) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error() + ", specify the fee in the asset currency"})
+					return
+				}
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get exchange rate"})
+					return
+				}
+			}
+		}
+		services.ApplyFee(&transaction, *req.Fee, feeCurrency, asset.Currency, rate)
+	} else if req.FeeCurrency != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fee_currency requires fee"})
+		return
+	}
+
+	// Для продажи определяем метод подбора лотов
+	isSell := services.IsTrade(transaction) && transaction.Type == "sell"
+	var lotMethod string
+	if isSell {
+		lotMethod, err = h.lotMethod(c, assetID, userIDStr, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve lot method"})
+			return
+		}
+	} else if len(req.Lots) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lots can only be selected for sell with quantity"})
+		return
 	}
 
 	// Выполняем операции в транзакции
 	err = h.Storage.Transaction(c, func(ctx context.Context, tx storage.Tx) error {
+		// Подбираем лоты до создания продажи, чтобы не учитывать ее саму
+		var matches []models.LotMatch
+		if isSell {
+			existing, err := h.Storage.GetTransactionsByAssetIDTx(ctx, tx, assetID)
+			if err != nil {
+				return err
+			}
+			existingMatches, err := h.Storage.GetLotMatchesByAssetIDTx(ctx, tx, assetID)
+			if err != nil {
+				return err
+			}
+			lots, _ := services.ReplayLots(existing, existingMatches)
+			matches, err = services.MatchLots(lots, transaction, lotMethod, req.Lots)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Создаем транзакцию
 		if err := h.Storage.CreateTransactionTx(ctx, tx, transaction); err != nil {
 			return err
 		}
 
-		// Определяем изменение баланса
-		var balanceChange float64
-		switch req.Type {
-		case "deposit":
-			balanceChange = req.Amount
-		case "withdrawal":
-			balanceChange = -req.Amount
-		case "buy":
-			balanceChange = -req.Amount
-		case "sell":
-			balanceChange = req.Amount
-		case "revaluation":
-			balanceChange = req.Amount // может быть как +, так и -
-		case "dividend":
-			balanceChange = req.Amount
+		// Сохраняем закрытые лоты
+		for _, match := range matches {
+			if err := h.Storage.CreateLotMatchTx(ctx, tx, match); err != nil {
+				return err
+			}
 		}
 
 		// Обновляем баланс актива
+		balanceChange := services.BalanceEffect(transaction)
 		return h.Storage.UpdateAssetBalanceTx(ctx, tx, assetID, balanceChange)
 	})
 
+	if errors.Is(err, services.ErrInsufficientLots) || errors.Is(err, services.ErrInvalidLotSelection) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create transaction"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "transaction created successfully", "transaction_id": transactionID})
+	response := gin.H{"message": "transaction created successfully", "transaction_id": transactionID}
+	if transaction.ExchangeRate != nil {
+		response["amount"] = transaction.Amount
+		response["currency"] = transaction.Currency
+		response["exchange_rate"] = *transaction.ExchangeRate
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
@@ -173,31 +476,49 @@ func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 			return err
 		}
 
-		// Удаляем транзакцию
+		// Покупку, лот которой уже закрыт продажами, удалять нельзя
+		if transaction.Type == "buy" {
+			matched, err := h.Storage.IsLotMatchedTx(ctx, tx, transactionID)
+			if err != nil {
+				return err
+			}
+			if matched {
+				return errLotMatched
+			}
+		}
+
+		// Нога перевода удаляется вместе со второй ногой
+		if transaction.TransferID != nil {
+			legs, err := h.Storage.GetTransactionsByTransferIDTx(ctx, tx, *transaction.TransferID)
+			if err != nil {
+				return err
+			}
+			if err := h.Storage.DeleteTransferTx(ctx, tx, *transaction.TransferID); err != nil {
+				return err
+			}
+			for _, leg := range legs {
+				balanceChange := -services.BalanceEffect(leg)
+				if err := h.Storage.UpdateAssetBalanceTx(ctx, tx, leg.AssetID, balanceChange); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// Удаляем транзакцию (закрытия лотов продажи удаляются каскадно)
 		if err := h.Storage.DeleteTransactionTx(ctx, tx, transactionID); err != nil {
 			return err
 		}
 
 		// Отменяем эффект удаленной транзакции
-		var balanceChange float64
-		switch transaction.Type {
-		case "deposit":
-			balanceChange = -transaction.Amount
-		case "withdrawal":
-			balanceChange = transaction.Amount
-		case "buy":
-			balanceChange = transaction.Amount
-		case "sell":
-			balanceChange = -transaction.Amount
-		case "revaluation":
-			balanceChange = -transaction.Amount
-		case "dividend":
-			balanceChange = -transaction.Amount
-		}
-
+		balanceChange := -services.BalanceEffect(*transaction)
 		return h.Storage.UpdateAssetBalanceTx(ctx, tx, transaction.AssetID, balanceChange)
 	})
 
+	if errors.Is(err, errLotMatched) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete transaction"})
 		return
@@ -205,3 +526,315 @@ func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "transaction deleted successfully"})
 }
+
+// UpdateTransaction частично изменяет транзакцию: отменяет ее прежний эффект на баланс
+// и применяет новый в одной транзакции БД, в том числе при переносе в другой актив.
+// Изменения применяются к строке, заблокированной внутри транзакции БД.
+func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	// Получаем transaction_id из параметра
+	transactionID := c.Param("id")
+
+	// Проверяем, существует ли транзакция и принадлежит ли она пользователю
+	exists, err := h.Storage.IsTransactionOwnedByUser(c, transactionID, userIDStr)
+	if err != nil || !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "transaction not found or doesn't belong to user"})
+		return
+	}
+
+	var req models.UpdateTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Currency != nil {
+		currency := strings.ToUpper(*req.Currency)
+		if !models.IsCurrencySupported(currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported currency: " + currency})
+			return
+		}
+		req.Currency = &currency
+	}
+	if req.AssetID != nil {
+		owned, err := h.Storage.IsAssetOwnedByUser(c, *req.AssetID, userIDStr)
+		if err != nil || !owned {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "asset not found or doesn't belong to user"})
+			return
+		}
+	}
+
+	// Выполняем операции в транзакции
+	var updated models.Transaction
+	err = h.Storage.Transaction(c, func(ctx context.Context, tx storage.Tx) error {
+		// Блокируем транзакцию: параллельное изменение дождется этого и увидит его результат
+		current, err := h.Storage.GetTransactionByIDForUpdateTx(ctx, tx, transactionID)
+		if err != nil {
+			return err
+		}
+
+		updated, err = h.applyTransactionUpdate(ctx, *current, req)
+		if err != nil {
+			return err
+		}
+		if err := h.relinkLots(ctx, tx, userIDStr, *current, updated); err != nil {
+			return err
+		}
+
+		if err := h.Storage.UpdateAssetBalanceTx(ctx, tx, current.AssetID, -services.BalanceEffect(*current)); err != nil {
+			return err
+		}
+		if err := h.Storage.UpdateTransactionTx(ctx, tx, updated); err != nil {
+			return err
+		}
+		return h.Storage.UpdateAssetBalanceTx(ctx, tx, updated.AssetID, services.BalanceEffect(updated))
+	})
+	if errors.Is(err, errTransferLegEdit) || errors.Is(err, errIncomeEdit) || errors.Is(err, errLotMatched) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errTradeType) || errors.Is(err, errFeeType) || errors.Is(err, errSameCurrencyRate) ||
+		errors.Is(err, services.ErrInsufficientLots) || errors.Is(err, services.ErrInvalidLotSelection) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrRateNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error() + ", specify exchange_rate explicitly"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating transaction %s: %v", transactionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// applyTransactionUpdate строит измененную транзакцию из текущей. Сумма, цена и комиссия
+// пересчитываются в валюту актива заново от значений ввода.
+func (h *TransactionHandler) applyTransactionUpdate(ctx context.Context, current models.Transaction, req models.UpdateTransactionRequest) (models.Transaction, error) {
+	updated := current
+	if req.Description != nil {
+		updated.Description = *req.Description
+	}
+
+	onlyDescription := req.Amount == nil && req.Currency == nil && req.Type == nil &&
+		req.Timestamp == nil && req.AssetID == nil && req.ExchangeRate == nil
+	if onlyDescription {
+		return updated, nil
+	}
+	if current.TransferID != nil {
+		return updated, errTransferLegEdit
+	}
+	if services.HasIncomeDetails(current) {
+		return updated, errIncomeEdit
+	}
+
+	// Возвращаемся к сумме, цене и валюте ввода, чтобы сконвертировать заново
+	if current.OriginalAmount != nil && current.OriginalCurrency != nil {
+		updated.Amount = *current.OriginalAmount
+		updated.Currency = *current.OriginalCurrency
+		if current.ExchangeRate != nil && *current.ExchangeRate != 0 {
+			updated.Price = current.Price / *current.ExchangeRate
+		}
+	}
+	updated.OriginalAmount, updated.OriginalCurrency, updated.ExchangeRate = nil, nil, nil
+
+	trade := services.IsTrade(current)
+	if req.Amount != nil {
+		updated.Amount = *req.Amount
+		// Цена сделки следует за суммой, количество не меняется
+		if trade {
+			updated.Price = updated.Amount / updated.Quantity
+		}
+	}
+	if req.Currency != nil {
+		updated.Currency = *req.Currency
+	}
+	if req.Type != nil {
+		updated.Type = *req.Type
+	}
+	if trade && updated.Type != "buy" && updated.Type != "sell" {
+		return updated, errTradeType
+	}
+	if current.Fee > 0 && updated.Type != "buy" && updated.Type != "sell" {
+		return updated, errFeeType
+	}
+	if updated.Type != "dividend" {
+		updated.IncomeKind, updated.ExDate, updated.PayDate = "", nil, nil
+	} else if updated.IncomeKind == "" {
+		updated.IncomeKind = models.IncomeKindDividend
+	}
+	if req.Timestamp != nil {
+		updated.Timestamp = *req.Timestamp
+	}
+	if req.AssetID != nil {
+		updated.AssetID = *req.AssetID
+	}
+
+	asset, err := h.Storage.AssetByID(ctx, updated.AssetID)
+	if err != nil {
+		return updated, err
+	}
+
+	if updated.Currency == asset.Currency {
+		if req.ExchangeRate != nil && *req.ExchangeRate != 1 {
+			return updated, errSameCurrencyRate
+		}
+	} else {
+		// Прежний курс сохраняется, если пара валют не изменилась
+		explicit := req.ExchangeRate
+		if explicit == nil && current.ExchangeRate != nil && current.OriginalCurrency != nil &&
+			*current.OriginalCurrency == updated.Currency && current.Currency == asset.Currency {
+			explicit = current.ExchangeRate
+		}
+		if err := h.convertToAsset(ctx, &updated, asset.Currency, explicit); err != nil {
+			return updated, err
+		}
+	}
+
+	// Комиссия пересчитывается из валюты ввода: по курсу суммы, если валюта та же,
+	// иначе по курсу на дату транзакции
+	if current.Fee > 0 {
+		fee, feeCurrency := current.Fee, current.Currency
+		if current.OriginalFee != nil && current.OriginalFeeCurrency != nil {
+			fee, feeCurrency = *current.OriginalFee, *current.OriginalFeeCurrency
+		}
+		rate := 1.0
+		if feeCurrency != asset.Currency {
+			if updated.OriginalCurrency != nil && *updated.OriginalCurrency == feeCurrency && updated.ExchangeRate != nil {
+				rate = *updated.ExchangeRate
+			} else {
+				rate, err = h.exchangeService.TransactionRate(ctx, feeCurrency, asset.Currency, updated.Timestamp, nil)
+				if err != nil {
+					return updated, err
+				}
+			}
+		}
+		services.ApplyFee(&updated, fee, feeCurrency, asset.Currency, rate)
+	}
+
+	return updated, nil
+}
+
+// relinkLots согласует закрытия лотов с измененной сделкой: лот покупки, уже закрытый
+// продажами, менять нельзя, а закрытия продажи подбираются заново тем же методом
+func (h *TransactionHandler) relinkLots(ctx context.Context, tx storage.Tx, userID string, current, updated models.Transaction) error {
+	if !services.IsTrade(current) {
+		return nil
+	}
+	changed := current.Type != updated.Type || current.AssetID != updated.AssetID ||
+		!current.Timestamp.Equal(updated.Timestamp) || current.Amount != updated.Amount ||
+		current.Price != updated.Price || current.Fee != updated.Fee
+	if !changed {
+		return nil
+	}
+
+	if current.Type == "buy" {
+		matched, err := h.Storage.IsLotMatchedTx(ctx, tx, current.ID)
+		if err != nil {
+			return err
+		}
+		if matched {
+			return errLotMatched
+		}
+	}
+
+	// Прежние закрытия продажи задают метод подбора и выбранные вручную лоты
+	method := ""
+	var selections []models.LotSelection
+	if current.Type == "sell" {
+		matches, err := h.Storage.GetLotMatchesByAssetIDTx(ctx, tx, current.AssetID)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			if m.SellTransactionID != current.ID {
+				continue
+			}
+			method = m.Method
+			selections = append(selections, models.LotSelection{LotID: m.BuyTransactionID, Quantity: m.Quantity})
+		}
+		if err := h.Storage.DeleteLotMatchesBySellTx(ctx, tx, current.ID); err != nil {
+			return err
+		}
+	}
+	if updated.Type != "sell" {
+		return nil
+	}
+	if method == "" || (method == models.LotMethodSpecific && updated.AssetID != current.AssetID) {
+		var err error
+		method, err = h.lotMethod(ctx, updated.AssetID, userID, models.CreateTransactionRequest{})
+		if err != nil {
+			return err
+		}
+	}
+	if method != models.LotMethodSpecific {
+		selections = nil
+	}
+
+	transactions, err := h.Storage.GetTransactionsByAssetIDTx(ctx, tx, updated.AssetID)
+	if err != nil {
+		return err
+	}
+	others := make([]models.Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		if t.ID != current.ID {
+			others = append(others, t)
+		}
+	}
+	existingMatches, err := h.Storage.GetLotMatchesByAssetIDTx(ctx, tx, updated.AssetID)
+	if err != nil {
+		return err
+	}
+	lots, _ := services.ReplayLots(others, existingMatches)
+	matches, err := services.MatchLots(lots, updated, method, selections)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if err := h.Storage.CreateLotMatchTx(ctx, tx, match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertToAsset переводит транзакцию в валюту актива по явному курсу или курсу на дату транзакции
+func (h *TransactionHandler) convertToAsset(ctx context.Context, transaction *models.Transaction, assetCurrency string, explicit *float64) error {
+	return h.exchangeService.ConvertToAsset(ctx, transaction, assetCurrency, explicit)
+}
+
+// lotMethod определяет метод подбора лотов для продажи
+func (h *TransactionHandler) lotMethod(ctx context.Context, assetID, userID string, req models.CreateTransactionRequest) (string, error) {
+	if len(req.Lots) > 0 {
+		return models.LotMethodSpecific, nil
+	}
+	if req.LotMethod != "" {
+		return req.LotMethod, nil
+	}
+
+	asset, err := h.Storage.AssetByID(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	user, err := h.Storage.UserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return services.ResolveLotMethod("", asset.LotMethod, user.LotMethod), nil
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/services"
+	"brok/internal/storage"
+)
+
+type TransferHandler struct {
+	Storage         storage.Storage
+	exchangeService *services.ExchangeRateService
+}
+
+func NewTransferHandler(s storage.Storage, exchangeService *services.ExchangeRateService) *TransferHandler {
+	return &TransferHandler{
+		Storage:         s,
+		exchangeService: exchangeService,
+	}
+}
+
+// CreateTransfer переводит средства между двумя активами пользователя:
+// вывод с источника (с комиссией сверх суммы) и ввод на получатель по курсу
+func (h *TransferHandler) CreateTransfer(c *gin.Context) {
+	// Извлекаем user_id из контекста
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.CreateTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Оба актива должны принадлежать пользователю
+	for _, assetID := range []string{req.SourceAssetID, req.TargetAssetID} {
+		owned, err := h.Storage.IsAssetOwnedByUser(c, assetID, userIDStr)
+		if err != nil || !owned {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "asset not found or doesn't belong to user: " + assetID})
+			return
+		}
+	}
+
+	source, err := h.Storage.AssetByID(c, req.SourceAssetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve asset"})
+		return
+	}
+	target, err := h.Storage.AssetByID(c, req.TargetAssetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve asset"})
+		return
+	}
+
+	timestamp := time.Now()
+	if req.Timestamp != nil {
+		timestamp = *req.Timestamp
+	}
+
+	// Курс источник -> получатель: явный или на дату перевода
+	rate := 1.0
+	if source.Currency != target.Currency {
+		rate, err = h.exchangeService.TransactionRate(c, source.Currency, target.Currency, timestamp, req.ExchangeRate)
+		if errors.Is(err, services.ErrRateNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error() + ", specify exchange_rate explicitly"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get exchange rate"})
+			return
+		}
+	} else if req.ExchangeRate != nil && *req.ExchangeRate != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exchange_rate is only allowed between assets in different currencies"})
+		return
+	}
+
+	transferID := uuid.New().String()
+
+	// Вывод с источника; комиссия списывается с него сверх суммы перевода
+	withdrawal := models.Transaction{
+		ID:          uuid.New().String(),
+		AssetID:     source.ID,
+		Amount:      req.Amount,
+		Currency:    source.Currency,
+		Type:        "withdrawal",
+		Description: req.Description,
+		Timestamp:   timestamp,
+		TransferID:  &transferID,
+	}
+
+	// Комиссия в чужой валюте переводится в валюту источника по курсу на дату перевода
+	if req.Fee != nil && *req.Fee > 0 {
+		feeCurrency := strings.ToUpper(req.FeeCurrency)
+		if feeCurrency == "" {
+			feeCurrency = source.Currency
+		}
+		if !models.IsCurrencySupported(feeCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported fee currency: " + feeCurrency})
+			return
+		}
+
+		feeRate := 1.0
+		if feeCurrency != source.Currency {
+			feeRate, err = h.exchangeService.TransactionRate(c, feeCurrency, source.Currency, timestamp, nil)
+			if errors.Is(err, services.ErrRateNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error() + ", specify the fee in the source asset currency"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get exchange rate"})
+				return
+			}
+		}
+		services.ApplyFee(&withdrawal, *req.Fee, feeCurrency, source.Currency, feeRate)
+	} else if req.FeeCurrency != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fee_currency requires fee"})
+		return
+	}
+
+	// Ввод на получатель в его валюте
+	deposit := models.Transaction{
+		ID:          uuid.New().String(),
+		AssetID:     target.ID,
+		Amount:      req.Amount,
+		Currency:    source.Currency,
+		Type:        "deposit",
+		Description: req.Description,
+		Timestamp:   timestamp,
+		TransferID:  &transferID,
+	}
+	services.ConvertTransaction(&deposit, target.Currency, rate)
+
+	transfer := models.Transfer{
+		ID:             transferID,
+		UserID:         userIDStr,
+		Amount:         req.Amount,
+		Currency:       source.Currency,
+		TargetAmount:   deposit.Amount,
+		TargetCurrency: target.Currency,
+		ExchangeRate:   rate,
+		Fee:            withdrawal.Fee,
+		Description:    req.Description,
+		Timestamp:      timestamp,
+		CreatedAt:      time.Now(),
+		Transactions:   []models.Transaction{withdrawal, deposit},
+	}
+
+	if transfer.TargetAmount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "converted amount is too small"})
+		return
+	}
+
+	// Перевод и обе ноги создаются атомарно
+	err = h.Storage.Transaction(c, func(ctx context.Context, tx storage.Tx) error {
+		if err := h.Storage.CreateTransferTx(ctx, tx, transfer); err != nil {
+			return err
+		}
+
+		for _, leg := range transfer.Transactions {
+			if err := h.Storage.CreateTransactionTx(ctx, tx, leg); err != nil {
+				return err
+			}
+			if err := h.Storage.UpdateAssetBalanceTx(ctx, tx, leg.AssetID, services.BalanceEffect(leg)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create transfer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brok/internal/models"
+	"brok/internal/services"
+)
+
+// TwoFactorHandler обработчик двухфакторной аутентификации
+type TwoFactorHandler struct {
+	Service *services.TwoFactorService
+}
+
+// NewTwoFactorHandler создает новый обработчик 2FA
+func NewTwoFactorHandler(service *services.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{Service: service}
+}
+
+// LoginTwoFactor второй шаг входа: обменивает токен из /auth/login и код на пару токенов
+func (h *TwoFactorHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	tokens, err := h.Service.CompleteLogin(c, req.ChallengeToken, req.Code, c.GetHeader("User-Agent"), c.ClientIP())
+	if errors.Is(err, services.ErrTwoFactorLocked) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidChallenge) || errors.Is(err, services.ErrInvalidTwoFactorCode) ||
+		errors.Is(err, services.ErrTwoFactorNotEnabled) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error completing two-factor login: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// GetStatus возвращает состояние 2FA пользователя
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	status, err := h.Service.Status(c, userIDStr)
+	if err != nil {
+		log.Printf("Error fetching two-factor status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Enroll начинает подключение 2FA и возвращает секрет и otpauth:// URI для QR-кода
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	enrollment, err := h.Service.Enroll(c, userIDStr)
+	if errors.Is(err, services.ErrTwoFactorEnabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error enrolling two-factor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start two-factor enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Confirm включает 2FA по первому коду из приложения и возвращает коды восстановления
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	codes, err := h.Service.Confirm(c, userIDStr, req.Code)
+	if err != nil {
+		h.respondError(c, err, "failed to enable two-factor")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable выключает 2FA; требует пароль и код TOTP или код восстановления
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.TwoFactorReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.Service.Disable(c, userIDStr, req.Password, req.Code); err != nil {
+		h.respondError(c, err, "failed to disable two-factor")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes выдает новый набор кодов восстановления вместо прежнего;
+// требует пароль и код TOTP или код восстановления
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req models.TwoFactorReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	codes, err := h.Service.RegenerateRecoveryCodes(c, userIDStr, req.Password, req.Code)
+	if err != nil {
+		h.respondError(c, err, "failed to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// respondError отвечает на ошибку изменения 2FA подходящим статусом
+func (h *TwoFactorHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPassword), errors.Is(err, services.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error changing two-factor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
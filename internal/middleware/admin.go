@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brok/internal/storage"
+)
+
+// RequireAdmin - middleware, пропускающий только администраторов.
+// Должен стоять после JWTAuth, который кладет user_id в контекст.
+func RequireAdmin(s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		userIDStr, isString := userID.(string)
+		if !ok || !isString {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+			return
+		}
+
+		user, err := s.UserByID(c, userIDStr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+
+		if !user.IsAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -6,11 +6,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"brok/internal/storage"
 	"brok/internal/utils"
 )
 
-// JWTAuth - middleware для аутентификации с использованием JWT
-func JWTAuth() gin.HandlerFunc {
+// JWTAuth - middleware для аутентификации с использованием JWT.
+// Токен должен принадлежать активной сессии и не быть отозванным (см. storage.IsTokenRevoked).
+func JWTAuth(s storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Читаем заголовок Authorization
 		authHeader := c.GetHeader("Authorization")
@@ -35,8 +37,25 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
-		// Прокладываем user_id из claims в контекст Gin
+		// Токены без jti и сессии выпущены до появления сессий и не могут быть отозваны
+		if claims.ID == "" || claims.SessionID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			return
+		}
+
+		revoked, err := s.IsTokenRevoked(c, claims.ID, claims.SessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check token"})
+			return
+		}
+		if revoked {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+			return
+		}
+
+		// Прокладываем user_id и claims токена в контекст Gin
 		c.Set("user_id", claims.UserID)
+		c.Set("token_claims", claims)
 
 		// Переходим к следующему обработчику
 		c.Next()
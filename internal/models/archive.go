@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// ArchiveFormat признак архива brok
+const ArchiveFormat = "brok-archive"
+
+// ArchiveSchemaVersion версия схемы архива. Увеличивается при несовместимых изменениях:
+// архивы других версий не импортируются.
+const ArchiveSchemaVersion = 1
+
+// Archive выгрузка всех данных пользователя
+type Archive struct {
+	Format        string         `json:"format"`
+	SchemaVersion int            `json:"schema_version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Profile       ArchiveProfile `json:"profile"`
+	Assets        []Asset        `json:"assets"`
+	Transactions  []Transaction  `json:"transactions"`
+	Transfers     []Transfer     `json:"transfers"`
+	LotMatches    []LotMatch     `json:"lot_matches"`
+	ExchangeRates []ExchangeRate `json:"exchange_rates"` // курсы для пар валют, встречающихся в данных; при импорте не загружаются
+}
+
+// ArchiveProfile настройки пользователя в архиве
+type ArchiveProfile struct {
+	Email        string    `json:"email"`
+	BaseCurrency string    `json:"base_currency"`
+	LotMethod    string    `json:"lot_method"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ArchiveImportReport итог восстановления из архива
+type ArchiveImportReport struct {
+	Assets       int               `json:"assets"`
+	Transactions int               `json:"transactions"`
+	Transfers    int               `json:"transfers"`
+	LotMatches   int               `json:"lot_matches"`
+	AssetIDs     map[string]string `json:"asset_ids"` // ID актива в архиве -> новый ID
+
+	// Применены ли базовая валюта и метод подбора лотов из профиля архива
+	SettingsApplied bool `json:"settings_applied"`
+}
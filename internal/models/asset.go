@@ -6,13 +6,17 @@ import (
 
 // Asset представляет актив пользователя
 type Asset struct {
-	ID        string    `db:"id" json:"id"`
-	UserID    string    `db:"user_id" json:"user_id"`
-	Name      string    `db:"name" json:"name"`
-	Type      string    `db:"type" json:"type"`
-	Balance   float64   `db:"balance" json:"balance"`
-	Currency  string    `db:"currency" json:"currency"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID           string    `db:"id" json:"id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Name         string    `db:"name" json:"name"`
+	Type         string    `db:"type" json:"type"`
+	LegacyType   *string   `db:"legacy_type" json:"legacy_type,omitempty"` // Исходный тип, не приведенный к классу активов
+	Balance      float64   `db:"balance" json:"balance"`
+	Currency     string    `db:"currency" json:"currency"`
+	LotMethod    *string   `db:"lot_method" json:"lot_method,omitempty"`       // Метод подбора лотов (nil — метод пользователя)
+	Symbol       *string   `db:"symbol" json:"symbol,omitempty"`               // Инструмент для оценки по рыночной цене
+	InstrumentID *string   `db:"instrument_id" json:"instrument_id,omitempty"` // Инструмент из справочника
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
 
 	// XIRR доходность (не хранится в БД, только для ответа)
 	Xirr *float64 `json:"xirr,omitempty"`
@@ -24,4 +28,20 @@ type Asset struct {
 	Apr *float64 `json:"apr,omitempty"`
 
 	Profit *float64 `json:"profit,omitempty"`
+
+	// Все комиссии и расходы по активу (не хранится в БД, только для ответа)
+	Fees *float64 `json:"fees,omitempty"`
+
+	// Доходность, взвешенная по времени, с первой операции (не хранится в БД, только для ответа)
+	Twr *float64 `json:"twr,omitempty"`
+
+	// Позиции по инструментам (не хранятся в БД, только для ответа)
+	Positions []Position `json:"positions,omitempty"`
+
+	// Реализованная и нереализованная прибыль по позициям (не хранятся в БД, только для ответа)
+	RealizedProfit   *float64 `json:"realized_profit,omitempty"`
+	UnrealizedProfit *float64 `json:"unrealized_profit,omitempty"`
+
+	// Рыночная стоимость позиции по инструменту актива (не хранится в БД, только для ответа)
+	MarketValue *float64 `json:"market_value,omitempty"`
 }
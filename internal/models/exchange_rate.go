@@ -11,6 +11,7 @@ type ExchangeRate struct {
 	ToCurrency   string    `db:"to_currency" json:"to_currency"`
 	Rate         float64   `db:"rate" json:"rate"`
 	Timestamp    time.Time `db:"timestamp" json:"timestamp"`
+	Provider     string    `db:"provider" json:"provider"` // Источник курса
 	CreatedAt    time.Time `db:"created_at" json:"created_at"`
 }
 
@@ -58,3 +59,44 @@ func GetSupportedCurrencies() []SupportedCurrency {
 	}
 	return currencies
 }
+
+// BackfillRequest запрос на загрузку истории курсов
+type BackfillRequest struct {
+	From       string   `json:"from" form:"from" binding:"required"` // YYYY-MM-DD
+	To         string   `json:"to" form:"to" binding:"required"`     // YYYY-MM-DD
+	Currencies []string `json:"currencies" form:"currencies"`        // по умолчанию — все поддерживаемые
+}
+
+// BackfillReport результат загрузки истории курсов
+type BackfillReport struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Currencies []string  `json:"currencies"`
+	Source     string    `json:"source"`
+	Inserted   int       `json:"inserted"` // сохранено новых курсов
+	Skipped    int       `json:"skipped"`  // курсы на эти даты уже были
+
+	// Рабочие дни, на которые после загрузки нет курса хотя бы для одной пары
+	// (выходные и праздники источника не учитываются)
+	Gaps []string `json:"gaps"`
+}
+
+// RateLeg курс из базы, фактически использованный при расчете
+type RateLeg struct {
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         float64   `json:"rate"`     // курс в направлении from -> to
+	Date         time.Time `json:"date"`     // дата найденного курса
+	Inverted     bool      `json:"inverted"` // найден обратный курс, использовано 1/rate
+	Provider     string    `json:"provider,omitempty"`
+}
+
+// RateResolution как был получен курс: напрямую или через промежуточную валюту
+type RateResolution struct {
+	FromCurrency  string     `json:"from_currency"`
+	ToCurrency    string     `json:"to_currency"`
+	Rate          float64    `json:"rate"`
+	RequestedDate *time.Time `json:"requested_date,omitempty"` // nil — запрошен последний курс
+	Pivot         string     `json:"pivot,omitempty"`          // промежуточная валюта для кросс-курса
+	Legs          []RateLeg  `json:"legs"`
+}
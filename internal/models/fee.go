@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Виды расходов в отчете о комиссиях
+const (
+	FeeKindTrade    = "trade"    // комиссии по сделкам buy/sell
+	FeeKindTransfer = "transfer" // комиссии переводов между активами
+	FeeKindOther    = "other"    // транзакции fee: обслуживание, хранение и т.п.
+)
+
+// FeeGroup расходы группы (месяца, вида) в базовой валюте
+type FeeGroup struct {
+	Key    string  `json:"key"`
+	Amount float64 `json:"amount"`
+}
+
+// FeeAsset расходы актива за период
+type FeeAsset struct {
+	AssetID      string  `json:"asset_id"`
+	Name         string  `json:"name"`
+	Currency     string  `json:"currency"`
+	TradeFees    float64 `json:"trade_fees"` // в валюте актива
+	TransferFees float64 `json:"transfer_fees"`
+	OtherFees    float64 `json:"other_fees"`
+	Total        float64 `json:"total"`      // в валюте актива
+	TotalBase    float64 `json:"total_base"` // в базовой валюте
+	Events       int     `json:"events"`
+
+	// Total / текущая стоимость актива, 0..1; нет, если стоимость не положительна
+	CostRatio *float64 `json:"cost_ratio,omitempty"`
+}
+
+// FeeReport комиссии и расходы пользователя за период в базовой валюте
+type FeeReport struct {
+	BaseCurrency string     `json:"base_currency"`
+	From         time.Time  `json:"from"`
+	To           time.Time  `json:"to"`
+	Total        float64    `json:"total"`
+	ByKind       []FeeGroup `json:"by_kind"`  // key — trade, transfer или other
+	ByMonth      []FeeGroup `json:"by_month"` // key — YYYY-MM, по возрастанию
+	ByAsset      []FeeAsset `json:"by_asset"` // от больших расходов к меньшим
+
+	// Валютные пары без курса: такие расходы не вошли в итоги
+	MissingRates []string `json:"missing_rates,omitempty"`
+}
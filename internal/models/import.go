@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// TypeMap сопоставление значений колонки типа из выписки с типами транзакций
+type TypeMap map[string]string
+
+// Value сохраняет сопоставление в JSONB
+func (m TypeMap) Value() (driver.Value, error) {
+	if m == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan читает сопоставление из JSONB
+func (m *TypeMap) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = TypeMap{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	}
+	return errors.New("unsupported type_map value")
+}
+
+// ImportProfile профиль сопоставления колонок CSV-выписки (по заголовкам) с полями транзакции
+type ImportProfile struct {
+	ID                string    `db:"id" json:"id"`
+	UserID            string    `db:"user_id" json:"user_id"`
+	Name              string    `db:"name" json:"name"`
+	Delimiter         string    `db:"delimiter" json:"delimiter"`
+	DateColumn        string    `db:"date_column" json:"date_column"`
+	DateFormat        string    `db:"date_format" json:"date_format"` // формат Go, например 02.01.2006
+	TypeColumn        string    `db:"type_column" json:"type_column"` // если пусто — тип по знаку суммы
+	AmountColumn      string    `db:"amount_column" json:"amount_column"`
+	CurrencyColumn    string    `db:"currency_column" json:"currency_column"` // если пусто — валюта актива
+	DescriptionColumn string    `db:"description_column" json:"description_column"`
+	TypeMap           TypeMap   `db:"type_map" json:"type_map"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+}
+
+// CreateImportProfileRequest используется для данных при создании профиля импорта
+type CreateImportProfileRequest struct {
+	Name              string  `json:"name" binding:"required,max=100"`
+	Delimiter         string  `json:"delimiter" binding:"omitempty,len=1"`
+	DateColumn        string  `json:"date_column" binding:"required,max=100"`
+	DateFormat        string  `json:"date_format" binding:"omitempty,max=50"`
+	TypeColumn        string  `json:"type_column" binding:"omitempty,max=100"`
+	AmountColumn      string  `json:"amount_column" binding:"required,max=100"`
+	CurrencyColumn    string  `json:"currency_column" binding:"omitempty,max=100"`
+	DescriptionColumn string  `json:"description_column" binding:"omitempty,max=100"`
+	TypeMap           TypeMap `json:"type_map" binding:"omitempty,dive,keys,required,endkeys,oneof=deposit withdrawal buy sell revaluation dividend fee"`
+}
+
+// ImportRow строка выписки, разобранная в запрос на создание транзакции
+type ImportRow struct {
+	Row     int // номер строки в файле, начиная с 1 (с учетом заголовка)
+	Request CreateTransactionRequest
+	Err     error
+}
+
+// ImportRowResult результат проверки строки выписки
+type ImportRowResult struct {
+	Row         int          `json:"row"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Duplicate   bool         `json:"duplicate,omitempty"` // такая транзакция уже есть, строка пропускается
+	Error       string       `json:"error,omitempty"`
+}
+
+// ImportReport итог импорта выписки
+type ImportReport struct {
+	DryRun     bool              `json:"dry_run"`
+	Total      int               `json:"total"`
+	Valid      int               `json:"valid"`
+	Invalid    int               `json:"invalid"`
+	Duplicates int               `json:"duplicates"`
+	Imported   int               `json:"imported"`
+	Rows       []ImportRowResult `json:"rows"`
+}
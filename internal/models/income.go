@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// Виды дохода по транзакциям dividend
+const (
+	IncomeKindDividend = "dividend"
+	IncomeKindCoupon   = "coupon"
+)
+
+// IncomeKindOf возвращает вид дохода транзакции; для записей без вида — dividend
+func IncomeKindOf(tx Transaction) string {
+	if tx.IncomeKind == "" {
+		return IncomeKindDividend
+	}
+	return tx.IncomeKind
+}
+
+// IncomeGroup доход группы (месяца, валюты выплаты, вида дохода) в базовой валюте
+type IncomeGroup struct {
+	Key   string  `json:"key"`
+	Gross float64 `json:"gross"`
+	Tax   float64 `json:"tax"`
+	Net   float64 `json:"net"`
+}
+
+// IncomeAsset доход актива за период в базовой валюте и доходность за 12 месяцев
+type IncomeAsset struct {
+	AssetID  string  `json:"asset_id"`
+	Name     string  `json:"name"`
+	Currency string  `json:"currency"`
+	Gross    float64 `json:"gross"`
+	Tax      float64 `json:"tax"`
+	Net      float64 `json:"net"`
+	Events   int     `json:"events"`
+
+	// Доход за последние 12 месяцев в валюте актива (независимо от периода отчета)
+	TTMGross float64 `json:"ttm_gross"`
+	TTMNet   float64 `json:"ttm_net"`
+	// TTMGross / текущая стоимость актива, 0..1; нет, если стоимость не положительна
+	TTMYield *float64 `json:"ttm_yield,omitempty"`
+}
+
+// IncomeReport доходы пользователя за период в базовой валюте
+type IncomeReport struct {
+	BaseCurrency string        `json:"base_currency"`
+	From         time.Time     `json:"from"`
+	To           time.Time     `json:"to"`
+	TotalGross   float64       `json:"total_gross"`
+	TotalTax     float64       `json:"total_tax"`
+	TotalNet     float64       `json:"total_net"`
+	ByMonth      []IncomeGroup `json:"by_month"`    // key — YYYY-MM, по возрастанию
+	ByAsset      []IncomeAsset `json:"by_asset"`    // от большего дохода к меньшему
+	ByCurrency   []IncomeGroup `json:"by_currency"` // key — валюта выплаты
+	ByKind       []IncomeGroup `json:"by_kind"`     // key — dividend или coupon
+
+	// Валютные пары без курса: такие выплаты не вошли в итоги
+	MissingRates []string `json:"missing_rates,omitempty"`
+}
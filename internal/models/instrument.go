@@ -0,0 +1,121 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Классы активов — допустимые значения Asset.Type
+const (
+	AssetClassStock      = "stock"
+	AssetClassBond       = "bond"
+	AssetClassETF        = "etf"
+	AssetClassFund       = "fund"
+	AssetClassDeposit    = "deposit"
+	AssetClassCash       = "cash"
+	AssetClassCrypto     = "crypto"
+	AssetClassCommodity  = "commodity"
+	AssetClassRealEstate = "real_estate"
+	AssetClassOther      = "other"
+)
+
+// assetClassAliases распространенные написания классов активов
+var assetClassAliases = map[string]string{
+	"stocks": AssetClassStock, "share": AssetClassStock, "shares": AssetClassStock, "equity": AssetClassStock,
+	"акции": AssetClassStock, "акция": AssetClassStock,
+	"bonds": AssetClassBond, "облигации": AssetClassBond, "облигация": AssetClassBond,
+	"etfs":  AssetClassETF,
+	"funds": AssetClassFund, "mutual_fund": AssetClassFund, "пиф": AssetClassFund,
+	"deposits": AssetClassDeposit, "вклад": AssetClassDeposit, "депозит": AssetClassDeposit,
+	"account": AssetClassCash, "наличные": AssetClassCash, "счет": AssetClassCash,
+	"cryptocurrency": AssetClassCrypto, "криптовалюта": AssetClassCrypto,
+	"commodities": AssetClassCommodity, "metal": AssetClassCommodity, "gold": AssetClassCommodity, "золото": AssetClassCommodity,
+	"realty": AssetClassRealEstate, "недвижимость": AssetClassRealEstate,
+}
+
+// GetAssetClasses возвращает список классов активов
+func GetAssetClasses() []string {
+	return []string{
+		AssetClassStock, AssetClassBond, AssetClassETF, AssetClassFund, AssetClassDeposit,
+		AssetClassCash, AssetClassCrypto, AssetClassCommodity, AssetClassRealEstate, AssetClassOther,
+	}
+}
+
+// IsAssetClassSupported проверяет, входит ли значение в список классов активов
+func IsAssetClassSupported(class string) bool {
+	for _, supported := range GetAssetClasses() {
+		if class == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeAssetClass приводит написание класса актива к значению из списка ("Shares" -> "stock").
+// Возвращает false, если значение не распознано.
+func NormalizeAssetClass(value string) (string, bool) {
+	class := strings.ToLower(strings.TrimSpace(value))
+	if alias, ok := assetClassAliases[class]; ok {
+		class = alias
+	}
+	return class, IsAssetClassSupported(class)
+}
+
+// Instrument инструмент из справочника
+type Instrument struct {
+	ID         string    `db:"id" json:"id"`
+	Symbol     string    `db:"symbol" json:"symbol"`
+	ISIN       *string   `db:"isin" json:"isin,omitempty"`
+	Name       string    `db:"name" json:"name"`
+	AssetClass string    `db:"asset_class" json:"asset_class"`
+	Exchange   string    `db:"exchange" json:"exchange"`
+	Currency   string    `db:"currency" json:"currency"`
+	LotSize    int       `db:"lot_size" json:"lot_size"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CreateInstrumentRequest используется для данных при создании инструмента
+type CreateInstrumentRequest struct {
+	Symbol     string  `json:"symbol" binding:"required,max=20"`
+	ISIN       *string `json:"isin" binding:"omitempty,len=12"`
+	Name       string  `json:"name" binding:"required,max=255"`
+	AssetClass string  `json:"asset_class" binding:"required"`
+	Exchange   string  `json:"exchange" binding:"omitempty,max=20"`
+	Currency   string  `json:"currency" binding:"required,len=3"`
+	LotSize    int     `json:"lot_size" binding:"omitempty,gt=0"`
+}
+
+// UpdateInstrumentRequest используется для частичного изменения инструмента
+type UpdateInstrumentRequest struct {
+	Symbol     *string `json:"symbol" binding:"omitempty,max=20"`
+	ISIN       *string `json:"isin" binding:"omitempty,max=12"` // пустая строка удаляет ISIN
+	Name       *string `json:"name" binding:"omitempty,max=255"`
+	AssetClass *string `json:"asset_class"`
+	Exchange   *string `json:"exchange" binding:"omitempty,max=20"`
+	Currency   *string `json:"currency" binding:"omitempty,len=3"`
+	LotSize    *int    `json:"lot_size" binding:"omitempty,gt=0"`
+}
+
+// InstrumentFilter условия выборки инструментов
+type InstrumentFilter struct {
+	Query      string // подстрока тикера, ISIN или названия
+	AssetClass string
+	Exchange   string
+	Limit      int
+}
+
+// InstrumentRowError ошибка в строке файла инструментов
+type InstrumentRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// InstrumentImportReport итог загрузки справочника из CSV
+type InstrumentImportReport struct {
+	DryRun  bool                 `json:"dry_run"`
+	Total   int                  `json:"total"`
+	Created int                  `json:"created"`
+	Updated int                  `json:"updated"`
+	Errors  []InstrumentRowError `json:"errors"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// Методы подбора лотов при продаже
+const (
+	LotMethodFIFO     = "fifo"     // первыми закрываются самые ранние покупки
+	LotMethodLIFO     = "lifo"     // первыми закрываются самые поздние покупки
+	LotMethodHIFO     = "hifo"     // первыми закрываются самые дорогие покупки
+	LotMethodSpecific = "specific" // лоты указаны явно в запросе на продажу
+)
+
+// Количество дней владения, после которого доход считается долгосрочным
+const LongTermHoldingDays = 365
+
+// IsLotMethodSupported проверяет, можно ли использовать метод по умолчанию
+func IsLotMethodSupported(method string) bool {
+	switch method {
+	case LotMethodFIFO, LotMethodLIFO, LotMethodHIFO:
+		return true
+	}
+	return false
+}
+
+// Lot лот — покупка и еще не проданный остаток по ней
+type Lot struct {
+	TransactionID string    `json:"lot_id"`
+	AssetID       string    `json:"asset_id"`
+	Ticker        string    `json:"ticker"`
+	AcquiredAt    time.Time `json:"acquired_at"`
+	Quantity      float64   `json:"quantity"`
+	Remaining     float64   `json:"remaining"`
+	UnitCost      float64   `json:"unit_cost"`
+	CostBasis     float64   `json:"cost_basis"` // стоимость оставшейся части лота
+}
+
+// LotMatch закрытие части лота продажей
+type LotMatch struct {
+	ID                string    `db:"id" json:"id"`
+	AssetID           string    `db:"asset_id" json:"asset_id"`
+	SellTransactionID string    `db:"sell_transaction_id" json:"sell_transaction_id"`
+	BuyTransactionID  string    `db:"buy_transaction_id" json:"lot_id"`
+	Ticker            string    `db:"ticker" json:"ticker"`
+	Method            string    `db:"method" json:"method"`
+	Quantity          float64   `db:"quantity" json:"quantity"`
+	CostBasis         float64   `db:"cost_basis" json:"cost_basis"`
+	Proceeds          float64   `db:"proceeds" json:"proceeds"`
+	RealizedGain      float64   `db:"realized_gain" json:"realized_gain"`
+	AcquiredAt        time.Time `db:"acquired_at" json:"acquired_at"`
+	DisposedAt        time.Time `db:"disposed_at" json:"disposed_at"`
+
+	// Срок владения (не хранится в БД, только для ответа)
+	HoldingDays int  `db:"-" json:"holding_days"`
+	LongTerm    bool `db:"-" json:"long_term"`
+}
+
+// LotSelection явно выбранный лот для продажи
+type LotSelection struct {
+	LotID    string  `json:"lot_id" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"required,gt=0"`
+}
+
+// RealizedGainsResponse реализованная прибыль по активу
+type RealizedGainsResponse struct {
+	Matches        []LotMatch `json:"matches"`
+	TotalRealized  float64    `json:"total_realized"`
+	ShortTermGain  float64    `json:"short_term_gain"`
+	LongTermGain   float64    `json:"long_term_gain"`
+	TotalProceeds  float64    `json:"total_proceeds"`
+	TotalCostBasis float64    `json:"total_cost_basis"`
+}
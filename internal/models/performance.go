@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Периоды расчета доходности
+const (
+	PeriodMTD       = "mtd"       // с начала месяца
+	PeriodQTD       = "qtd"       // с начала квартала
+	PeriodYTD       = "ytd"       // с начала года
+	Period1Y        = "1y"        // за последний год
+	PeriodInception = "inception" // с первой операции
+	PeriodCustom    = "custom"    // произвольный период from/to
+)
+
+// AssetPerformance доходность актива за период
+type AssetPerformance struct {
+	AssetID  string   `json:"asset_id"`
+	Name     string   `json:"name"`
+	Currency string   `json:"currency"`
+	Twr      *float64 `json:"twr,omitempty"` // time-weighted return, в долях
+}
+
+// PerformanceResponse доходность портфеля и активов за период
+type PerformanceResponse struct {
+	Period       string             `json:"period"`
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	BaseCurrency string             `json:"base_currency"`
+	PortfolioTwr *float64           `json:"portfolio_twr,omitempty"` // в базовой валюте, в долях
+	Assets       []AssetPerformance `json:"assets"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+)
+
+// PortfolioAsset актив в сводке портфеля, суммы в базовой валюте
+type PortfolioAsset struct {
+	AssetID  string  `json:"asset_id"`
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"` // в валюте актива
+	Value    float64 `json:"value"`   // в базовой валюте
+	Invested float64 `json:"invested"`
+	Profit   float64 `json:"profit"`
+	Share    float64 `json:"share"` // доля в портфеле, 0..1
+}
+
+// AllocationEntry доля группы активов в портфеле
+type AllocationEntry struct {
+	Key   string  `json:"key"`
+	Value float64 `json:"value"`
+	Share float64 `json:"share"`
+}
+
+// PortfolioSummary сводка по портфелю в базовой валюте пользователя
+type PortfolioSummary struct {
+	BaseCurrency   string            `json:"base_currency"`
+	TotalValue     float64           `json:"total_value"`
+	TotalInvested  float64           `json:"total_invested"` // вложения минус выводы
+	TotalDeposits  float64           `json:"total_deposits"`
+	TotalWithdrawn float64           `json:"total_withdrawn"`
+	TotalDividends float64           `json:"total_dividends"`
+	TotalProfit    float64           `json:"total_profit"`
+	Xirr           *float64          `json:"xirr,omitempty"`
+	Assets         []PortfolioAsset  `json:"assets"`
+	ByType         []AllocationEntry `json:"allocation_by_type"`
+	ByCurrency     []AllocationEntry `json:"allocation_by_currency"`
+
+	// Валютные пары без курса: такие активы не вошли в итоги
+	MissingRates []string `json:"missing_rates,omitempty"`
+}
+
+// Интервалы точек истории портфеля
+const (
+	HistoryIntervalDay   = "day"
+	HistoryIntervalWeek  = "week"
+	HistoryIntervalMonth = "month"
+)
+
+// HistoryAssetValue стоимость актива в точке истории
+type HistoryAssetValue struct {
+	AssetID  string  `json:"asset_id"`
+	Name     string  `json:"name"`
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"` // в валюте актива
+	Rate     float64 `json:"rate"`    // курс валюты актива к базовой
+	Value    float64 `json:"value"`   // в базовой валюте
+
+	// Курса на дату нет, использован последний известный
+	Approximate bool `json:"approximate,omitempty"`
+}
+
+// HistoryPoint точка истории стоимости портфеля
+type HistoryPoint struct {
+	Date       time.Time           `json:"date"`
+	TotalValue float64             `json:"total_value"`
+	Assets     []HistoryAssetValue `json:"assets"`
+}
+
+// PortfolioHistory история стоимости портфеля в базовой валюте
+type PortfolioHistory struct {
+	BaseCurrency string         `json:"base_currency"`
+	Interval     string         `json:"interval"`
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	Points       []HistoryPoint `json:"points"`
+
+	// Валютные пары без курса: такие активы не вошли в итоги
+	MissingRates []string `json:"missing_rates,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// Position представляет позицию по инструменту внутри актива
+type Position struct {
+	Ticker           string     `json:"ticker"`
+	Quantity         float64    `json:"quantity"`
+	AverageCost      float64    `json:"average_cost"`
+	CostBasis        float64    `json:"cost_basis"`
+	LastPrice        float64    `json:"last_price"`
+	PriceDate        *time.Time `json:"price_date,omitempty"` // дата рыночной цены; nil — цена последней сделки
+	MarketValue      float64    `json:"market_value"`
+	RealizedProfit   float64    `json:"realized_profit"`
+	UnrealizedProfit float64    `json:"unrealized_profit"`
+	PriceStale       bool       `json:"price_stale,omitempty"` // рыночную цену не удалось получить или она старше недели
+}
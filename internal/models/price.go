@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Price цена закрытия инструмента за день
+type Price struct {
+	Symbol    string    `db:"symbol" json:"symbol"`
+	Date      time.Time `db:"date" json:"date"`
+	Close     float64   `db:"close" json:"close"`
+	Currency  string    `db:"currency" json:"currency"`
+	Provider  string    `db:"provider" json:"provider"` // Источник цены
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// MarkToMarket результат переоценки актива по рыночной цене
+type MarkToMarket struct {
+	AssetID       string    `json:"asset_id"`
+	Symbol        string    `json:"symbol"`
+	Quantity      float64   `json:"quantity"`
+	Price         float64   `json:"price"` // в валюте актива
+	PriceDate     time.Time `json:"price_date"`
+	MarketValue   float64   `json:"market_value"`
+	Adjustment    float64   `json:"adjustment"`               // сумма созданной переоценки
+	TransactionID *string   `json:"transaction_id,omitempty"` // nil, если стоимость не изменилась
+}
+
+// PriceUpdateReport результат загрузки цен и переоценки активов
+type PriceUpdateReport struct {
+	Symbols []string       `json:"symbols"`
+	Saved   int            `json:"saved"`
+	Missing []string       `json:"missing"` // инструменты, для которых источники не вернули цену
+	Marked  []MarkToMarket `json:"marked"`
+	Errors  []string       `json:"errors,omitempty"`
+}
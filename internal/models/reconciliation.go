@@ -0,0 +1,37 @@
+package models
+
+// Режимы сверки балансов с журналом транзакций
+const (
+	ReconcileModeReport = "report" // только отчет о расхождениях
+	ReconcileModeFix    = "fix"    // баланс актива приводится к сумме транзакций
+	ReconcileModeAdjust = "adjust" // расхождение записывается корректирующей переоценкой
+)
+
+// IsReconcileModeSupported проверяет режим сверки
+func IsReconcileModeSupported(mode string) bool {
+	switch mode {
+	case ReconcileModeReport, ReconcileModeFix, ReconcileModeAdjust:
+		return true
+	}
+	return false
+}
+
+// BalanceDiscrepancy расхождение сохраненного баланса актива с журналом транзакций
+type BalanceDiscrepancy struct {
+	AssetID       string  `json:"asset_id"`
+	UserID        string  `json:"user_id"`
+	Name          string  `json:"name"`
+	Currency      string  `json:"currency"`
+	StoredBalance float64 `json:"stored_balance"`
+	LedgerBalance float64 `json:"ledger_balance"` // сумма эффектов всех транзакций
+	Difference    float64 `json:"difference"`     // stored_balance - ledger_balance
+	Action        string  `json:"action,omitempty"`
+	AdjustmentID  string  `json:"adjustment_id,omitempty"` // корректирующая переоценка (режим adjust)
+}
+
+// ReconciliationReport итог сверки балансов
+type ReconciliationReport struct {
+	Mode          string               `json:"mode"`
+	Checked       int                  `json:"checked"`
+	Discrepancies []BalanceDiscrepancy `json:"discrepancies"`
+}
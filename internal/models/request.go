@@ -4,9 +4,12 @@ import "time"
 
 // CreateAssetRequest используется для данных при создании актива
 type CreateAssetRequest struct {
-	Name     string `json:"name" binding:"required"`
-	Type     string `json:"type" binding:"required"`
-	Currency string `json:"currency" binding:"required,len=3"`
+	Name         string  `json:"name" binding:"required"`
+	Type         string  `json:"type" binding:"required_without=InstrumentID"` // Класс актива (см. GetAssetClasses); по умолчанию — класс инструмента
+	Currency     string  `json:"currency" binding:"required,len=3"`
+	LotMethod    *string `json:"lot_method" binding:"omitempty,oneof=fifo lifo hifo"` // Метод подбора лотов для актива
+	Symbol       *string `json:"symbol" binding:"omitempty,max=20"`                   // Инструмент для оценки по рыночной цене
+	InstrumentID *string `json:"instrument_id"`                                       // Инструмент из справочника; по умолчанию задает и symbol
 }
 
 // UpdateAssetRequest используется для данных при обновлении актива
@@ -15,15 +18,61 @@ type UpdateAssetRequest struct {
 	Type     *string  `json:"type"`     // Если поле не передано, значит его не нужно обновлять
 	Balance  *float64 `json:"balance"`  // Также указатель для учета изменения баланса
 	Currency *string  `json:"currency"` // Валюта актива
+
+	// Метод подбора лотов: fifo, lifo, hifo; пустая строка сбрасывает к методу пользователя
+	LotMethod *string `json:"lot_method" binding:"omitempty,oneof='' fifo lifo hifo"`
+
+	// Инструмент для оценки по рыночной цене; пустая строка отвязывает актив
+	Symbol *string `json:"symbol" binding:"omitempty,max=20"`
+
+	// Инструмент из справочника; пустая строка отвязывает актив
+	InstrumentID *string `json:"instrument_id"`
 }
 
 // CreateTransactionRequest используется для данных при создании транзакции
 type CreateTransactionRequest struct {
 	Amount      float64    `json:"amount"`
 	Currency    string     `json:"currency" binding:"required,len=3"`
-	Type        string     `json:"type" binding:"required,oneof=deposit withdrawal buy sell revaluation dividend"` // Тип операции
+	Type        string     `json:"type" binding:"required,oneof=deposit withdrawal buy sell revaluation dividend fee"` // Тип операции
 	Description string     `json:"description"`
 	Timestamp   *time.Time `json:"timestamp,omitempty"` // Опциональное поле для указания времени транзакции
+
+	// Позиционный учет для buy/sell
+	Ticker       string   `json:"ticker" binding:"omitempty,max=20"`
+	InstrumentID *string  `json:"instrument_id"`                     // инструмент из справочника; по умолчанию задает ticker
+	Quantity     *float64 `json:"quantity" binding:"omitempty,gt=0"` // Количество бумаг
+	Price        *float64 `json:"price" binding:"omitempty,gte=0"`   // Цена за единицу
+
+	// Подбор лотов для продажи: метод (переопределяет метод актива) или явный список лотов
+	LotMethod string         `json:"lot_method" binding:"omitempty,oneof=fifo lifo hifo specific"`
+	Lots      []LotSelection `json:"lots" binding:"omitempty,dive"`
+
+	// Курс currency -> валюта актива; если не указан, берется курс на дату транзакции
+	ExchangeRate *float64 `json:"exchange_rate" binding:"omitempty,gt=0"`
+
+	// Доход (только для dividend): вид, начисление до налога, налог у источника, даты.
+	// Если указан gross_amount, amount можно не указывать: он равен gross_amount - withholding_tax.
+	IncomeKind     string     `json:"income_kind" binding:"omitempty,oneof=dividend coupon"`
+	GrossAmount    *float64   `json:"gross_amount" binding:"omitempty,gt=0"`
+	WithholdingTax *float64   `json:"withholding_tax" binding:"omitempty,gte=0"`
+	ExDate         *time.Time `json:"ex_date"`
+	PayDate        *time.Time `json:"pay_date"` // по умолчанию задает timestamp
+
+	// Комиссия по сделке (только для buy/sell); валюта по умолчанию — валюта транзакции
+	Fee         *float64 `json:"fee" binding:"omitempty,gte=0"`
+	FeeCurrency string   `json:"fee_currency" binding:"omitempty,len=3"`
+}
+
+// UpdateTransactionRequest используется для частичного изменения транзакции.
+// Сумма и валюта указываются как при создании — в валюте ввода, до конвертации.
+type UpdateTransactionRequest struct {
+	Amount       *float64   `json:"amount"`
+	Currency     *string    `json:"currency" binding:"omitempty,len=3"`
+	Type         *string    `json:"type" binding:"omitempty,oneof=deposit withdrawal buy sell revaluation dividend fee"`
+	Description  *string    `json:"description"`
+	Timestamp    *time.Time `json:"timestamp"`
+	AssetID      *string    `json:"asset_id"`                               // перенести в другой актив пользователя
+	ExchangeRate *float64   `json:"exchange_rate" binding:"omitempty,gt=0"` // курс валюты ввода -> валюта актива
 }
 
 // LoginRequest Модель запроса на логин
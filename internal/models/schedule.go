@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// Schedule расписание регулярной транзакции
+type Schedule struct {
+	ID             string     `db:"id" json:"id"`
+	UserID         string     `db:"user_id" json:"user_id"`
+	AssetID        string     `db:"asset_id" json:"asset_id"`
+	Amount         float64    `db:"amount" json:"amount"`
+	Currency       string     `db:"currency" json:"currency"`
+	Type           string     `db:"type" json:"type"`
+	Description    string     `db:"description" json:"description"`
+	Frequency      string     `db:"frequency" json:"frequency"` // FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=15
+	StartDate      time.Time  `db:"start_date" json:"start_date"`
+	EndDate        *time.Time `db:"end_date" json:"end_date,omitempty"`
+	Timezone       string     `db:"timezone" json:"timezone"` // часовой пояс IANA, в котором считаются наступления
+	Active         bool       `db:"active" json:"active"`
+	LastOccurrence *time.Time `db:"last_occurrence" json:"last_occurrence,omitempty"`
+	ResumedAt      *time.Time `db:"resumed_at" json:"resumed_at,omitempty"` // наступления до возобновления не создаются
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+
+	// Следующее наступление (не хранится в БД, только для ответа)
+	NextOccurrence *time.Time `db:"-" json:"next_occurrence,omitempty"`
+}
+
+// CreateScheduleRequest используется для данных при создании расписания
+type CreateScheduleRequest struct {
+	AssetID     string     `json:"asset_id" binding:"required"`
+	Amount      float64    `json:"amount" binding:"required,gt=0"`
+	Currency    string     `json:"currency" binding:"required,len=3"`
+	Type        string     `json:"type" binding:"required,oneof=deposit withdrawal dividend fee"`
+	Description string     `json:"description"`
+	Frequency   string     `json:"frequency" binding:"required,max=100"`
+	StartDate   time.Time  `json:"start_date" binding:"required"`
+	EndDate     *time.Time `json:"end_date"`
+	Timezone    string     `json:"timezone" binding:"omitempty,max=64"` // по умолчанию — часовой пояс пользователя
+}
+
+// UpdateScheduleRequest используется для частичного изменения расписания
+type UpdateScheduleRequest struct {
+	Amount      *float64   `json:"amount" binding:"omitempty,gt=0"`
+	Currency    *string    `json:"currency" binding:"omitempty,len=3"`
+	Type        *string    `json:"type" binding:"omitempty,oneof=deposit withdrawal dividend fee"`
+	Description *string    `json:"description"`
+	Frequency   *string    `json:"frequency" binding:"omitempty,max=100"`
+	EndDate     *time.Time `json:"end_date"`
+	ClearEnd    bool       `json:"clear_end_date"` // снять дату окончания
+	Timezone    *string    `json:"timezone" binding:"omitempty,max=64"`
+	Active      *bool      `json:"active"`
+}
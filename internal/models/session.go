@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Session сессия пользователя, открытая входом и продлеваемая refresh-токеном
+type Session struct {
+	ID                string     `db:"id" json:"id"`
+	UserID            string     `db:"user_id" json:"user_id"`
+	RefreshTokenHash  string     `db:"refresh_token_hash" json:"-"`
+	PreviousTokenHash *string    `db:"previous_token_hash" json:"-"`
+	UserAgent         string     `db:"user_agent" json:"user_agent"`
+	IP                string     `db:"ip" json:"ip"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt        time.Time  `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt         time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt         *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+
+	// Сессия, которой принадлежит токен запроса (не хранится в БД, только для ответа)
+	Current bool `db:"-" json:"current"`
+}
+
+// RefreshRequest запрос на обновление пары токенов
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
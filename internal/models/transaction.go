@@ -10,7 +10,38 @@ type Transaction struct {
 	AssetID     string    `db:"asset_id" json:"asset_id"`
 	Amount      float64   `db:"amount" json:"amount"`
 	Currency    string    `db:"currency" json:"currency"`
-	Type        string    `db:"type" json:"type"` // 'deposit', 'withdrawal', 'buy', 'sell', 'revaluation', 'dividend'
+	Type        string    `db:"type" json:"type"` // 'deposit', 'withdrawal', 'buy', 'sell', 'revaluation', 'dividend', 'fee'
 	Description string    `db:"description" json:"description"`
 	Timestamp   time.Time `db:"timestamp" json:"timestamp"`
+
+	// Позиционный учет (заполняется для buy/sell)
+	Ticker   string  `db:"ticker" json:"ticker,omitempty"`
+	Quantity float64 `db:"quantity" json:"quantity,omitempty"`
+	Price    float64 `db:"price" json:"price,omitempty"`
+
+	// Конвертация: заполняется, если транзакция введена в валюте, отличной от валюты актива.
+	// Amount и Currency при этом уже в валюте актива.
+	OriginalAmount   *float64 `db:"original_amount" json:"original_amount,omitempty"`
+	OriginalCurrency *string  `db:"original_currency" json:"original_currency,omitempty"`
+	ExchangeRate     *float64 `db:"exchange_rate" json:"exchange_rate,omitempty"`
+
+	// Перевод между активами, частью которого является транзакция
+	TransferID *string `db:"transfer_id" json:"transfer_id,omitempty"`
+
+	// Инструмент из справочника
+	InstrumentID *string `db:"instrument_id" json:"instrument_id,omitempty"`
+
+	// Доход (dividend): начисление до налога и удержанный налог в валюте актива,
+	// Amount при этом — сумма к получению (GrossAmount - WithholdingTax)
+	IncomeKind     string     `db:"income_kind" json:"income_kind,omitempty"` // 'dividend', 'coupon'
+	GrossAmount    *float64   `db:"gross_amount" json:"gross_amount,omitempty"`
+	WithholdingTax *float64   `db:"withholding_tax" json:"withholding_tax,omitempty"`
+	ExDate         *time.Time `db:"ex_date" json:"ex_date,omitempty"`
+	PayDate        *time.Time `db:"pay_date" json:"pay_date,omitempty"`
+
+	// Комиссия по сделке (buy/sell) или ноге перевода в валюте актива. Списывается с баланса
+	// сверх Amount, увеличивает стоимость покупки и уменьшает выручку продажи.
+	Fee                 float64  `db:"fee" json:"fee,omitempty"`
+	OriginalFee         *float64 `db:"original_fee" json:"original_fee,omitempty"`
+	OriginalFeeCurrency *string  `db:"original_fee_currency" json:"original_fee_currency,omitempty"`
 }
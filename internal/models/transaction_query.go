@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Размер страницы транзакций
+const (
+	DefaultTransactionPageSize = 100
+	MaxTransactionPageSize     = 500
+)
+
+// Поля сортировки транзакций
+const (
+	TransactionSortTimestamp = "timestamp"
+	TransactionSortAmount    = "amount"
+)
+
+// ErrInvalidCursor курсор поврежден или получен для другой сортировки
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListTransactionsRequest параметры запроса списка транзакций
+type ListTransactionsRequest struct {
+	Types       []string `form:"type" binding:"omitempty,dive,oneof=deposit withdrawal buy sell revaluation dividend fee"`
+	Currencies  []string `form:"currency" binding:"omitempty,dive,len=3"`
+	From        string   `form:"from"` // YYYY-MM-DD или RFC3339, включительно
+	To          string   `form:"to"`   // YYYY-MM-DD (весь день) или RFC3339, включительно
+	MinAmount   *float64 `form:"min_amount"`
+	MaxAmount   *float64 `form:"max_amount"`
+	Description string   `form:"description" binding:"omitempty,max=200"` // подстрока без учета регистра
+	Sort        string   `form:"sort" binding:"omitempty,oneof=timestamp -timestamp amount -amount"`
+	Limit       int      `form:"limit" binding:"omitempty,min=1,max=500"`
+	Cursor      string   `form:"cursor"`
+}
+
+// TransactionFilter условия выборки транзакций для хранилища
+type TransactionFilter struct {
+	UserID      string
+	AssetID     string // пусто — все активы пользователя
+	Types       []string
+	Currencies  []string
+	From        *time.Time
+	To          *time.Time
+	MinAmount   *float64
+	MaxAmount   *float64
+	Description string
+	SortField   string // TransactionSortTimestamp или TransactionSortAmount
+	Descending  bool
+	Limit       int                // 0 — без ограничения
+	After       *TransactionCursor // продолжить после этой записи
+}
+
+// TransactionCursor позиция последней выданной записи: значение ключа сортировки и id
+type TransactionCursor struct {
+	Sort      string     `json:"s"`
+	Timestamp *time.Time `json:"t,omitempty"`
+	Amount    *float64   `json:"a,omitempty"`
+	ID        string     `json:"id"`
+}
+
+// NewTransactionCursor курсор после транзакции tx при сортировке sort
+func NewTransactionCursor(sort string, tx Transaction) TransactionCursor {
+	cursor := TransactionCursor{Sort: sort, ID: tx.ID}
+	if sort == TransactionSortAmount {
+		amount := tx.Amount
+		cursor.Amount = &amount
+	} else {
+		timestamp := tx.Timestamp
+		cursor.Timestamp = &timestamp
+	}
+	return cursor
+}
+
+// Encode кодирует курсор в непрозрачную строку
+func (c TransactionCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTransactionCursor разбирает курсор и проверяет, что он получен для сортировки sort
+func DecodeTransactionCursor(value, sort string) (*TransactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor TransactionCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" || cursor.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	if (sort == TransactionSortAmount && cursor.Amount == nil) || (sort != TransactionSortAmount && cursor.Timestamp == nil) {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// TransactionPage страница списка транзакций
+type TransactionPage struct {
+	Items      []Transaction `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"` // пусто — это последняя страница
+}
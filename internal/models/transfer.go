@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Transfer перевод между двумя активами пользователя.
+// Состоит из вывода с актива-источника и ввода на актив-получатель.
+type Transfer struct {
+	ID             string    `db:"id" json:"id"`
+	UserID         string    `db:"user_id" json:"user_id"`
+	Amount         float64   `db:"amount" json:"amount"`               // в валюте источника, без комиссии
+	Currency       string    `db:"currency" json:"currency"`           // валюта источника
+	TargetAmount   float64   `db:"target_amount" json:"target_amount"` // в валюте получателя
+	TargetCurrency string    `db:"target_currency" json:"target_currency"`
+	ExchangeRate   float64   `db:"exchange_rate" json:"exchange_rate"` // currency -> target_currency
+	Fee            float64   `db:"fee" json:"fee"`                     // в валюте источника
+	Description    string    `db:"description" json:"description"`
+	Timestamp      time.Time `db:"timestamp" json:"timestamp"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+
+	// Ноги перевода (только для ответа)
+	Transactions []Transaction `db:"-" json:"transactions,omitempty"`
+}
+
+// CreateTransferRequest используется для данных при создании перевода
+type CreateTransferRequest struct {
+	SourceAssetID string     `json:"source_asset_id" binding:"required"`
+	TargetAssetID string     `json:"target_asset_id" binding:"required,nefield=SourceAssetID"`
+	Amount        float64    `json:"amount" binding:"required,gt=0"`         // в валюте источника
+	ExchangeRate  *float64   `json:"exchange_rate" binding:"omitempty,gt=0"` // если не указан — курс на дату
+	Fee           *float64   `json:"fee" binding:"omitempty,gte=0"`          // в fee_currency
+	FeeCurrency   string     `json:"fee_currency" binding:"omitempty,len=3"` // по умолчанию — валюта источника
+	Description   string     `json:"description"`
+	Timestamp     *time.Time `json:"timestamp,omitempty"`
+}
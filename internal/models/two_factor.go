@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// TwoFactor состояние TOTP пользователя
+type TwoFactor struct {
+	UserID    string     `db:"id"`
+	Email     string     `db:"email"`
+	Secret    *string    `db:"totp_secret"`
+	EnabledAt *time.Time `db:"totp_enabled_at"`
+	LastStep  int64      `db:"totp_last_step"`
+	// LockedUntil до этого времени коды не принимаются после серии неверных
+	LockedUntil *time.Time `db:"totp_locked_until"`
+}
+
+// TwoFactorChallenge незавершенный вход: пароль проверен, ожидается код 2FA
+type TwoFactorChallenge struct {
+	ID        string    `db:"id"`
+	UserID    string    `db:"user_id"`
+	TokenHash string    `db:"token_hash"`
+	Attempts  int       `db:"attempts"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// TwoFactorStatus состояние 2FA для ответа
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	Pending           bool       `json:"pending"` // подключение начато, но не подтверждено кодом
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollment секрет для приложения-аутентификатора
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"` // содержимое QR-кода
+}
+
+// RecoveryCodesResponse новые коды восстановления; показываются только один раз
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorChallengeResponse ответ на вход с паролем, если включена 2FA
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginRequest второй шаг входа: код TOTP или код восстановления
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest подтверждение подключения 2FA первым кодом
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorReauthRequest повторная аутентификация для изменения 2FA:
+// пароль и код TOTP или код восстановления
+type TwoFactorReauthRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
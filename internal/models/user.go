@@ -9,10 +9,17 @@ import (
 
 // User Основная модель: для чтения данных из базы
 type User struct {
-	ID           string    `db:"id" json:"id"`
-	Email        string    `db:"email" json:"email"`
-	BaseCurrency string    `db:"base_currency" json:"base_currency"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	ID            string    `db:"id" json:"id"`
+	Email         string    `db:"email" json:"email"`
+	BaseCurrency  string    `db:"base_currency" json:"base_currency"`
+	LotMethod     string    `db:"lot_method" json:"lot_method"`
+	DisplayName   string    `db:"display_name" json:"display_name"`
+	Locale        string    `db:"locale" json:"locale"`
+	Timezone      string    `db:"timezone" json:"timezone"`
+	IsAdmin       bool      `db:"is_admin" json:"is_admin"`
+	TwoFactor     bool      `db:"two_factor_enabled" json:"two_factor_enabled"`
+	EmailVerified bool      `db:"email_verified" json:"email_verified"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
 }
 
 // UserWithPassword Модель с паролем — используется только внутри приложения
@@ -20,8 +27,11 @@ type UserWithPassword struct {
 	ID           string    `db:"id"`
 	Email        string    `db:"email"`
 	BaseCurrency string    `db:"base_currency"`
+	LotMethod    string    `db:"lot_method"`
 	PasswordHash string    `db:"password_hash"`
 	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+
+	PasswordChangedAt *time.Time `db:"password_changed_at"`
 }
 
 // RegisterRequest Модель запроса на регистрацию
@@ -29,11 +39,54 @@ type RegisterRequest struct {
 	Email        string `json:"email" binding:"required,email"`
 	Password     string `json:"password" binding:"required,min=6"`
 	BaseCurrency string `json:"base_currency" binding:"required,len=3"`
+	LotMethod    string `json:"lot_method" binding:"omitempty,oneof=fifo lifo hifo"`
+}
+
+// ForgotPasswordRequest запрос ссылки на сброс пароля
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest новый пароль по токену из письма
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// VerifyEmailRequest подтверждение email токеном из письма
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ChangePasswordRequest смена пароля вошедшим пользователем
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// UpdateProfileRequest изменение профиля; не переданные поля не меняются
+type UpdateProfileRequest struct {
+	Email           *string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string  `json:"current_password"` // обязателен при смене email
+	BaseCurrency    *string `json:"base_currency" binding:"omitempty,len=3"`
+	LotMethod       *string `json:"lot_method" binding:"omitempty,oneof=fifo lifo hifo"` // метод подбора лотов по умолчанию
+	DisplayName     *string `json:"display_name" binding:"omitempty,max=100"`
+	Locale          *string `json:"locale" binding:"omitempty,max=35"`
+	Timezone        *string `json:"timezone" binding:"omitempty,max=64"`
+}
+
+// DeleteAccountRequest удаление учетной записи
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"` // код 2FA или код восстановления, если 2FA включена
 }
 
-// LoginResponse Ответ при логине (JWT)
+// LoginResponse Ответ при логине: короткоживущий access-токен (JWT) и refresh-токен сессии
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
 // Хэширование пароля перед сохранением в базу данных
@@ -2,7 +2,6 @@ package routes
 
 import (
 	"brok/internal/handler"
-	"brok/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,42 +12,135 @@ func RegisterRoutes(
 	authHandler *handler.AuthHandler,
 	assetHandler *handler.AssetHandler,
 	transactionHandler *handler.TransactionHandler,
+	transferHandler *handler.TransferHandler,
 	exchangeRateHandler *handler.ExchangeRateHandler,
+	portfolioHandler *handler.PortfolioHandler,
+	archiveHandler *handler.ArchiveHandler,
+	reconciliationHandler *handler.ReconciliationHandler,
+	scheduleHandler *handler.ScheduleHandler,
+	priceHandler *handler.PriceHandler,
+	instrumentHandler *handler.InstrumentHandler,
+	twoFactorHandler *handler.TwoFactorHandler,
+	accountHandler *handler.AccountHandler,
+	jwtAuth gin.HandlerFunc,
+	adminAuth gin.HandlerFunc,
 ) {
 	// Healthcheck
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Открытые ключи проверки JWT
+	router.GET("/.well-known/jwks.json", authHandler.GetJWKS)
+
 	// Auth
 	auth := router.Group("/auth")
 	{
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
+		auth.POST("/login/2fa", twoFactorHandler.LoginTwoFactor)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/logout", jwtAuth, authHandler.Logout)
+		auth.POST("/logout-all", jwtAuth, authHandler.LogoutAll)
+		auth.POST("/forgot-password", accountHandler.ForgotPassword)
+		auth.POST("/reset-password", accountHandler.ResetPassword)
+		auth.POST("/verify-email", accountHandler.VerifyEmail)
 	}
 
 	// Защищённые маршруты
 	api := router.Group("/api")
-	api.Use(middleware.JWTAuth())
+	api.Use(jwtAuth)
 	{
 		api.GET("/me", authHandler.GetCurrentUser)
+		api.PATCH("/me", accountHandler.UpdateProfile)
+		api.DELETE("/me", accountHandler.DeleteAccount)
+		api.PATCH("/me/password", accountHandler.ChangePassword)
+		api.POST("/me/verify-email", accountHandler.ResendVerification)
+
+		// Two-factor authentication
+		api.GET("/me/2fa", twoFactorHandler.GetStatus)
+		api.POST("/me/2fa/enroll", twoFactorHandler.Enroll)
+		api.POST("/me/2fa/confirm", twoFactorHandler.Confirm)
+		api.POST("/me/2fa/disable", twoFactorHandler.Disable)
+		api.POST("/me/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+
+		// Sessions
+		api.GET("/sessions", authHandler.GetSessions)
+		api.DELETE("/sessions/:id", authHandler.RevokeSession)
 
 		// Assets
 		api.GET("/assets", assetHandler.GetAssets)
 		api.POST("/assets", assetHandler.CreateAsset)
 		api.PATCH("/assets/:id", assetHandler.UpdateAsset)
 		api.DELETE("/assets/:id", assetHandler.DeleteAsset)
+		api.GET("/assets/:id/lots", assetHandler.GetAssetLots)
+		api.GET("/assets/:id/realized", assetHandler.GetRealizedGains)
 
 		// Transactions
+		api.GET("/transactions", transactionHandler.ListTransactions)
 		api.GET("/assets/:id/transactions", transactionHandler.GetTransactionsByAsset)
 		api.POST("/assets/:id/transactions", transactionHandler.CreateTransaction)
+		api.PATCH("/transactions/:id", transactionHandler.UpdateTransaction)
 		api.DELETE("/transactions/:id", transactionHandler.DeleteTransaction)
 
+		// Statement import
+		api.POST("/assets/:id/import", transactionHandler.ImportTransactions)
+		api.GET("/import-profiles", transactionHandler.GetImportProfiles)
+		api.POST("/import-profiles", transactionHandler.CreateImportProfile)
+		api.DELETE("/import-profiles/:id", transactionHandler.DeleteImportProfile)
+
+		// Transfers
+		api.POST("/transfers", transferHandler.CreateTransfer)
+
+		// Schedules
+		api.GET("/schedules", scheduleHandler.GetSchedules)
+		api.POST("/schedules", scheduleHandler.CreateSchedule)
+		api.GET("/schedules/:id", scheduleHandler.GetSchedule)
+		api.PATCH("/schedules/:id", scheduleHandler.UpdateSchedule)
+		api.DELETE("/schedules/:id", scheduleHandler.DeleteSchedule)
+
 		// Exchange Rates
 		api.GET("/currencies", exchangeRateHandler.GetSupportedCurrencies)
 		api.GET("/exchange-rates", exchangeRateHandler.GetExchangeRate)
 		api.POST("/exchange-rates/update", exchangeRateHandler.UpdateExchangeRates)
 		api.POST("/exchange-rates/update-if-needed", exchangeRateHandler.UpdateExchangeRatesIfNeeded)
 		api.GET("/convert", exchangeRateHandler.ConvertAmount)
+
+		// Prices
+		api.GET("/prices/:symbol", priceHandler.GetPrices)
+		api.POST("/assets/:id/mark-to-market", priceHandler.MarkToMarket)
+
+		// Instruments
+		api.GET("/asset-classes", instrumentHandler.GetAssetClasses)
+		api.GET("/instruments", instrumentHandler.GetInstruments)
+		api.GET("/instruments/:id", instrumentHandler.GetInstrument)
+
+		// Portfolio
+		api.GET("/portfolio/summary", portfolioHandler.GetSummary)
+		api.GET("/portfolio/history", portfolioHandler.GetHistory)
+		api.GET("/portfolio/income", portfolioHandler.GetIncome)
+		api.GET("/portfolio/fees", portfolioHandler.GetFees)
+		api.GET("/performance", portfolioHandler.GetPerformance)
+
+		// Reconciliation
+		api.GET("/reconciliation", reconciliationHandler.GetReconciliation)
+		api.POST("/reconciliation", reconciliationHandler.Reconcile)
+
+		// Export / import
+		api.GET("/export", archiveHandler.Export)
+		api.POST("/import", archiveHandler.Import)
+	}
+
+	// Административные маршруты
+	admin := router.Group("/api/admin")
+	admin.Use(jwtAuth, adminAuth)
+	{
+		admin.POST("/exchange-rates/backfill", exchangeRateHandler.BackfillExchangeRates)
+		admin.POST("/reconciliation", reconciliationHandler.ReconcileAll)
+		admin.POST("/prices/update", priceHandler.UpdatePrices)
+		admin.POST("/instruments", instrumentHandler.CreateInstrument)
+		admin.PATCH("/instruments/:id", instrumentHandler.UpdateInstrument)
+		admin.DELETE("/instruments/:id", instrumentHandler.DeleteInstrument)
+		admin.POST("/instruments/import", instrumentHandler.ImportInstruments)
 	}
 }
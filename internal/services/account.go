@@ -0,0 +1,401 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"brok/config"
+	"brok/internal/models"
+	"brok/internal/storage"
+	"brok/internal/utils"
+)
+
+const (
+	// verifyEmailTTL срок действия ссылки подтверждения email
+	verifyEmailTTL = 48 * time.Hour
+	// resetPasswordTTL срок действия ссылки сброса пароля
+	resetPasswordTTL = time.Hour
+	// mailTimeout время на отправку письма в фоне
+	mailTimeout = time.Minute
+)
+
+var (
+	// ErrInvalidActionToken токен из письма неверен, истек, уже использован или устарел
+	ErrInvalidActionToken = errors.New("invalid or expired token")
+	// ErrEmailVerified email уже подтвержден
+	ErrEmailVerified = errors.New("email is already verified")
+	// ErrInvalidProfile неверные данные профиля
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrEmailTaken email занят другим пользователем
+	ErrEmailTaken = errors.New("user with this email already exists")
+	// ErrLastAdmin нельзя удалить единственного администратора
+	ErrLastAdmin = errors.New("cannot delete the only admin account")
+)
+
+// AccountService сервис учетной записи: профиль, подтверждение email, пароль и удаление
+type AccountService struct {
+	storage   storage.Storage
+	mailer    Mailer
+	twoFactor *TwoFactorService
+	appURL    string // адрес приложения для ссылок в письмах
+}
+
+// NewAccountService создает новый сервис учетной записи; адрес для ссылок задается APP_URL
+func NewAccountService(storage storage.Storage, mailer Mailer, twoFactor *TwoFactorService) *AccountService {
+	return &AccountService{
+		storage:   storage,
+		mailer:    mailer,
+		twoFactor: twoFactor,
+		appURL:    strings.TrimRight(config.GetEnv("APP_URL", "http://localhost:8080"), "/"),
+	}
+}
+
+// SendVerification отправляет письмо со ссылкой подтверждения текущего email пользователя
+func (s *AccountService) SendVerification(ctx context.Context, userID string) error {
+	user, err := s.storage.UserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.EmailVerified {
+		return ErrEmailVerified
+	}
+
+	token, _, err := utils.GenerateActionToken(user.ID, user.Email, utils.TokenPurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	s.send(Email{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: "Please confirm your email address by opening the link below:\n\n" +
+			s.link("/verify-email", token) + "\n\n" +
+			"The link is valid for 48 hours. If you did not create an account, ignore this email.\n",
+	})
+	return nil
+}
+
+// VerifyEmail подтверждает email по токену из письма. Токен действует только для адреса,
+// на который было отправлено письмо.
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := s.useToken(ctx, token, utils.TokenPurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	verified, err := s.storage.VerifyUserEmail(ctx, claims.Subject, claims.Email)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if !verified {
+		// Адрес сменился после отправки письма
+		return ErrInvalidActionToken
+	}
+	return nil
+}
+
+// ForgotPassword отправляет ссылку сброса пароля, если такой пользователь есть.
+// Результат не зависит от существования адреса, чтобы по ответу нельзя было проверить email.
+func (s *AccountService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.storage.UserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, _, err := utils.GenerateActionToken(user.ID, user.Email, utils.TokenPurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	s.send(Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Someone requested a password reset for your account. To choose a new password, open the link below:\n\n" +
+			s.link("/reset-password", token) + "\n\n" +
+			"The link is valid for 1 hour and can be used once. If you did not request a reset, ignore this email.\n",
+	})
+	return nil
+}
+
+// ResetPassword задает новый пароль по токену из письма и завершает все сессии пользователя.
+// Токен перестает действовать после использования или смены пароля.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	claims, err := utils.ParseActionToken(token, utils.TokenPurposeResetPassword)
+	if err != nil {
+		return ErrInvalidActionToken
+	}
+
+	user, err := s.storage.UserWithPasswordByID(ctx, claims.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidActionToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Email != claims.Email {
+		return ErrInvalidActionToken
+	}
+	if user.PasswordChangedAt != nil && claims.IssuedAt != nil &&
+		claims.IssuedAt.Time.Before(user.PasswordChangedAt.Truncate(time.Second)) {
+		return ErrInvalidActionToken
+	}
+
+	hash, err := models.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	// Токен погашается вместе со сменой пароля и отзывом сессий: при ошибке
+	// ссылка остается действительной, а пароль и сессии не меняются
+	err = s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		used, err := s.storage.UseOneTimeTokenTx(ctx, tx, claims.ID, claims.ExpiresAt.Time)
+		if err != nil {
+			return fmt.Errorf("failed to use token: %w", err)
+		}
+		if !used {
+			return ErrInvalidActionToken
+		}
+		if err := s.storage.UserPasswordSetTx(ctx, tx, user.ID, hash); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		if _, err := s.storage.RevokeUserSessionsTx(ctx, tx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifyPasswordChanged(user.Email)
+	return nil
+}
+
+// ChangePassword меняет пароль после проверки текущего; остальные сессии пользователя завершаются
+func (s *AccountService) ChangePassword(ctx context.Context, userID, sessionID, current, password string) error {
+	user, err := s.storage.UserWithPasswordByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !models.CheckPassword(user.PasswordHash, current) {
+		return ErrInvalidPassword
+	}
+
+	if err := s.setPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	if _, err := s.storage.RevokeUserSessionsExcept(ctx, userID, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.notifyPasswordChanged(user.Email)
+	return nil
+}
+
+// UpdateProfile меняет переданные поля профиля и настройки учета. Смена email требует текущий пароль,
+// сбрасывает подтверждение и отправляет письмо подтверждения на новый адрес.
+func (s *AccountService) UpdateProfile(ctx context.Context, userID string, req models.UpdateProfileRequest) (*models.User, error) {
+	user, err := s.storage.UserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	previousEmail := user.Email
+
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if email == "" {
+			return nil, fmt.Errorf("%w: email must not be empty", ErrInvalidProfile)
+		}
+		if email != user.Email {
+			current, err := s.storage.UserWithPasswordByID(ctx, userID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get user: %w", err)
+			}
+			if !models.CheckPassword(current.PasswordHash, req.CurrentPassword) {
+				return nil, ErrInvalidPassword
+			}
+			exists, err := s.storage.IsUsersMailExist(ctx, email)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check email: %w", err)
+			}
+			if exists {
+				return nil, ErrEmailTaken
+			}
+			user.Email = email
+		}
+	}
+
+	if req.BaseCurrency != nil {
+		currency := strings.ToUpper(*req.BaseCurrency)
+		if !models.IsCurrencySupported(currency) {
+			return nil, fmt.Errorf("%w: unsupported currency: %s", ErrInvalidProfile, *req.BaseCurrency)
+		}
+		user.BaseCurrency = currency
+	}
+
+	if req.LotMethod != nil {
+		user.LotMethod = *req.LotMethod
+	}
+
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+
+	if req.Locale != nil {
+		user.Locale = ""
+		if locale := strings.TrimSpace(*req.Locale); locale != "" {
+			tag, err := language.Parse(locale)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid locale: %s", ErrInvalidProfile, locale)
+			}
+			user.Locale = tag.String()
+		}
+	}
+
+	if req.Timezone != nil {
+		timezone := strings.TrimSpace(*req.Timezone)
+		if timezone == "" {
+			timezone = "UTC"
+		}
+		// "Local" зависит от настроек сервера, а не пользователя
+		if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+			return nil, fmt.Errorf("%w: invalid timezone: %s", ErrInvalidProfile, timezone)
+		}
+		user.Timezone = timezone
+	}
+
+	if err := s.storage.UserSet(ctx, user); err != nil {
+		// Адрес могли занять между проверкой и сохранением
+		if storage.IsUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if user.Email != previousEmail {
+		s.send(Email{
+			To:      previousEmail,
+			Subject: "Your email was changed",
+			Body: "The email for your account was changed to " + user.Email + ".\n\n" +
+				"If it was not you, reset your password at " + s.appURL + "/forgot-password right away.\n",
+		})
+		if err := s.SendVerification(ctx, userID); err != nil {
+			log.Printf("⚠️  Не удалось отправить письмо подтверждения: %v", err)
+		}
+	}
+
+	return s.storage.UserByID(ctx, userID)
+}
+
+// DeleteAccount удаляет учетную запись со всеми активами, транзакциями и сессиями.
+// Требует пароль, а при включенной 2FA — также код.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID, password, code string) error {
+	user, err := s.storage.UserWithPasswordByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !models.CheckPassword(user.PasswordHash, password) {
+		return ErrInvalidPassword
+	}
+
+	twoFactor, err := s.twoFactor.Enabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if twoFactor {
+		if err := s.twoFactor.VerifyCode(ctx, userID, code); err != nil {
+			return err
+		}
+	}
+
+	profile, err := s.storage.UserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if profile.IsAdmin {
+		admins, err := s.storage.AdminsCount(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count admins: %w", err)
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	if _, err := s.storage.UserDelete(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	s.send(Email{
+		To:      user.Email,
+		Subject: "Your account was deleted",
+		Body:    "Your account and all its data were deleted at your request.\n",
+	})
+	return nil
+}
+
+// useToken проверяет токен из письма и погашает его
+func (s *AccountService) useToken(ctx context.Context, token, purpose string) (*utils.ActionClaims, error) {
+	claims, err := utils.ParseActionToken(token, purpose)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	used, err := s.storage.UseOneTimeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use token: %w", err)
+	}
+	if !used {
+		return nil, ErrInvalidActionToken
+	}
+	return claims, nil
+}
+
+// setPassword хэширует и сохраняет новый пароль
+func (s *AccountService) setPassword(ctx context.Context, userID, password string) error {
+	hash, err := models.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.storage.UserPasswordSet(ctx, userID, hash); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+// notifyPasswordChanged сообщает пользователю о смене пароля
+func (s *AccountService) notifyPasswordChanged(email string) {
+	s.send(Email{
+		To:      email,
+		Subject: "Your password was changed",
+		Body: "The password for your account was just changed and other sessions were signed out.\n\n" +
+			"If it was not you, reset your password at " + s.appURL + "/forgot-password right away.\n",
+	})
+}
+
+// send отправляет письмо в фоне: ответ не ждет SMTP-сервер, а ошибки пишутся в лог
+func (s *AccountService) send(email Email) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, email); err != nil {
+			log.Printf("⚠️  Не удалось отправить письмо %q для %s: %v", email.Subject, email.To, err)
+		}
+	}()
+}
+
+// link собирает ссылку приложения с токеном
+func (s *AccountService) link(path, token string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
+}
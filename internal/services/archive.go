@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+)
+
+var (
+	// ErrIncompatibleArchive архив записан несовместимой версией схемы
+	ErrIncompatibleArchive = errors.New("incompatible archive schema version")
+	// ErrInvalidArchive архив поврежден или содержит ссылки на отсутствующие записи
+	ErrInvalidArchive = errors.New("invalid archive")
+)
+
+// archiveRateMargin насколько раньше первой операции выгружаются курсы,
+// чтобы на ее дату нашелся ближайший предыдущий курс
+const archiveRateMargin = 7 * 24 * time.Hour
+
+// ArchiveService выгрузка и восстановление всех данных пользователя
+type ArchiveService struct {
+	storage storage.Storage
+}
+
+// NewArchiveService создает новый сервис архивов
+func NewArchiveService(storage storage.Storage) *ArchiveService {
+	return &ArchiveService{storage: storage}
+}
+
+// Export собирает профиль, активы, транзакции, переводы, закрытия лотов
+// и курсы валют для встречающихся в данных пар
+func (s *ArchiveService) Export(ctx context.Context, userID string) (*models.Archive, error) {
+	user, err := s.storage.UserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	archive := &models.Archive{
+		Format:        models.ArchiveFormat,
+		SchemaVersion: models.ArchiveSchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		Profile: models.ArchiveProfile{
+			Email:        user.Email,
+			BaseCurrency: user.BaseCurrency,
+			LotMethod:    user.LotMethod,
+			CreatedAt:    user.CreatedAt,
+		},
+		Assets:        []models.Asset{},
+		Transactions:  []models.Transaction{},
+		LotMatches:    []models.LotMatch{},
+		ExchangeRates: []models.ExchangeRate{},
+	}
+
+	archive.Assets, err = s.storage.AssetsByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	// Пары валют, для которых нужны курсы: активы к базовой валюте и конвертации транзакций
+	pairs := map[string]bool{}
+	addPair := func(from, to string) {
+		if from != to {
+			pairs[from+"->"+to] = true
+			pairs[to+"->"+from] = true
+		}
+	}
+	var first time.Time
+
+	for _, asset := range archive.Assets {
+		addPair(asset.Currency, user.BaseCurrency)
+
+		transactions, err := s.storage.GetTransactionsByAssetID(ctx, asset.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transactions for asset %s: %w", asset.ID, err)
+		}
+		for _, tx := range transactions {
+			if tx.OriginalCurrency != nil {
+				addPair(*tx.OriginalCurrency, tx.Currency)
+			}
+			if first.IsZero() || tx.Timestamp.Before(first) {
+				first = tx.Timestamp
+			}
+		}
+		archive.Transactions = append(archive.Transactions, SortTransactions(transactions)...)
+
+		matches, err := s.storage.GetLotMatchesByAssetID(ctx, asset.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get lot matches for asset %s: %w", asset.ID, err)
+		}
+		archive.LotMatches = append(archive.LotMatches, matches...)
+	}
+
+	archive.Transfers, err = s.storage.GetTransfersByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+	for _, transfer := range archive.Transfers {
+		addPair(transfer.Currency, transfer.TargetCurrency)
+	}
+
+	if len(pairs) > 0 && !first.IsZero() {
+		rates, err := s.storage.GetExchangeRatesInRange(ctx, first.Add(-archiveRateMargin), time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+		}
+		for _, rate := range rates {
+			if pairs[rate.FromCurrency+"->"+rate.ToCurrency] {
+				archive.ExchangeRates = append(archive.ExchangeRates, rate)
+			}
+		}
+	}
+
+	return archive, nil
+}
+
+// Import восстанавливает архив под пользователем userID в одной транзакции:
+// все записи получают новые ID, ссылки между ними переназначаются.
+// Существующие данные пользователя не удаляются. Курсы валют из архива не импортируются:
+// таблица курсов общая для всех пользователей. Базовая валюта и метод подбора лотов
+// из профиля архива применяются, только если applySettings.
+func (s *ArchiveService) Import(ctx context.Context, userID string, archive models.Archive, applySettings bool) (*models.ArchiveImportReport, error) {
+	if archive.Format != models.ArchiveFormat {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidArchive, archive.Format)
+	}
+	if archive.SchemaVersion != models.ArchiveSchemaVersion {
+		return nil, fmt.Errorf("%w: archive has version %d, supported %d", ErrIncompatibleArchive, archive.SchemaVersion, models.ArchiveSchemaVersion)
+	}
+
+	// Новые ID для всех записей
+	assetIDs := make(map[string]string, len(archive.Assets))
+	for _, asset := range archive.Assets {
+		if !models.IsCurrencySupported(asset.Currency) {
+			return nil, fmt.Errorf("%w: asset %s has unsupported currency %s", ErrInvalidArchive, asset.ID, asset.Currency)
+		}
+		assetIDs[asset.ID] = uuid.New().String()
+	}
+	transferIDs := make(map[string]string, len(archive.Transfers))
+	for _, transfer := range archive.Transfers {
+		transferIDs[transfer.ID] = uuid.New().String()
+	}
+	transactionIDs := make(map[string]string, len(archive.Transactions))
+	for _, tx := range archive.Transactions {
+		if _, ok := assetIDs[tx.AssetID]; !ok {
+			return nil, fmt.Errorf("%w: transaction %s references unknown asset %s", ErrInvalidArchive, tx.ID, tx.AssetID)
+		}
+		if tx.TransferID != nil {
+			if _, ok := transferIDs[*tx.TransferID]; !ok {
+				return nil, fmt.Errorf("%w: transaction %s references unknown transfer %s", ErrInvalidArchive, tx.ID, *tx.TransferID)
+			}
+		}
+		transactionIDs[tx.ID] = uuid.New().String()
+	}
+	for _, match := range archive.LotMatches {
+		_, sellOK := transactionIDs[match.SellTransactionID]
+		_, buyOK := transactionIDs[match.BuyTransactionID]
+		_, assetOK := assetIDs[match.AssetID]
+		if !sellOK || !buyOK || !assetOK {
+			return nil, fmt.Errorf("%w: lot match %s references unknown records", ErrInvalidArchive, match.ID)
+		}
+	}
+
+	// Справочник инструментов общий для окружения: ссылки на отсутствующие
+	// в нем инструменты не переносятся
+	instruments := make(map[string]bool)
+	knownInstrument := func(id *string) (*string, error) {
+		if id == nil {
+			return nil, nil
+		}
+		known, ok := instruments[*id]
+		if !ok {
+			_, err := s.storage.InstrumentByID(ctx, *id)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			known = err == nil
+			instruments[*id] = known
+		}
+		if !known {
+			return nil, nil
+		}
+		return id, nil
+	}
+
+	report := &models.ArchiveImportReport{AssetIDs: assetIDs}
+
+	err := s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		// Настройки профиля — только по явному запросу; email и пароль не переносятся
+		profile := archive.Profile
+		if applySettings && models.IsCurrencySupported(profile.BaseCurrency) && models.IsLotMethodSupported(profile.LotMethod) {
+			if err := s.storage.UserSettingsSetTx(ctx, tx, userID, profile.BaseCurrency, profile.LotMethod); err != nil {
+				return err
+			}
+			report.SettingsApplied = true
+		}
+
+		for _, asset := range archive.Assets {
+			asset.ID = assetIDs[asset.ID]
+			asset.UserID = userID
+			// Типы из архивов до появления справочника классов приводятся к перечню
+			// исходный тип сохраняется в legacy_type
+			if class, ok := models.NormalizeAssetClass(asset.Type); ok {
+				asset.Type = class
+			} else if asset.Type != models.AssetClassOther {
+				legacyType := asset.Type
+				asset.LegacyType = &legacyType
+				asset.Type = models.AssetClassOther
+			}
+			instrumentID, err := knownInstrument(asset.InstrumentID)
+			if err != nil {
+				return err
+			}
+			asset.InstrumentID = instrumentID
+			if err := s.storage.CreateAssetTx(ctx, tx, asset); err != nil {
+				return err
+			}
+			report.Assets++
+		}
+
+		for _, transfer := range archive.Transfers {
+			transfer.ID = transferIDs[transfer.ID]
+			transfer.UserID = userID
+			if err := s.storage.CreateTransferTx(ctx, tx, transfer); err != nil {
+				return err
+			}
+			report.Transfers++
+		}
+
+		for _, transaction := range archive.Transactions {
+			transaction.ID = transactionIDs[transaction.ID]
+			transaction.AssetID = assetIDs[transaction.AssetID]
+			if transaction.TransferID != nil {
+				transferID := transferIDs[*transaction.TransferID]
+				transaction.TransferID = &transferID
+			}
+			instrumentID, err := knownInstrument(transaction.InstrumentID)
+			if err != nil {
+				return err
+			}
+			transaction.InstrumentID = instrumentID
+			if err := s.storage.CreateTransactionTx(ctx, tx, transaction); err != nil {
+				return err
+			}
+			report.Transactions++
+		}
+
+		for _, match := range archive.LotMatches {
+			match.ID = uuid.New().String()
+			match.AssetID = assetIDs[match.AssetID]
+			match.SellTransactionID = transactionIDs[match.SellTransactionID]
+			match.BuyTransactionID = transactionIDs[match.BuyTransactionID]
+			if err := s.storage.CreateLotMatchTx(ctx, tx, match); err != nil {
+				return err
+			}
+			report.LotMatches++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import archive: %w", err)
+	}
+
+	return report, nil
+}
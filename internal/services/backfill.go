@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+)
+
+// MaxBackfillDays ограничение на длину периода загрузки истории курсов
+const MaxBackfillDays = 3660
+
+// ErrNoHistoryProvider ни один из настроенных источников не отдает историю курсов
+var ErrNoHistoryProvider = errors.New("no configured exchange rate provider supports historical rates")
+
+// pairKey ключ курса валютной пары на дату
+type pairKey struct {
+	from, to string
+	date     time.Time
+}
+
+// backfillCurrencies проверяет список валют; пустой список — все поддерживаемые
+func backfillCurrencies(currencies []string) ([]string, error) {
+	if len(currencies) == 0 {
+		for _, currency := range models.GetSupportedCurrencies() {
+			currencies = append(currencies, currency.Code)
+		}
+	}
+	for _, currency := range currencies {
+		if !models.IsCurrencySupported(currency) {
+			return nil, fmt.Errorf("%w: unsupported currency %s", ErrInvalidPeriod, currency)
+		}
+	}
+	if len(currencies) < 2 {
+		return nil, fmt.Errorf("%w: at least two currencies are required", ErrInvalidPeriod)
+	}
+
+	sorted := append([]string(nil), currencies...)
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// validateBackfillRange проверяет период загрузки
+func validateBackfillRange(from, to time.Time) error {
+	if to.Before(from) {
+		return fmt.Errorf("%w: from must not be after to", ErrInvalidPeriod)
+	}
+	if to.Sub(from) > MaxBackfillDays*24*time.Hour {
+		return fmt.Errorf("%w: range is longer than %d days", ErrInvalidPeriod, MaxBackfillDays)
+	}
+	return nil
+}
+
+// BackfillRates загружает курсы за период из источника истории
+func (s *ExchangeRateService) BackfillRates(ctx context.Context, from, to time.Time, currencies []string) (*models.BackfillReport, error) {
+	if s.history == nil {
+		return nil, ErrNoHistoryProvider
+	}
+	if err := validateBackfillRange(from, to); err != nil {
+		return nil, err
+	}
+
+	tables, err := s.history.FetchHistory(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical rates: %w", err)
+	}
+
+	return s.ImportRateTables(ctx, from, to, currencies, tables, s.history.Name())
+}
+
+// ImportRateTables сохраняет курсы из таблиц за период для всех пар из currencies
+// в одной транзакции, пропуская даты, на которые курс уже есть, и сообщает о рабочих днях без курсов
+func (s *ExchangeRateService) ImportRateTables(ctx context.Context, from, to time.Time, currencies []string, tables []*RateSet, source string) (*models.BackfillReport, error) {
+	if err := validateBackfillRange(from, to); err != nil {
+		return nil, err
+	}
+	currencies, err := backfillCurrencies(currencies)
+	if err != nil {
+		return nil, err
+	}
+
+	existingRates, err := s.storage.GetExchangeRatesInRange(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing rates: %w", err)
+	}
+	existing := map[pairKey]bool{}
+	for _, rate := range existingRates {
+		existing[pairKey{from: rate.FromCurrency, to: rate.ToCurrency, date: dayOf(rate.Timestamp)}] = true
+	}
+
+	report := &models.BackfillReport{
+		From:       from,
+		To:         to,
+		Currencies: currencies,
+		Source:     source,
+		Gaps:       []string{},
+	}
+
+	// Дни, на которые источник опубликовал курсы
+	published := map[time.Time]bool{}
+	err = s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		for _, table := range tables {
+			date := dayOf(table.Date)
+			if date.Before(from) || date.After(to) {
+				continue
+			}
+			published[date] = true
+
+			for _, base := range currencies {
+				rates, err := crossRates(table.Provider, date, table.Base, table.Rates, base)
+				if err != nil {
+					// В таблице нет базовой валюты — пропускаем
+					continue
+				}
+
+				for _, target := range currencies {
+					rate, ok := rates.Rates[target]
+					if target == base || !ok {
+						continue
+					}
+
+					key := pairKey{from: base, to: target, date: date}
+					if existing[key] {
+						report.Skipped++
+						continue
+					}
+
+					exchangeRate := models.ExchangeRate{
+						FromCurrency: base,
+						ToCurrency:   target,
+						Rate:         rate,
+						Timestamp:    date,
+						Provider:     table.Provider,
+					}
+					// Уже сохраненные курсы не перезаписываются, даже если появились после чтения existing
+					inserted, err := s.storage.SaveExchangeRateIfMissingTx(ctx, tx, exchangeRate)
+					if err != nil {
+						return fmt.Errorf("failed to save rate %s->%s on %s: %w", base, target, date.Format("2006-01-02"), err)
+					}
+					existing[key] = true
+					if inserted {
+						report.Inserted++
+					} else {
+						report.Skipped++
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Пропуски: рабочие дни, где нет курса хотя бы для одной пары. Выходные не проверяются,
+	// будни без опубликованных источником курсов считаются его праздниками.
+	for day := dayOf(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		if len(published) > 0 && !published[day] {
+			continue
+		}
+
+		complete := true
+		for _, base := range currencies {
+			for _, target := range currencies {
+				if base != target && !existing[pairKey{from: base, to: target, date: day}] {
+					complete = false
+				}
+			}
+		}
+		if !complete {
+			report.Gaps = append(report.Gaps, day.Format("2006-01-02"))
+		}
+	}
+
+	return report, nil
+}
+
+// dayOf начало дня в UTC
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"brok/internal/models"
+)
+
+// TransactionRate курс для перевода транзакции в валюту актива:
+// явно указанный пользователем или курс на дату транзакции
+func (s *ExchangeRateService) TransactionRate(ctx context.Context, fromCurrency, toCurrency string, date time.Time, explicit *float64) (float64, error) {
+	if explicit != nil {
+		return *explicit, nil
+	}
+	return s.GetExchangeRate(ctx, fromCurrency, toCurrency, date)
+}
+
+// ConvertToAsset переводит транзакцию в валюту актива по явному курсу или курсу
+// на дату транзакции (см. TransactionRate), сохраняя исходные сумму, валюту и курс
+func (s *ExchangeRateService) ConvertToAsset(ctx context.Context, tx *models.Transaction, assetCurrency string, explicit *float64) error {
+	if tx.Currency == assetCurrency {
+		return nil
+	}
+
+	rate, err := s.TransactionRate(ctx, tx.Currency, assetCurrency, tx.Timestamp, explicit)
+	if err != nil {
+		return err
+	}
+	ConvertTransaction(tx, assetCurrency, rate)
+	return nil
+}
+
+// ConvertTransaction переводит сумму, цену и суммы дохода транзакции в валюту currency по курсу rate,
+// сохраняя исходную сумму, валюту и курс
+func ConvertTransaction(tx *models.Transaction, currency string, rate float64) {
+	if tx.Currency == currency {
+		return
+	}
+
+	originalAmount := tx.Amount
+	originalCurrency := tx.Currency
+	tx.OriginalAmount = &originalAmount
+	tx.OriginalCurrency = &originalCurrency
+	tx.ExchangeRate = &rate
+
+	tx.Amount = math.Round(tx.Amount*rate*100) / 100
+	tx.Price = tx.Price * rate
+	tx.Currency = currency
+
+	// Начисление и налог дохода переводятся по тому же курсу
+	if tx.GrossAmount != nil {
+		gross := math.Round(*tx.GrossAmount*rate*100) / 100
+		tx.GrossAmount = &gross
+	}
+	if tx.WithholdingTax != nil {
+		tax := math.Round(*tx.WithholdingTax*rate*100) / 100
+		tx.WithholdingTax = &tax
+	}
+}
+
+// ApplyFee записывает комиссию по транзакции: fee в валюте feeCurrency переводится
+// в валюту актива по курсу rate, исходные сумма и валюта сохраняются
+func ApplyFee(tx *models.Transaction, fee float64, feeCurrency, assetCurrency string, rate float64) {
+	tx.Fee = fee
+	tx.OriginalFee, tx.OriginalFeeCurrency = nil, nil
+	if feeCurrency == assetCurrency {
+		return
+	}
+
+	originalFee := fee
+	originalCurrency := feeCurrency
+	tx.OriginalFee = &originalFee
+	tx.OriginalFeeCurrency = &originalCurrency
+	tx.Fee = math.Round(fee*rate*100) / 100
+}
@@ -2,12 +2,9 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"math"
-	"net/http"
 	"time"
 
 	"brok/internal/models"
@@ -16,27 +13,22 @@ import (
 
 // ExchangeRateService сервис для работы с курсами валют
 type ExchangeRateService struct {
-	storage storage.Storage
-	apiKey  string
-	apiURL  string
+	storage    storage.Storage
+	provider   RateProvider
+	history    HistoricalRateProvider // может быть nil, если история недоступна
+	resolution RateResolutionConfig
 }
 
 // NewExchangeRateService создает новый сервис курсов валют
-func NewExchangeRateService(storage storage.Storage) *ExchangeRateService {
+func NewExchangeRateService(storage storage.Storage, provider RateProvider, history HistoricalRateProvider, resolution RateResolutionConfig) *ExchangeRateService {
 	return &ExchangeRateService{
-		storage: storage,
-		apiKey:  "demo", // Используем бесплатный API для демо
-		apiURL:  "https://api.exchangerate-api.com/v4/latest/",
+		storage:    storage,
+		provider:   provider,
+		history:    history,
+		resolution: resolution,
 	}
 }
 
-// ExchangeRateAPIResponse ответ от API курсов валют
-type ExchangeRateAPIResponse struct {
-	Base  string             `json:"base"`
-	Date  string             `json:"date"`
-	Rates map[string]float64 `json:"rates"`
-}
-
 // ShouldUpdateRates проверяет, нужно ли обновлять курсы валют
 func (s *ExchangeRateService) ShouldUpdateRates(ctx context.Context, updateInterval time.Duration) (bool, error) {
 	lastUpdate, err := s.storage.GetLastExchangeRateUpdate(ctx)
@@ -95,36 +87,13 @@ func (s *ExchangeRateService) UpdateExchangeRates(ctx context.Context) error {
 
 // updateRatesForCurrency обновляет курсы для конкретной валюты
 func (s *ExchangeRateService) updateRatesForCurrency(ctx context.Context, baseCurrency string) error {
-	url := s.apiURL + baseCurrency
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to fetch rates: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API returned status: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var apiResp ExchangeRateAPIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Парсим дату
-	date, err := time.Parse("2006-01-02", apiResp.Date)
+	rates, err := s.provider.FetchRates(ctx, baseCurrency)
 	if err != nil {
-		return fmt.Errorf("failed to parse date: %w", err)
+		return err
 	}
 
 	// Сохраняем курсы в базу
-	for targetCurrency, rate := range apiResp.Rates {
+	for targetCurrency, rate := range rates.Rates {
 		// Пропускаем не поддерживаемые валюты
 		if !models.IsCurrencySupported(targetCurrency) {
 			continue
@@ -139,7 +108,8 @@ func (s *ExchangeRateService) updateRatesForCurrency(ctx context.Context, baseCu
 			FromCurrency: baseCurrency,
 			ToCurrency:   targetCurrency,
 			Rate:         rate,
-			Timestamp:    date,
+			Timestamp:    rates.Date,
+			Provider:     rates.Provider,
 		}
 
 		if err := s.storage.SaveExchangeRate(ctx, exchangeRate); err != nil {
@@ -150,27 +120,14 @@ func (s *ExchangeRateService) updateRatesForCurrency(ctx context.Context, baseCu
 	return nil
 }
 
-// GetExchangeRate получает курс валют
+// GetExchangeRate получает курс валют на дату (см. ResolveExchangeRate)
 func (s *ExchangeRateService) GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string, date time.Time) (float64, error) {
-	// Если валюты одинаковые, возвращаем 1
-	if fromCurrency == toCurrency {
-		return 1.0, nil
-	}
-
-	// Ищем курс в базе данных
-	rate, err := s.storage.GetExchangeRate(ctx, fromCurrency, toCurrency, date)
-	if err == nil {
-		return rate, nil
-	}
-
-	// Если курс не найден, пробуем найти обратный курс
-	reverseRate, err := s.storage.GetExchangeRate(ctx, toCurrency, fromCurrency, date)
-	if err == nil {
-		return 1.0 / reverseRate, nil
+	resolution, err := s.ResolveExchangeRate(ctx, fromCurrency, toCurrency, date)
+	if err != nil {
+		return 0, err
 	}
 
-	// Если ничего не найдено, возвращаем ошибку
-	return 0, fmt.Errorf("exchange rate not found for %s->%s on %s", fromCurrency, toCurrency, date.Format("2006-01-02"))
+	return resolution.Rate, nil
 }
 
 // ConvertAmount конвертирует сумму из одной валюты в другую
@@ -183,27 +140,14 @@ func (s *ExchangeRateService) ConvertAmount(ctx context.Context, amount float64,
 	return amount * rate, nil
 }
 
-// GetLatestExchangeRate получает последний доступный курс валют
+// GetLatestExchangeRate получает последний доступный курс валют (см. ResolveLatestExchangeRate)
 func (s *ExchangeRateService) GetLatestExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error) {
-	// Если валюты одинаковые, возвращаем 1
-	if fromCurrency == toCurrency {
-		return 1.0, nil
-	}
-
-	// Ищем последний курс в базе данных
-	rate, err := s.storage.GetLatestExchangeRate(ctx, fromCurrency, toCurrency)
-	if err == nil {
-		return rate, nil
-	}
-
-	// Если курс не найден, пробуем найти обратный курс
-	reverseRate, err := s.storage.GetLatestExchangeRate(ctx, toCurrency, fromCurrency)
-	if err == nil {
-		return 1.0 / reverseRate, nil
+	resolution, err := s.ResolveLatestExchangeRate(ctx, fromCurrency, toCurrency)
+	if err != nil {
+		return 0, err
 	}
 
-	// Если ничего не найдено, возвращаем ошибку
-	return 0, fmt.Errorf("exchange rate not found for %s->%s", fromCurrency, toCurrency)
+	return resolution.Rate, nil
 }
 
 // ConvertAmountLatest конвертирует сумму по последнему курсу
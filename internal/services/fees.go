@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"brok/internal/models"
+)
+
+// TransactionFees возвращает расходы по транзакции в валюте актива и их вид:
+// сумму транзакции fee или комиссию по сделке либо ноге перевода
+func TransactionFees(tx models.Transaction) (float64, string) {
+	switch {
+	case tx.Type == "fee":
+		return tx.Amount, models.FeeKindOther
+	case tx.Fee > 0 && IsTransferLeg(tx):
+		return tx.Fee, models.FeeKindTransfer
+	case tx.Fee > 0:
+		return tx.Fee, models.FeeKindTrade
+	}
+	return 0, ""
+}
+
+// Fees собирает комиссии и расходы пользователя с from по to включительно
+// по видам, месяцам и активам. Суммы переводятся в базовую валюту по курсу на дату операции.
+func (s *PortfolioService) Fees(ctx context.Context, userID string, from, to time.Time) (*models.FeeReport, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidPeriod)
+	}
+
+	base, err := s.baseCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.storage.AssetsByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	report := &models.FeeReport{
+		BaseCurrency: base,
+		From:         from,
+		To:           to,
+		ByAsset:      []models.FeeAsset{},
+	}
+	byKind := map[string]float64{}
+	byMonth := map[string]float64{}
+	missing := map[string]bool{}
+	until := to.AddDate(0, 0, 1) // конечная дата включительно
+
+	for _, asset := range assets {
+		transactions, err := s.storage.GetTransactionsByAssetID(ctx, asset.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transactions for asset %s: %w", asset.ID, err)
+		}
+
+		entry := models.FeeAsset{
+			AssetID:  asset.ID,
+			Name:     asset.Name,
+			Currency: asset.Currency,
+		}
+		for _, tx := range transactions {
+			amount, kind := TransactionFees(tx)
+			if amount == 0 || tx.Timestamp.Before(from) || !tx.Timestamp.Before(until) {
+				continue
+			}
+
+			converted, err := s.convertAt(ctx, amount, asset.Currency, base, tx.Timestamp)
+			if err != nil {
+				missing[asset.Currency+"->"+base] = true
+				continue
+			}
+
+			switch kind {
+			case models.FeeKindTrade:
+				entry.TradeFees += amount
+			case models.FeeKindTransfer:
+				entry.TransferFees += amount
+			default:
+				entry.OtherFees += amount
+			}
+			entry.Total += amount
+			entry.TotalBase += converted
+			entry.Events++
+
+			byKind[kind] += converted
+			byMonth[tx.Timestamp.Format("2006-01")] += converted
+			report.Total += converted
+		}
+
+		if entry.Events == 0 {
+			continue
+		}
+		if asset.Balance > 0 {
+			ratio := entry.Total / asset.Balance
+			entry.CostRatio = &ratio
+		}
+		entry.TradeFees = s.exchange.RoundAmount(entry.TradeFees)
+		entry.TransferFees = s.exchange.RoundAmount(entry.TransferFees)
+		entry.OtherFees = s.exchange.RoundAmount(entry.OtherFees)
+		entry.Total = s.exchange.RoundAmount(entry.Total)
+		entry.TotalBase = s.exchange.RoundAmount(entry.TotalBase)
+		report.ByAsset = append(report.ByAsset, entry)
+	}
+
+	sort.SliceStable(report.ByAsset, func(i, j int) bool {
+		return report.ByAsset[i].TotalBase > report.ByAsset[j].TotalBase
+	})
+	report.ByKind = feeGroups(byKind)
+	sort.Slice(report.ByKind, func(i, j int) bool {
+		if report.ByKind[i].Amount == report.ByKind[j].Amount {
+			return report.ByKind[i].Key < report.ByKind[j].Key
+		}
+		return report.ByKind[i].Amount > report.ByKind[j].Amount
+	})
+	report.ByMonth = feeGroups(byMonth)
+	sort.Slice(report.ByMonth, func(i, j int) bool {
+		return report.ByMonth[i].Key < report.ByMonth[j].Key
+	})
+	for pair := range missing {
+		report.MissingRates = append(report.MissingRates, pair)
+	}
+	sort.Strings(report.MissingRates)
+
+	report.Total = s.exchange.RoundAmount(report.Total)
+
+	return report, nil
+}
+
+// feeGroups возвращает группы расходов с округленными суммами
+func feeGroups(groups map[string]float64) []models.FeeGroup {
+	result := make([]models.FeeGroup, 0, len(groups))
+	for key, amount := range groups {
+		result = append(result, models.FeeGroup{Key: key, Amount: math.Round(amount*100) / 100})
+	}
+	return result
+}
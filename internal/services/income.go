@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"brok/internal/models"
+)
+
+// ErrInvalidIncome неверные данные о доходе
+var ErrInvalidIncome = errors.New("invalid income")
+
+// IncomeDetails данные о доходе из запроса; суммы в валюте ввода
+type IncomeDetails struct {
+	Kind           string
+	GrossAmount    *float64
+	WithholdingTax *float64
+	ExDate         *time.Time
+	PayDate        *time.Time
+}
+
+// empty сообщает, что никаких данных о доходе не передано
+func (d IncomeDetails) empty() bool {
+	return d.Kind == "" && d.GrossAmount == nil && d.WithholdingTax == nil && d.ExDate == nil && d.PayDate == nil
+}
+
+// ApplyIncomeDetails заполняет поля дохода транзакции dividend и выводит сумму к получению:
+// amount = gross_amount - withholding_tax. Если указан только налог, amount считается суммой к получению.
+func ApplyIncomeDetails(tx *models.Transaction, details IncomeDetails) error {
+	if tx.Type != "dividend" {
+		if !details.empty() {
+			return fmt.Errorf("%w: income details are only allowed for dividend", ErrInvalidIncome)
+		}
+		return nil
+	}
+
+	tx.IncomeKind = details.Kind
+	if tx.IncomeKind == "" {
+		tx.IncomeKind = models.IncomeKindDividend
+	}
+
+	tax := 0.0
+	if details.WithholdingTax != nil {
+		tax = *details.WithholdingTax
+	}
+	if details.GrossAmount != nil {
+		gross := *details.GrossAmount
+		if tax > gross {
+			return fmt.Errorf("%w: withholding_tax exceeds gross_amount", ErrInvalidIncome)
+		}
+		net := math.Round((gross-tax)*100) / 100
+		if tx.Amount != 0 && math.Abs(tx.Amount-net) >= 0.005 {
+			return fmt.Errorf("%w: amount must equal gross_amount - withholding_tax", ErrInvalidIncome)
+		}
+		tx.Amount = net
+		tx.GrossAmount = &gross
+	} else if details.WithholdingTax != nil {
+		gross := math.Round((tx.Amount+tax)*100) / 100
+		tx.GrossAmount = &gross
+	}
+	if details.WithholdingTax != nil {
+		tx.WithholdingTax = &tax
+	}
+
+	if details.ExDate != nil && details.PayDate != nil && details.ExDate.After(*details.PayDate) {
+		return fmt.Errorf("%w: ex_date is after pay_date", ErrInvalidIncome)
+	}
+	tx.ExDate = details.ExDate
+	tx.PayDate = details.PayDate
+
+	return nil
+}
+
+// HasIncomeDetails сообщает, что у дохода записаны начисление или налог
+func HasIncomeDetails(tx models.Transaction) bool {
+	return tx.GrossAmount != nil || tx.WithholdingTax != nil
+}
+
+// IncomeAmounts возвращает начисление, налог и сумму к получению по транзакции дохода в валюте актива
+func IncomeAmounts(tx models.Transaction) (gross, tax, net float64) {
+	net = tx.Amount
+	if tx.WithholdingTax != nil {
+		tax = *tx.WithholdingTax
+	}
+	gross = net + tax
+	if tx.GrossAmount != nil {
+		gross = *tx.GrossAmount
+	}
+	return gross, tax, net
+}
+
+// IncomeDate дата получения дохода: дата выплаты, а если ее нет — дата транзакции
+func IncomeDate(tx models.Transaction) time.Time {
+	if tx.PayDate != nil {
+		return *tx.PayDate
+	}
+	return tx.Timestamp
+}
+
+// Income собирает доходы пользователя (dividend) с датой выплаты с from по to включительно
+// по месяцам, активам, валютам выплаты и видам дохода в базовой валюте.
+// Доходность за 12 месяцев считается по активу относительно его текущей стоимости.
+func (s *PortfolioService) Income(ctx context.Context, userID string, from, to, now time.Time) (*models.IncomeReport, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidPeriod)
+	}
+
+	base, err := s.baseCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.storage.AssetsByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	report := &models.IncomeReport{
+		BaseCurrency: base,
+		From:         from,
+		To:           to,
+		ByAsset:      []models.IncomeAsset{},
+	}
+	byMonth := map[string]*models.IncomeGroup{}
+	byCurrency := map[string]*models.IncomeGroup{}
+	byKind := map[string]*models.IncomeGroup{}
+	missing := map[string]bool{}
+	until := to.AddDate(0, 0, 1) // конечная дата включительно
+	ttmFrom := now.AddDate(-1, 0, 0)
+
+	for _, asset := range assets {
+		transactions, err := s.storage.GetTransactionsByAssetID(ctx, asset.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transactions for asset %s: %w", asset.ID, err)
+		}
+
+		entry := models.IncomeAsset{
+			AssetID:  asset.ID,
+			Name:     asset.Name,
+			Currency: asset.Currency,
+		}
+		for _, tx := range transactions {
+			if tx.Type != "dividend" {
+				continue
+			}
+			gross, tax, net := IncomeAmounts(tx)
+			date := IncomeDate(tx)
+
+			if date.After(ttmFrom) && !date.After(now) {
+				entry.TTMGross += gross
+				entry.TTMNet += net
+			}
+			if date.Before(from) || !date.Before(until) {
+				continue
+			}
+
+			rate, err := s.convertAt(ctx, 1, asset.Currency, base, date)
+			if err != nil {
+				missing[asset.Currency+"->"+base] = true
+				continue
+			}
+			amounts := models.IncomeGroup{Gross: gross * rate, Tax: tax * rate, Net: net * rate}
+
+			entry.Gross += amounts.Gross
+			entry.Tax += amounts.Tax
+			entry.Net += amounts.Net
+			entry.Events++
+
+			currency := tx.Currency
+			if tx.OriginalCurrency != nil {
+				currency = *tx.OriginalCurrency
+			}
+			addIncome(byMonth, date.Format("2006-01"), amounts)
+			addIncome(byCurrency, currency, amounts)
+			addIncome(byKind, models.IncomeKindOf(tx), amounts)
+
+			report.TotalGross += amounts.Gross
+			report.TotalTax += amounts.Tax
+			report.TotalNet += amounts.Net
+		}
+
+		if entry.Events == 0 && entry.TTMGross == 0 {
+			continue
+		}
+		if asset.Balance > 0 {
+			yield := entry.TTMGross / asset.Balance
+			entry.TTMYield = &yield
+		}
+		entry.Gross = s.exchange.RoundAmount(entry.Gross)
+		entry.Tax = s.exchange.RoundAmount(entry.Tax)
+		entry.Net = s.exchange.RoundAmount(entry.Net)
+		entry.TTMGross = s.exchange.RoundAmount(entry.TTMGross)
+		entry.TTMNet = s.exchange.RoundAmount(entry.TTMNet)
+		report.ByAsset = append(report.ByAsset, entry)
+	}
+
+	sort.SliceStable(report.ByAsset, func(i, j int) bool {
+		return report.ByAsset[i].Gross > report.ByAsset[j].Gross
+	})
+	report.ByMonth = incomeGroups(byMonth)
+	sort.Slice(report.ByMonth, func(i, j int) bool {
+		return report.ByMonth[i].Key < report.ByMonth[j].Key
+	})
+	report.ByCurrency = incomeGroups(byCurrency)
+	report.ByKind = incomeGroups(byKind)
+	for pair := range missing {
+		report.MissingRates = append(report.MissingRates, pair)
+	}
+	sort.Strings(report.MissingRates)
+
+	report.TotalGross = s.exchange.RoundAmount(report.TotalGross)
+	report.TotalTax = s.exchange.RoundAmount(report.TotalTax)
+	report.TotalNet = s.exchange.RoundAmount(report.TotalNet)
+
+	return report, nil
+}
+
+// addIncome добавляет суммы дохода к группе key
+func addIncome(groups map[string]*models.IncomeGroup, key string, amounts models.IncomeGroup) {
+	group, ok := groups[key]
+	if !ok {
+		group = &models.IncomeGroup{Key: key}
+		groups[key] = group
+	}
+	group.Gross += amounts.Gross
+	group.Tax += amounts.Tax
+	group.Net += amounts.Net
+}
+
+// incomeGroups возвращает группы с округленными суммами, от большего дохода к меньшему
+func incomeGroups(groups map[string]*models.IncomeGroup) []models.IncomeGroup {
+	result := make([]models.IncomeGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, models.IncomeGroup{
+			Key:   group.Key,
+			Gross: math.Round(group.Gross*100) / 100,
+			Tax:   math.Round(group.Tax*100) / 100,
+			Net:   math.Round(group.Net*100) / 100,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Gross == result[j].Gross {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Gross > result[j].Gross
+	})
+	return result
+}
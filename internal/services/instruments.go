@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+)
+
+// MaxInstrumentRows максимальное количество строк в файле справочника
+const MaxInstrumentRows = 50000
+
+var (
+	// ErrInvalidInstrument инструмент не прошел проверку
+	ErrInvalidInstrument = errors.New("invalid instrument")
+	// ErrInvalidInstrumentFile файл справочника не разобран
+	ErrInvalidInstrumentFile = errors.New("invalid instruments file")
+)
+
+// ValidateISIN проверяет формат ISIN (ISO 6166) и контрольную цифру
+func ValidateISIN(isin string) bool {
+	if len(isin) != 12 {
+		return false
+	}
+
+	// Буквы заменяются числами (A=10 ... Z=35), затем считается контрольная сумма Луна
+	var digits []int
+	for i, r := range isin[:11] {
+		switch {
+		case r >= '0' && r <= '9' && i >= 2:
+			digits = append(digits, int(r-'0'))
+		case r >= 'A' && r <= 'Z':
+			value := int(r-'A') + 10
+			digits = append(digits, value/10, value%10)
+		default:
+			return false
+		}
+	}
+	check := isin[11]
+	if check < '0' || check > '9' {
+		return false
+	}
+
+	sum := 0
+	for i := range digits {
+		digit := digits[len(digits)-1-i]
+		// Удваивается каждая вторая цифра, начиная с правой: контрольная цифра еще не добавлена
+		if i%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+
+	return (10-sum%10)%10 == int(check-'0')
+}
+
+// NormalizeInstrument приводит поля инструмента к каноническому виду и проверяет их
+func NormalizeInstrument(instrument *models.Instrument) error {
+	instrument.Symbol = strings.ToUpper(strings.TrimSpace(instrument.Symbol))
+	instrument.Name = strings.TrimSpace(instrument.Name)
+	instrument.Exchange = strings.ToUpper(strings.TrimSpace(instrument.Exchange))
+	instrument.Currency = strings.ToUpper(strings.TrimSpace(instrument.Currency))
+
+	if instrument.Symbol == "" || len(instrument.Symbol) > 20 {
+		return fmt.Errorf("%w: symbol must be 1..20 characters", ErrInvalidInstrument)
+	}
+	if instrument.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInstrument)
+	}
+	if len(instrument.Exchange) > 20 {
+		return fmt.Errorf("%w: exchange must be at most 20 characters", ErrInvalidInstrument)
+	}
+
+	class, ok := models.NormalizeAssetClass(instrument.AssetClass)
+	if !ok {
+		return fmt.Errorf("%w: unknown asset class %q, use: %s", ErrInvalidInstrument,
+			instrument.AssetClass, strings.Join(models.GetAssetClasses(), ", "))
+	}
+	instrument.AssetClass = class
+
+	if !models.IsCurrencySupported(instrument.Currency) {
+		return fmt.Errorf("%w: unsupported currency %s", ErrInvalidInstrument, instrument.Currency)
+	}
+
+	if instrument.ISIN != nil {
+		isin := strings.ToUpper(strings.TrimSpace(*instrument.ISIN))
+		if isin == "" {
+			instrument.ISIN = nil
+		} else if !ValidateISIN(isin) {
+			return fmt.Errorf("%w: invalid ISIN %s", ErrInvalidInstrument, isin)
+		} else {
+			instrument.ISIN = &isin
+		}
+	}
+
+	if instrument.LotSize == 0 {
+		instrument.LotSize = 1
+	}
+	if instrument.LotSize < 0 {
+		return fmt.Errorf("%w: lot_size must be positive", ErrInvalidInstrument)
+	}
+
+	return nil
+}
+
+// instrumentKey инструмент однозначно определяется тикером и биржей
+func instrumentKey(instrument models.Instrument) string {
+	return instrument.Symbol + "@" + instrument.Exchange
+}
+
+// instrumentRow строка файла справочника
+type instrumentRow struct {
+	row        int
+	instrument models.Instrument
+}
+
+// parseInstrumentsCSV разбирает файл справочника с заголовком: symbol, name, asset_class, currency
+// обязательны; isin, exchange, lot_size — по желанию. Колонки ищутся по заголовкам без учета регистра.
+func parseInstrumentsCSV(r io.Reader) ([]instrumentRow, []models.InstrumentRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidInstrumentFile, err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"symbol", "name", "asset_class", "currency"} {
+		if _, ok := index[required]; !ok {
+			return nil, nil, fmt.Errorf("%w: column %q not found", ErrInvalidInstrumentFile, required)
+		}
+	}
+
+	var rows []instrumentRow
+	var rowErrors []models.InstrumentRowError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: line %d: %v", ErrInvalidInstrumentFile, line, err)
+		}
+		if len(rows)+len(rowErrors) >= MaxInstrumentRows {
+			return nil, nil, fmt.Errorf("%w: more than %d rows", ErrInvalidInstrumentFile, MaxInstrumentRows)
+		}
+
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		instrument := models.Instrument{
+			Symbol:     field("symbol"),
+			Name:       field("name"),
+			AssetClass: field("asset_class"),
+			Exchange:   field("exchange"),
+			Currency:   field("currency"),
+		}
+		if isin := field("isin"); isin != "" {
+			instrument.ISIN = &isin
+		}
+		if lotSize := field("lot_size"); lotSize != "" {
+			instrument.LotSize, err = strconv.Atoi(lotSize)
+			if err != nil || instrument.LotSize <= 0 {
+				rowErrors = append(rowErrors, models.InstrumentRowError{Row: line, Error: "invalid lot_size"})
+				continue
+			}
+		}
+
+		if err := NormalizeInstrument(&instrument); err != nil {
+			rowErrors = append(rowErrors, models.InstrumentRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, instrumentRow{row: line, instrument: instrument})
+	}
+
+	return rows, rowErrors, nil
+}
+
+// InstrumentService ведение справочника инструментов
+type InstrumentService struct {
+	storage storage.Storage
+}
+
+// NewInstrumentService создает новый сервис справочника инструментов
+func NewInstrumentService(storage storage.Storage) *InstrumentService {
+	return &InstrumentService{storage: storage}
+}
+
+// Import загружает справочник из CSV: новые инструменты добавляются, существующие
+// (по тикеру и бирже) обновляются. Файл записывается целиком или не записывается вовсе:
+// при ошибках хотя бы в одной строке возвращается отчет с ошибками и ErrInvalidInstrumentFile.
+func (s *InstrumentService) Import(ctx context.Context, r io.Reader, dryRun bool) (*models.InstrumentImportReport, error) {
+	rows, rowErrors, err := parseInstrumentsCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.InstrumentImportReport{
+		DryRun: dryRun,
+		Total:  len(rows) + len(rowErrors),
+		Errors: append([]models.InstrumentRowError{}, rowErrors...),
+	}
+
+	existing, err := s.storage.AllInstruments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instruments: %w", err)
+	}
+	byKey := make(map[string]models.Instrument, len(existing))
+	isinOwners := make(map[string]string, len(existing))
+	for _, instrument := range existing {
+		byKey[instrumentKey(instrument)] = instrument
+		if instrument.ISIN != nil {
+			isinOwners[*instrument.ISIN] = instrumentKey(instrument)
+		}
+	}
+
+	now := time.Now()
+	var creates, updates []models.Instrument
+	seen := map[string]int{}
+	for _, row := range rows {
+		instrument := row.instrument
+		key := instrumentKey(instrument)
+
+		if first, ok := seen[key]; ok {
+			report.Errors = append(report.Errors, models.InstrumentRowError{
+				Row: row.row, Error: fmt.Sprintf("duplicate of row %d", first)})
+			continue
+		}
+		seen[key] = row.row
+
+		// ISIN уникален во всем справочнике
+		if instrument.ISIN != nil {
+			if owner, ok := isinOwners[*instrument.ISIN]; ok && owner != key {
+				report.Errors = append(report.Errors, models.InstrumentRowError{
+					Row: row.row, Error: fmt.Sprintf("ISIN %s already belongs to %s", *instrument.ISIN, owner)})
+				continue
+			}
+			isinOwners[*instrument.ISIN] = key
+		}
+
+		instrument.UpdatedAt = now
+		if current, ok := byKey[key]; ok {
+			instrument.ID = current.ID
+			instrument.CreatedAt = current.CreatedAt
+			updates = append(updates, instrument)
+		} else {
+			instrument.ID = uuid.New().String()
+			instrument.CreatedAt = now
+			creates = append(creates, instrument)
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		return report, ErrInvalidInstrumentFile
+	}
+
+	report.Created = len(creates)
+	report.Updated = len(updates)
+	if dryRun {
+		return report, nil
+	}
+
+	err = s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		for _, instrument := range updates {
+			if err := s.storage.UpdateInstrumentTx(ctx, tx, instrument); err != nil {
+				return err
+			}
+		}
+		for _, instrument := range creates {
+			if err := s.storage.CreateInstrumentTx(ctx, tx, instrument); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save instruments: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+)
+
+var (
+	// ErrInsufficientLots в открытых лотах не хватает бумаг для продажи
+	ErrInsufficientLots = errors.New("insufficient quantity in open lots")
+	// ErrInvalidLotSelection явно выбранные лоты не подходят для продажи
+	ErrInvalidLotSelection = errors.New("invalid lot selection")
+)
+
+// ResolveLotMethod выбирает метод подбора лотов: из запроса, затем актива, затем пользователя
+func ResolveLotMethod(requested string, asset *string, user string) string {
+	if requested != "" {
+		return requested
+	}
+	if asset != nil && *asset != "" {
+		return *asset
+	}
+	if user != "" {
+		return user
+	}
+	return models.LotMethodFIFO
+}
+
+// ReplayLots проводит все сделки актива через лоты. Для продаж применяются сохраненные
+// сопоставления, а продажи без них (созданные до учета лотов) закрывают лоты по FIFO.
+// Возвращает лоты с остатками и все закрытия.
+func ReplayLots(transactions []models.Transaction, matches []models.LotMatch) ([]models.Lot, []models.LotMatch) {
+	bySell := map[string][]models.LotMatch{}
+	for _, m := range matches {
+		bySell[m.SellTransactionID] = append(bySell[m.SellTransactionID], m)
+	}
+
+	var lots []models.Lot
+	index := map[string]int{}
+	var realized []models.LotMatch
+
+	for _, tx := range SortTransactions(transactions) {
+		if !IsTrade(tx) {
+			continue
+		}
+
+		switch tx.Type {
+		case "buy":
+			// Комиссия покупки входит в стоимость лота
+			value := TradeValue(tx) + tx.Fee
+			index[tx.ID] = len(lots)
+			lots = append(lots, models.Lot{
+				TransactionID: tx.ID,
+				AssetID:       tx.AssetID,
+				Ticker:        tx.Ticker,
+				AcquiredAt:    tx.Timestamp,
+				Quantity:      tx.Quantity,
+				Remaining:     tx.Quantity,
+				UnitCost:      value / tx.Quantity,
+			})
+		case "sell":
+			sellMatches, ok := bySell[tx.ID]
+			if !ok {
+				// Продажа без сохраненных сопоставлений: закрываем что есть по FIFO
+				sellMatches = matchAvailable(lots, tx, models.LotMethodFIFO)
+			}
+			for _, m := range sellMatches {
+				if i, ok := index[m.BuyTransactionID]; ok {
+					lots[i].Remaining -= m.Quantity
+				}
+				realized = append(realized, m)
+			}
+		}
+	}
+
+	for i := range lots {
+		if lots[i].Remaining < quantityEpsilon {
+			lots[i].Remaining = 0
+		}
+		lots[i].CostBasis = lots[i].Remaining * lots[i].UnitCost
+	}
+
+	return lots, realized
+}
+
+// OpenLots возвращает лоты с ненулевым остатком
+func OpenLots(transactions []models.Transaction, matches []models.LotMatch) []models.Lot {
+	lots, _ := ReplayLots(transactions, matches)
+
+	open := make([]models.Lot, 0, len(lots))
+	for _, lot := range lots {
+		if lot.Remaining > 0 {
+			open = append(open, lot)
+		}
+	}
+	return open
+}
+
+// MatchLots подбирает лоты для продажи выбранным методом. Для метода specific
+// используются явно указанные лоты и количества.
+func MatchLots(lots []models.Lot, sell models.Transaction, method string, selections []models.LotSelection) ([]models.LotMatch, error) {
+	if method == models.LotMethodSpecific {
+		return matchSelected(lots, sell, selections)
+	}
+
+	var available float64
+	for _, lot := range eligibleLots(lots, sell) {
+		available += lot.Remaining
+	}
+	if available+quantityEpsilon < sell.Quantity {
+		return nil, ErrInsufficientLots
+	}
+
+	return matchAvailable(lots, sell, method), nil
+}
+
+// eligibleLots лоты того же инструмента, купленные не позже продажи и не закрытые полностью
+func eligibleLots(lots []models.Lot, sell models.Transaction) []models.Lot {
+	var result []models.Lot
+	for _, lot := range lots {
+		if lot.Ticker != sell.Ticker || lot.Remaining <= 0 || lot.AcquiredAt.After(sell.Timestamp) {
+			continue
+		}
+		result = append(result, lot)
+	}
+	return result
+}
+
+// matchAvailable закрывает лоты в порядке метода, пока не наберется количество продажи
+func matchAvailable(lots []models.Lot, sell models.Transaction, method string) []models.LotMatch {
+	candidates := eligibleLots(lots, sell)
+
+	switch method {
+	case models.LotMethodLIFO:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].AcquiredAt.After(candidates[j].AcquiredAt)
+		})
+	case models.LotMethodHIFO:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].UnitCost > candidates[j].UnitCost
+		})
+	default:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].AcquiredAt.Before(candidates[j].AcquiredAt)
+		})
+	}
+
+	var matches []models.LotMatch
+	left := sell.Quantity
+	for _, lot := range candidates {
+		if left < quantityEpsilon {
+			break
+		}
+		qty := math.Min(left, lot.Remaining)
+		matches = append(matches, newLotMatch(lot, sell, method, qty))
+		left -= qty
+	}
+
+	return matches
+}
+
+// matchSelected закрывает явно выбранные лоты
+func matchSelected(lots []models.Lot, sell models.Transaction, selections []models.LotSelection) ([]models.LotMatch, error) {
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("%w: no lots selected", ErrInvalidLotSelection)
+	}
+
+	available := map[string]models.Lot{}
+	for _, lot := range eligibleLots(lots, sell) {
+		available[lot.TransactionID] = lot
+	}
+
+	var matches []models.LotMatch
+	var total float64
+	for _, sel := range selections {
+		lot, ok := available[sel.LotID]
+		if !ok {
+			return nil, fmt.Errorf("%w: lot %s is not open for %s", ErrInvalidLotSelection, sel.LotID, sell.Ticker)
+		}
+		if sel.Quantity > lot.Remaining+quantityEpsilon {
+			return nil, fmt.Errorf("%w: lot %s has only %g remaining", ErrInvalidLotSelection, sel.LotID, lot.Remaining)
+		}
+
+		matches = append(matches, newLotMatch(lot, sell, models.LotMethodSpecific, sel.Quantity))
+		lot.Remaining -= sel.Quantity
+		available[sel.LotID] = lot
+		total += sel.Quantity
+	}
+
+	if math.Abs(total-sell.Quantity) > quantityEpsilon {
+		return nil, fmt.Errorf("%w: selected quantity %g does not match sold quantity %g", ErrInvalidLotSelection, total, sell.Quantity)
+	}
+
+	return matches, nil
+}
+
+// newLotMatch формирует закрытие части лота
+func newLotMatch(lot models.Lot, sell models.Transaction, method string, qty float64) models.LotMatch {
+	cost := lot.UnitCost * qty
+	// Выручка — за вычетом комиссии продажи, пропорционально количеству
+	proceeds := (TradeValue(sell) - sell.Fee) * qty / sell.Quantity
+
+	return models.LotMatch{
+		ID:                uuid.New().String(),
+		AssetID:           sell.AssetID,
+		SellTransactionID: sell.ID,
+		BuyTransactionID:  lot.TransactionID,
+		Ticker:            sell.Ticker,
+		Method:            method,
+		Quantity:          qty,
+		CostBasis:         cost,
+		Proceeds:          proceeds,
+		RealizedGain:      proceeds - cost,
+		AcquiredAt:        lot.AcquiredAt,
+		DisposedAt:        sell.Timestamp,
+	}
+}
+
+// FillHoldingPeriod заполняет срок владения для закрытия лота
+func FillHoldingPeriod(m *models.LotMatch) {
+	m.HoldingDays = int(m.DisposedAt.Sub(m.AcquiredAt) / (24 * time.Hour))
+	m.LongTerm = m.HoldingDays > models.LongTermHoldingDays
+}
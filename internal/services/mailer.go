@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/config"
+)
+
+// Способы отправки писем
+const (
+	MailerSMTP = "smtp"
+	MailerLog  = "log"
+	MailerFile = "file"
+)
+
+// Email письмо пользователю (простой текст)
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer способ отправки писем
+type Mailer interface {
+	Name() string
+	Send(ctx context.Context, email Email) error
+}
+
+// message собирает письмо в формате RFC 5322
+func (e Email) message(from string, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", e.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", e.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@brok>\r\n", uuid.New().String())
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(e.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// SMTPMailer отправляет письма через SMTP-сервер. STARTTLS используется, если сервер его поддерживает;
+// без логина письма отправляются без аутентификации (локальные заглушки вроде MailHog).
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	timeout  time.Duration
+}
+
+// NewSMTPMailer создает отправку писем через SMTP-сервер host:port
+func NewSMTPMailer(host, port, username, password, from string, timeout time.Duration) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+		timeout:  timeout,
+	}
+}
+
+// Name возвращает имя способа отправки
+func (m *SMTPMailer) Name() string {
+	return MailerSMTP
+}
+
+// Send отправляет письмо
+func (m *SMTPMailer) Send(ctx context.Context, email Email) error {
+	dialer := net.Dialer{Timeout: m.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(m.timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	if m.username != "" {
+		// PlainAuth отказывается передавать пароль без TLS (кроме localhost)
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	if err := client.Mail(sender.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(email.To); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(email.message(m.from, time.Now())); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// linkTokenPattern токен в ссылке из письма
+var linkTokenPattern = regexp.MustCompile(`([?&]token=)[^\s&]+`)
+
+// LogMailer пишет письма в лог вместо отправки (для разработки). Токены в ссылках
+// скрываются, чтобы ссылки сброса пароля и подтверждения не попадали в логи.
+type LogMailer struct{}
+
+// Name возвращает имя способа отправки
+func (m LogMailer) Name() string {
+	return MailerLog
+}
+
+// Send выводит письмо в лог
+func (m LogMailer) Send(ctx context.Context, email Email) error {
+	body := linkTokenPattern.ReplaceAllString(email.Body, "${1}[redacted]")
+	log.Printf("✉️  Письмо для %s: %s\n%s", email.To, email.Subject, body)
+	return nil
+}
+
+// FileMailer сохраняет письма в каталог файлами .eml (для тестов и разработки)
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer создает сохранение писем в каталог dir
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+// Name возвращает имя способа отправки
+func (m *FileMailer) Name() string {
+	return MailerFile
+}
+
+// Send записывает письмо в файл <время>-<id>.eml
+func (m *FileMailer) Send(ctx context.Context, email Email) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), uuid.New().String())
+	return os.WriteFile(filepath.Join(m.dir, name), email.message(m.from, now), 0o600)
+}
+
+// NewMailerFromEnv собирает отправку писем по переменным окружения:
+//
+//	MAILER — smtp, log или file; задается явно, чтобы письма не уходили в лог по ошибке настройки
+//	MAIL_FROM — адрес отправителя (по умолчанию brok <no-reply@localhost>)
+//	SMTP_HOST, SMTP_PORT (по умолчанию 587), SMTP_USERNAME, SMTP_PASSWORD — SMTP-сервер
+//	SMTP_TIMEOUT — таймаут отправки (по умолчанию 10s)
+//	MAIL_DIR — каталог для писем способа file (по умолчанию mail)
+func NewMailerFromEnv() (Mailer, error) {
+	from := config.GetEnv("MAIL_FROM", "brok <no-reply@localhost>")
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+
+	switch name := config.GetEnv("MAILER", ""); name {
+	case "":
+		return nil, errors.New("MAILER is required: smtp, log or file")
+	case MailerSMTP:
+		host := config.GetEnv("SMTP_HOST", "")
+		if host == "" {
+			return nil, errors.New("SMTP_HOST is required for the smtp mailer")
+		}
+		timeout, err := time.ParseDuration(config.GetEnv("SMTP_TIMEOUT", "10s"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_TIMEOUT: %w", err)
+		}
+		return NewSMTPMailer(host, config.GetEnv("SMTP_PORT", "587"),
+			os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from, timeout), nil
+	case MailerLog:
+		return LogMailer{}, nil
+	case MailerFile:
+		return NewFileMailer(config.GetEnv("MAIL_DIR", "mail"), from), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer: %s", name)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession команды и письмо, полученные локальной заглушкой SMTP
+type smtpSession struct {
+	from string
+	rcpt string
+	data string
+}
+
+// startSMTPStub запускает заглушку SMTP-сервера на одно соединение; rejectRcpt — отклонять получателя
+func startSMTPStub(t *testing.T, rejectRcpt bool) (host, port string, session <-chan smtpSession) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	result := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		var got smtpSession
+		defer func() { result <- got }()
+
+		reply("220 localhost ESMTP stub")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimRight(line, "\r\n")
+			switch upper := strings.ToUpper(command); {
+			case strings.HasPrefix(upper, "EHLO"):
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(upper, "MAIL FROM:"):
+				got.from = command[len("MAIL FROM:"):]
+				reply("250 OK")
+			case strings.HasPrefix(upper, "RCPT TO:"):
+				if rejectRcpt {
+					reply("550 no such user")
+					continue
+				}
+				got.rcpt = command[len("RCPT TO:"):]
+				reply("250 OK")
+			case upper == "DATA":
+				reply("354 end with <CRLF>.<CRLF>")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				got.data = data.String()
+				reply("250 queued")
+			case upper == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, port, err = net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port, result
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	host, port, session := startSMTPStub(t, false)
+	mailer := NewSMTPMailer(host, port, "", "", "brok <no-reply@example.com>", 5*time.Second)
+
+	err := mailer.Send(context.Background(), Email{
+		To:      "user@example.com",
+		Subject: "Сброс пароля",
+		Body:    "Line one\nLine two\n",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := <-session
+	// Заглушка объявляет 8BITMIME, поэтому клиент добавляет BODY=8BITMIME
+	if got.from != "<no-reply@example.com> BODY=8BITMIME" {
+		t.Errorf("MAIL FROM = %q, want <no-reply@example.com> BODY=8BITMIME", got.from)
+	}
+	if got.rcpt != "<user@example.com>" {
+		t.Errorf("RCPT TO = %q, want <user@example.com>", got.rcpt)
+	}
+	for _, want := range []string{
+		"From: brok <no-reply@example.com>\r\n",
+		"To: user@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nLine one\r\nLine two\r\n",
+	} {
+		if !strings.Contains(got.data, want) {
+			t.Errorf("message does not contain %q:\n%s", want, got.data)
+		}
+	}
+}
+
+func TestSMTPMailerRejectedRecipient(t *testing.T) {
+	host, port, _ := startSMTPStub(t, true)
+	mailer := NewSMTPMailer(host, port, "", "", "no-reply@example.com", 5*time.Second)
+
+	err := mailer.Send(context.Background(), Email{To: "missing@example.com", Subject: "Test", Body: "Body"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("Send to rejected recipient: error = %v, want 550", err)
+	}
+}
+
+func TestFileMailerSend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := NewFileMailer(dir, "no-reply@example.com")
+
+	if err := mailer.Send(context.Background(), Email{To: "user@example.com", Subject: "Hello", Body: "Body\n"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one .eml file in %s, got %v (%v)", dir, files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte("To: user@example.com\r\n")) || !bytes.HasSuffix(content, []byte("\r\n\r\nBody\r\n")) {
+		t.Errorf("unexpected message:\n%s", content)
+	}
+}
+
+func TestLogMailerRedactsTokens(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	err := LogMailer{}.Send(context.Background(), Email{
+		To:      "user@example.com",
+		Subject: "Reset your password",
+		Body:    "Open http://localhost:8080/reset-password?token=secret.jwt.value to continue\n",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	logged := buf.String()
+	if strings.Contains(logged, "secret.jwt.value") {
+		t.Errorf("token leaked to log: %s", logged)
+	}
+	if !strings.Contains(logged, "/reset-password?token=[redacted] to continue") {
+		t.Errorf("unexpected log output: %s", logged)
+	}
+}
+
+func TestNewMailerFromEnv(t *testing.T) {
+	t.Setenv("MAIL_FROM", "")
+
+	t.Setenv("MAILER", "")
+	if _, err := NewMailerFromEnv(); err == nil {
+		t.Error("MAILER is not set: expected error")
+	}
+
+	t.Setenv("MAILER", "log")
+	if mailer, err := NewMailerFromEnv(); err != nil || mailer.Name() != MailerLog {
+		t.Errorf("MAILER=log: mailer = %v, err = %v", mailer, err)
+	}
+
+	t.Setenv("MAILER", "smtp")
+	t.Setenv("SMTP_HOST", "")
+	if _, err := NewMailerFromEnv(); err == nil {
+		t.Error("smtp without SMTP_HOST: expected error")
+	}
+
+	t.Setenv("MAILER", "pigeon")
+	if _, err := NewMailerFromEnv(); err == nil {
+		t.Error("unknown mailer: expected error")
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"github.com/maksim77/goxirr"
+
+	"brok/internal/models"
+)
+
+// AssetMetrics денежные потоки и прибыль актива в его валюте
+type AssetMetrics struct {
+	Cashflows   goxirr.Transactions
+	Deposits    float64
+	Withdrawals float64
+	Dividends   float64
+	Fees        float64 // комиссии по сделкам и переводам и транзакции fee
+	Profit      float64
+}
+
+// CalculateAssetMetrics собирает потоки для XIRR и считает прибыль актива
+func CalculateAssetMetrics(balance float64, transactions []models.Transaction) AssetMetrics {
+	var m AssetMetrics
+
+	for _, tx := range transactions {
+		amount := tx.Amount
+		switch tx.Type {
+		case "deposit":
+			m.Cashflows = append(m.Cashflows, goxirr.Transaction{
+				Date: tx.Timestamp,
+				Cash: -amount, // вложения — отрицательный поток для XIRR
+			})
+			m.Deposits += amount
+		case "withdrawal":
+			m.Cashflows = append(m.Cashflows, goxirr.Transaction{
+				Date: tx.Timestamp,
+				Cash: amount, // выводы — положительный поток для XIRR
+			})
+			m.Withdrawals += amount
+		case "buy":
+			m.Cashflows = append(m.Cashflows, goxirr.Transaction{
+				Date: tx.Timestamp,
+				Cash: -amount, // покупки — отрицательный поток для XIRR
+			})
+		case "sell":
+			m.Cashflows = append(m.Cashflows, goxirr.Transaction{
+				Date: tx.Timestamp,
+				Cash: amount, // продажи — положительный поток для XIRR
+			})
+		case "dividend":
+			m.Cashflows = append(m.Cashflows, goxirr.Transaction{
+				Date: tx.Timestamp,
+				Cash: amount, // дивиденды — положительный поток для XIRR
+			})
+			m.Dividends += amount
+		case "revaluation":
+			m.Cashflows = append(m.Cashflows, goxirr.Transaction{
+				Date: tx.Timestamp,
+				Cash: amount, // может быть + или -
+			})
+		case "fee":
+			m.Cashflows = append(m.Cashflows, goxirr.Transaction{
+				Date: tx.Timestamp,
+				Cash: -amount, // расходы — отрицательный поток для XIRR
+			})
+			m.Fees += amount
+		}
+
+		// Комиссия по сделке или переводу — отдельный отрицательный поток
+		if tx.Fee > 0 {
+			m.Cashflows = append(m.Cashflows, goxirr.Transaction{
+				Date: tx.Timestamp,
+				Cash: -tx.Fee,
+			})
+			m.Fees += tx.Fee
+		}
+	}
+
+	// Чистая прибыль = Текущий баланс - Сумма вложений + Сумма выводов + Дивиденды.
+	// Комиссии уже уменьшили баланс.
+	m.Profit = balance - m.Deposits + m.Withdrawals + m.Dividends
+
+	return m
+}
+
+// Xirr считает XIRR по потокам, упорядочивая их по дате.
+// Возвращает false, если потоков недостаточно или результат не определен.
+func Xirr(cashflows goxirr.Transactions) (float64, bool) {
+	if len(cashflows) < 2 {
+		return 0, false
+	}
+
+	sorted := make(goxirr.Transactions, len(cashflows))
+	copy(sorted, cashflows)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	xirr := goxirr.Xirr(sorted)
+	if math.IsNaN(xirr) || math.IsInf(xirr, 0) {
+		return 0, false
+	}
+	return xirr, true
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"brok/internal/models"
+)
+
+// ValuePoint оценка стоимости на момент времени
+type ValuePoint struct {
+	Date  time.Time
+	Value float64
+}
+
+// ExternalFlow внешний денежный поток: положительный — вложение, отрицательный — вывод
+type ExternalFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// IsExternalFlow проверяет, является ли транзакция внешним потоком для TWR.
+// Внешними считаются только ввод и вывод средств, сделки — внутреннее перемещение (см. valueAt).
+func IsExternalFlow(tx models.Transaction) bool {
+	return tx.Type == "deposit" || tx.Type == "withdrawal"
+}
+
+// IsTransferLeg проверяет, является ли транзакция ногой перевода между активами пользователя.
+// Для отдельного актива это внешний поток, для портфеля в целом — внутреннее перемещение.
+func IsTransferLeg(tx models.Transaction) bool {
+	return tx.TransferID != nil
+}
+
+// WithoutTransfers возвращает транзакции без ног переводов между активами
+func WithoutTransfers(transactions []models.Transaction) []models.Transaction {
+	external := make([]models.Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if !IsTransferLeg(tx) {
+			external = append(external, tx)
+		}
+	}
+	return external
+}
+
+// LinkedReturn считает доходность, взвешенную по времени: период делится на
+// подпериоды между оценками, доходность каждого считается по Модифицированному
+// методу Дитца (потоки взвешиваются по оставшейся доле подпериода), а результаты
+// перемножаются. Возвращает доходность в долях, как XIRR, и false, если посчитать нечего.
+func LinkedReturn(points []ValuePoint, flows []ExternalFlow) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+
+	growth := 1.0
+	linked := false
+	for i := 1; i < len(points); i++ {
+		start, end := points[i-1], points[i]
+		length := end.Date.Sub(start.Date).Seconds()
+
+		var net, weighted float64
+		for _, f := range flows {
+			if !f.Date.After(start.Date) || f.Date.After(end.Date) {
+				continue
+			}
+			weight := 0.0
+			if length > 0 {
+				weight = end.Date.Sub(f.Date).Seconds() / length
+			}
+			net += f.Amount
+			weighted += weight * f.Amount
+		}
+
+		denominator := start.Value + weighted
+		if denominator <= 0 {
+			// Подпериод без вложенного капитала не влияет на доходность
+			continue
+		}
+
+		growth *= 1 + (end.Value-start.Value-net)/denominator
+		linked = true
+	}
+
+	if !linked {
+		return 0, false
+	}
+	return growth - 1, true
+}
+
+// valueAt стоимость актива на момент t для TWR (транзакции упорядочены): денежный баланс
+// плюс оценка позиций. Сделки — внутреннее перемещение: покупка переводит деньги в позицию
+// по цене сделки, продажа списывает долю позиции пропорционально проданному количеству.
+// Рыночная переоценка заменяет оценку позиции учтенной рыночной стоимостью инструмента.
+// Стоимость меняют только переоценки, доходы и комиссии.
+func valueAt(transactions []models.Transaction, t time.Time) float64 {
+	var cash float64
+	holdings := map[string]float64{}
+	quantities := map[string]float64{}
+	recognized := map[string]float64{}
+	for _, tx := range transactions {
+		if tx.Timestamp.After(t) {
+			break
+		}
+		if IsPriceMark(tx) {
+			recognized[tx.Ticker] += tx.Amount
+			holdings[tx.Ticker] = recognized[tx.Ticker]
+			continue
+		}
+		cash += BalanceEffect(tx)
+
+		switch tx.Type {
+		case "buy":
+			holdings[tx.Ticker] += tx.Amount
+			quantities[tx.Ticker] += tx.Quantity
+		case "sell":
+			held := quantities[tx.Ticker]
+			if tx.Quantity > 0 && held > quantityEpsilon {
+				share := math.Min(tx.Quantity/held, 1)
+				holdings[tx.Ticker] -= holdings[tx.Ticker] * share
+				quantities[tx.Ticker] = math.Max(held-tx.Quantity, 0)
+			} else {
+				// Без количества позиция уменьшается по цене сделки
+				holdings[tx.Ticker] -= math.Min(tx.Amount, math.Max(holdings[tx.Ticker], 0))
+			}
+		}
+	}
+
+	value := cash
+	for _, holding := range holdings {
+		value += holding
+	}
+	return value
+}
+
+// valuationDates даты оценки за период: начало, переоценки внутри периода и конец
+func valuationDates(transactions []models.Transaction, from, to time.Time) []time.Time {
+	dates := []time.Time{from}
+	for _, tx := range transactions {
+		if tx.Type == "revaluation" && tx.Timestamp.After(from) && tx.Timestamp.Before(to) {
+			dates = append(dates, tx.Timestamp)
+		}
+	}
+	return append(dates, to)
+}
+
+// AssetTWR считает доходность актива, взвешенную по времени, за период.
+// Точками оценки служат начало и конец периода и транзакции revaluation.
+func AssetTWR(transactions []models.Transaction, from, to time.Time) (float64, bool) {
+	transactions = SortTransactions(transactions)
+
+	// Оценка на начало — до операций в сам момент начала
+	startValue := valueAt(transactions, from.Add(-time.Nanosecond))
+	points := []ValuePoint{{Date: from, Value: startValue}}
+	for _, date := range valuationDates(transactions, from, to)[1:] {
+		points = append(points, ValuePoint{Date: date, Value: valueAt(transactions, date)})
+	}
+
+	var flows []ExternalFlow
+	for _, tx := range transactions {
+		if !IsExternalFlow(tx) || tx.Timestamp.Before(from) || tx.Timestamp.After(to) {
+			continue
+		}
+		// Поток в сам момент начала относим к первому подпериоду
+		date := tx.Timestamp
+		if !date.After(from) {
+			date = from.Add(time.Nanosecond)
+		}
+		// Комиссия по ноге перевода — расход актива, а не внешний поток
+		flows = append(flows, ExternalFlow{Date: date, Amount: BalanceChange(tx.Type, tx.Amount)})
+	}
+
+	return LinkedReturn(points, flows)
+}
+
+// PeriodRange возвращает границы стандартного периода относительно now.
+// Для inception началом служит дата первой операции.
+func PeriodRange(period string, now, inception time.Time) (time.Time, time.Time, error) {
+	switch period {
+	case models.PeriodMTD:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), now, nil
+	case models.PeriodQTD:
+		month := time.Month((int(now.Month())-1)/3*3 + 1)
+		return time.Date(now.Year(), month, 1, 0, 0, 0, 0, now.Location()), now, nil
+	case models.PeriodYTD:
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location()), now, nil
+	case models.Period1Y:
+		return now.AddDate(-1, 0, 0), now, nil
+	case models.PeriodInception:
+		if inception.IsZero() || inception.After(now) {
+			return now, now, nil
+		}
+		return inception, now, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%w: unsupported period %s", ErrInvalidPeriod, period)
+}
+
+// Performance считает доходность, взвешенную по времени, для каждого актива (в его валюте)
+// и для портфеля целиком (в базовой валюте). Если from и to заданы, period игнорируется.
+func (s *PortfolioService) Performance(ctx context.Context, userID, period string, from, to time.Time) (*models.PerformanceResponse, error) {
+	base, err := s.baseCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.storage.AssetsByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	byAsset := make([][]models.Transaction, len(assets))
+	var inception time.Time
+	for i, asset := range assets {
+		transactions, err := s.storage.GetTransactionsByAssetID(ctx, asset.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transactions for asset %s: %w", asset.ID, err)
+		}
+		byAsset[i] = SortTransactions(transactions)
+		if len(byAsset[i]) > 0 && (inception.IsZero() || byAsset[i][0].Timestamp.Before(inception)) {
+			inception = byAsset[i][0].Timestamp
+		}
+	}
+
+	if from.IsZero() || to.IsZero() {
+		from, to, err = PeriodRange(period, time.Now(), inception)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		period = models.PeriodCustom
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidPeriod)
+	}
+
+	response := &models.PerformanceResponse{
+		Period:       period,
+		From:         from,
+		To:           to,
+		BaseCurrency: base,
+		Assets:       make([]models.AssetPerformance, 0, len(assets)),
+	}
+
+	for i, asset := range assets {
+		perf := models.AssetPerformance{AssetID: asset.ID, Name: asset.Name, Currency: asset.Currency}
+		if twr, ok := AssetTWR(byAsset[i], from, to); ok {
+			perf.Twr = &twr
+		}
+		response.Assets = append(response.Assets, perf)
+	}
+
+	twr, ok, err := s.portfolioTWR(ctx, assets, byAsset, base, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		response.PortfolioTwr = &twr
+	}
+
+	return response, nil
+}
+
+// portfolioTWR доходность портфеля в базовой валюте: точки оценки — переоценки всех активов
+func (s *PortfolioService) portfolioTWR(ctx context.Context, assets []models.Asset, byAsset [][]models.Transaction, base string, from, to time.Time) (float64, bool, error) {
+	cache := map[rateKey]float64{}
+	approximate := map[rateKey]bool{}
+	convert := func(amount float64, currency string, date time.Time) (float64, error) {
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		rate, _, err := s.rateAt(ctx, cache, approximate, currency, base, day)
+		if err != nil {
+			return 0, err
+		}
+		return amount * rate, nil
+	}
+
+	var all []models.Transaction
+	for _, transactions := range byAsset {
+		all = append(all, transactions...)
+	}
+	dates := valuationDates(SortTransactions(all), from, to)
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	points := make([]ValuePoint, len(dates))
+	for i, date := range dates {
+		points[i].Date = date
+		at := date
+		if i == 0 {
+			at = date.Add(-time.Nanosecond)
+		}
+		for j, asset := range assets {
+			value, err := convert(valueAt(byAsset[j], at), asset.Currency, date)
+			if err != nil {
+				return 0, false, fmt.Errorf("failed to convert %s to %s: %w", asset.Currency, base, err)
+			}
+			points[i].Value += value
+		}
+	}
+
+	var flows []ExternalFlow
+	for j, asset := range assets {
+		// Переводы между активами для портфеля внешними потоками не являются
+		for _, tx := range WithoutTransfers(byAsset[j]) {
+			if !IsExternalFlow(tx) || tx.Timestamp.Before(from) || tx.Timestamp.After(to) {
+				continue
+			}
+			amount, err := convert(BalanceChange(tx.Type, tx.Amount), asset.Currency, tx.Timestamp)
+			if err != nil {
+				return 0, false, fmt.Errorf("failed to convert %s to %s: %w", asset.Currency, base, err)
+			}
+			date := tx.Timestamp
+			if !date.After(from) {
+				date = from.Add(time.Nanosecond)
+			}
+			flows = append(flows, ExternalFlow{Date: date, Amount: amount})
+		}
+	}
+
+	twr, ok := LinkedReturn(points, flows)
+	return twr, ok, nil
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/maksim77/goxirr"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+)
+
+// PortfolioService сервис для расчетов по портфелю пользователя целиком
+type PortfolioService struct {
+	storage  storage.Storage
+	exchange *ExchangeRateService
+}
+
+// NewPortfolioService создает новый сервис портфеля
+func NewPortfolioService(storage storage.Storage, exchange *ExchangeRateService) *PortfolioService {
+	return &PortfolioService{
+		storage:  storage,
+		exchange: exchange,
+	}
+}
+
+// baseCurrency возвращает базовую валюту пользователя
+func (s *PortfolioService) baseCurrency(ctx context.Context, userID string) (string, error) {
+	user, err := s.storage.UserByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.BaseCurrency == "" {
+		return "USD", nil
+	}
+	return user.BaseCurrency, nil
+}
+
+// convertAt конвертирует сумму по курсу на дату, а если его нет — по последнему курсу
+func (s *PortfolioService) convertAt(ctx context.Context, amount float64, from, to string, date time.Time) (float64, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	converted, err := s.exchange.ConvertAmount(ctx, amount, from, to, day)
+	if err == nil {
+		return converted, nil
+	}
+	return s.exchange.ConvertAmountLatest(ctx, amount, from, to)
+}
+
+// Summary считает стоимость, вложения, прибыль, распределение и XIRR портфеля в базовой валюте
+func (s *PortfolioService) Summary(ctx context.Context, userID string) (*models.PortfolioSummary, error) {
+	base, err := s.baseCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.storage.AssetsByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	summary := &models.PortfolioSummary{
+		BaseCurrency: base,
+		Assets:       []models.PortfolioAsset{},
+	}
+	byType := map[string]float64{}
+	byCurrency := map[string]float64{}
+	var cashflows goxirr.Transactions
+
+	for _, asset := range assets {
+		transactions, err := s.storage.GetTransactionsByAssetID(ctx, asset.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transactions for asset %s: %w", asset.ID, err)
+		}
+
+		value, err := s.exchange.ConvertAmountLatest(ctx, asset.Balance, asset.Currency, base)
+		if err != nil {
+			summary.MissingRates = append(summary.MissingRates, asset.Currency+"->"+base)
+			continue
+		}
+
+		// Потоки конвертируем по курсу на дату операции.
+		// Переводы между активами учитываются в показателях актива, но не портфеля.
+		var deposits, withdrawals, dividends float64
+		var externalDeposits, externalWithdrawals float64
+		for _, tx := range transactions {
+			converted, err := s.convertAt(ctx, tx.Amount, asset.Currency, base, tx.Timestamp)
+			if err != nil {
+				return nil, err
+			}
+			switch tx.Type {
+			case "deposit":
+				deposits += converted
+				if !IsTransferLeg(tx) {
+					externalDeposits += converted
+				}
+			case "withdrawal":
+				withdrawals += converted
+				if !IsTransferLeg(tx) {
+					externalWithdrawals += converted
+				}
+			case "dividend":
+				dividends += converted
+			}
+		}
+		portfolioMetrics := CalculateAssetMetrics(asset.Balance, WithoutTransfers(transactions))
+		for _, flow := range portfolioMetrics.Cashflows {
+			converted, err := s.convertAt(ctx, flow.Cash, asset.Currency, base, flow.Date)
+			if err != nil {
+				return nil, err
+			}
+			cashflows = append(cashflows, goxirr.Transaction{Date: flow.Date, Cash: converted})
+		}
+
+		profit := value - deposits + withdrawals + dividends
+
+		summary.Assets = append(summary.Assets, models.PortfolioAsset{
+			AssetID:  asset.ID,
+			Name:     asset.Name,
+			Type:     asset.Type,
+			Currency: asset.Currency,
+			Balance:  asset.Balance,
+			Value:    s.exchange.RoundAmount(value),
+			Invested: s.exchange.RoundAmount(deposits - withdrawals),
+			Profit:   s.exchange.RoundAmount(profit),
+		})
+
+		summary.TotalValue += value
+		summary.TotalDeposits += externalDeposits
+		summary.TotalWithdrawn += externalWithdrawals
+		summary.TotalDividends += dividends
+		summary.TotalProfit += value - externalDeposits + externalWithdrawals + dividends
+		byType[asset.Type] += value
+		byCurrency[asset.Currency] += value
+	}
+
+	summary.TotalInvested = summary.TotalDeposits - summary.TotalWithdrawn
+
+	for i := range summary.Assets {
+		summary.Assets[i].Share = share(summary.Assets[i].Value, summary.TotalValue)
+	}
+	summary.ByType = allocation(byType, summary.TotalValue)
+	summary.ByCurrency = allocation(byCurrency, summary.TotalValue)
+
+	// Текущая стоимость портфеля — завершающий положительный поток для XIRR
+	if len(cashflows) > 0 && summary.TotalValue != 0 {
+		cashflows = append(cashflows, goxirr.Transaction{Date: time.Now(), Cash: summary.TotalValue})
+	}
+	if xirr, ok := Xirr(cashflows); ok {
+		summary.Xirr = &xirr
+	}
+
+	summary.TotalValue = s.exchange.RoundAmount(summary.TotalValue)
+	summary.TotalInvested = s.exchange.RoundAmount(summary.TotalInvested)
+	summary.TotalDeposits = s.exchange.RoundAmount(summary.TotalDeposits)
+	summary.TotalWithdrawn = s.exchange.RoundAmount(summary.TotalWithdrawn)
+	summary.TotalDividends = s.exchange.RoundAmount(summary.TotalDividends)
+	summary.TotalProfit = s.exchange.RoundAmount(summary.TotalProfit)
+
+	return summary, nil
+}
+
+// share доля значения в итоге
+func share(value, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / total
+}
+
+// allocation раскладывает суммы по группам с долями, от большей к меньшей
+func allocation(groups map[string]float64, total float64) []models.AllocationEntry {
+	entries := make([]models.AllocationEntry, 0, len(groups))
+	for key, value := range groups {
+		entries = append(entries, models.AllocationEntry{
+			Key:   key,
+			Value: math.Round(value*100) / 100,
+			Share: share(value, total),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Value == entries[j].Value {
+			return entries[i].Key < entries[j].Key
+		}
+		return entries[i].Value > entries[j].Value
+	})
+	return entries
+}
+
+// MaxHistoryPoints ограничение на количество точек истории в одном запросе
+const MaxHistoryPoints = 1000
+
+// ErrInvalidPeriod неверный период или интервал
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// HistoryDates возвращает даты точек истории от from до to с заданным шагом.
+// Последней точкой всегда идет to.
+func HistoryDates(from, to time.Time, interval string) ([]time.Time, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidPeriod)
+	}
+
+	var dates []time.Time
+	for date := from; date.Before(to); {
+		dates = append(dates, date)
+		if len(dates) > MaxHistoryPoints {
+			return nil, fmt.Errorf("%w: too many points, maximum is %d", ErrInvalidPeriod, MaxHistoryPoints)
+		}
+
+		switch interval {
+		case models.HistoryIntervalDay:
+			date = date.AddDate(0, 0, 1)
+		case models.HistoryIntervalWeek:
+			date = date.AddDate(0, 0, 7)
+		case models.HistoryIntervalMonth:
+			date = date.AddDate(0, 1, 0)
+		default:
+			return nil, fmt.Errorf("%w: unsupported interval %s", ErrInvalidPeriod, interval)
+		}
+	}
+
+	return append(dates, to), nil
+}
+
+// rateKey ключ кеша курсов на дату
+type rateKey struct {
+	currency string
+	date     time.Time
+}
+
+// rateAt курс валюты к базовой на дату; если его нет — последний известный курс
+func (s *PortfolioService) rateAt(ctx context.Context, cache map[rateKey]float64, approximate map[rateKey]bool, currency, base string, date time.Time) (float64, bool, error) {
+	key := rateKey{currency: currency, date: date}
+	if rate, ok := cache[key]; ok {
+		return rate, approximate[key], nil
+	}
+
+	rate, err := s.exchange.GetExchangeRate(ctx, currency, base, date)
+	if err != nil {
+		rate, err = s.exchange.GetLatestExchangeRate(ctx, currency, base)
+		if err != nil {
+			return 0, false, err
+		}
+		approximate[key] = true
+	}
+
+	cache[key] = rate
+	return rate, approximate[key], nil
+}
+
+// History восстанавливает стоимость портфеля на каждую дату, проигрывая транзакции активов,
+// и конвертирует ее в базовую валюту по курсу на эту дату
+func (s *PortfolioService) History(ctx context.Context, userID string, from, to time.Time, interval string) (*models.PortfolioHistory, error) {
+	dates, err := HistoryDates(from, to, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := s.baseCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.storage.AssetsByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	history := &models.PortfolioHistory{
+		BaseCurrency: base,
+		Interval:     interval,
+		From:         from,
+		To:           to,
+		Points:       make([]models.HistoryPoint, len(dates)),
+	}
+	for i, date := range dates {
+		history.Points[i] = models.HistoryPoint{Date: date, Assets: []models.HistoryAssetValue{}}
+	}
+
+	cache := map[rateKey]float64{}
+	approximate := map[rateKey]bool{}
+	missing := map[string]bool{}
+
+	for _, asset := range assets {
+		transactions, err := s.storage.GetTransactionsByAssetID(ctx, asset.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transactions for asset %s: %w", asset.ID, err)
+		}
+		transactions = SortTransactions(transactions)
+
+		// Проигрываем транзакции по порядку, сдвигая указатель до конца дня каждой точки
+		var balance float64
+		next := 0
+		for i, date := range dates {
+			dayEnd := date.AddDate(0, 0, 1)
+			for next < len(transactions) && transactions[next].Timestamp.Before(dayEnd) {
+				balance += BalanceEffect(transactions[next])
+				next++
+			}
+
+			// Актив еще не существовал и по нему не было операций
+			if next == 0 && asset.CreatedAt.After(dayEnd) {
+				continue
+			}
+
+			rate, approx, err := s.rateAt(ctx, cache, approximate, asset.Currency, base, date)
+			if err != nil {
+				missing[asset.Currency+"->"+base] = true
+				break
+			}
+
+			value := balance * rate
+			history.Points[i].Assets = append(history.Points[i].Assets, models.HistoryAssetValue{
+				AssetID:     asset.ID,
+				Name:        asset.Name,
+				Currency:    asset.Currency,
+				Balance:     s.exchange.RoundAmount(balance),
+				Rate:        rate,
+				Value:       s.exchange.RoundAmount(value),
+				Approximate: approx,
+			})
+			history.Points[i].TotalValue += value
+		}
+	}
+
+	for i := range history.Points {
+		history.Points[i].TotalValue = s.exchange.RoundAmount(history.Points[i].TotalValue)
+	}
+	for pair := range missing {
+		history.MissingRates = append(history.MissingRates, pair)
+	}
+	sort.Strings(history.MissingRates)
+
+	return history, nil
+}
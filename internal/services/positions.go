@@ -0,0 +1,112 @@
+package services
+
+import (
+	"sort"
+
+	"brok/internal/models"
+)
+
+// quantityEpsilon порог, ниже которого остаток позиции считается нулевым
+const quantityEpsilon = 1e-9
+
+// TradeValue возвращает денежный объем сделки: сумму транзакции,
+// а если она не указана — количество, умноженное на цену
+func TradeValue(tx models.Transaction) float64 {
+	if tx.Amount != 0 {
+		return tx.Amount
+	}
+	return tx.Quantity * tx.Price
+}
+
+// IsTrade проверяет, является ли транзакция сделкой с количеством бумаг
+func IsTrade(tx models.Transaction) bool {
+	return (tx.Type == "buy" || tx.Type == "sell") && tx.Quantity > 0
+}
+
+// SortTransactions возвращает копию транзакций, упорядоченную по времени
+func SortTransactions(transactions []models.Transaction) []models.Transaction {
+	sorted := make([]models.Transaction, len(transactions))
+	copy(sorted, transactions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	return sorted
+}
+
+// CalculatePositions рассчитывает позиции по инструментам на основе лотов:
+// стоимость приобретения — сумма открытых лотов, реализованная прибыль — сумма закрытий
+func CalculatePositions(transactions []models.Transaction, matches []models.LotMatch) []models.Position {
+	lots, realized := ReplayLots(transactions, matches)
+
+	positions := map[string]*models.Position{}
+	var tickers []string
+	position := func(ticker string) *models.Position {
+		p, ok := positions[ticker]
+		if !ok {
+			p = &models.Position{Ticker: ticker}
+			positions[ticker] = p
+			tickers = append(tickers, ticker)
+		}
+		return p
+	}
+
+	// Последняя известная цена — цена последней сделки
+	for _, tx := range SortTransactions(transactions) {
+		if IsTrade(tx) {
+			position(tx.Ticker).LastPrice = TradeValue(tx) / tx.Quantity
+		}
+	}
+
+	for _, lot := range lots {
+		p := position(lot.Ticker)
+		p.Quantity += lot.Remaining
+		p.CostBasis += lot.CostBasis
+	}
+
+	for _, m := range realized {
+		position(m.Ticker).RealizedProfit += m.RealizedGain
+	}
+
+	result := make([]models.Position, 0, len(tickers))
+	for _, ticker := range tickers {
+		p := positions[ticker]
+		if p.Quantity < quantityEpsilon {
+			p.Quantity = 0
+			p.CostBasis = 0
+		}
+		if p.Quantity > 0 {
+			p.AverageCost = p.CostBasis / p.Quantity
+		}
+		p.MarketValue = p.Quantity * p.LastPrice
+		p.UnrealizedProfit = p.MarketValue - p.CostBasis
+		result = append(result, *p)
+	}
+
+	return result
+}
+
+// BalanceChange возвращает изменение баланса актива от транзакции
+func BalanceChange(txType string, amount float64) float64 {
+	switch txType {
+	case "deposit":
+		return amount
+	case "withdrawal":
+		return -amount
+	case "buy":
+		return -amount
+	case "sell":
+		return amount
+	case "revaluation":
+		return amount // может быть как +, так и -
+	case "dividend":
+		return amount
+	case "fee":
+		return -amount
+	}
+	return 0
+}
+
+// BalanceEffect возвращает изменение баланса актива от транзакции с учетом комиссии по ней
+func BalanceEffect(tx models.Transaction) float64 {
+	return BalanceChange(tx.Type, tx.Amount) - tx.Fee
+}
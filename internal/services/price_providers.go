@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"brok/config"
+)
+
+// Имена источников цен
+const (
+	PriceProviderMOEX = "moex"
+	PriceProviderFile = "file"
+)
+
+// PriceQuote цена закрытия инструмента за день
+type PriceQuote struct {
+	Symbol   string
+	Date     time.Time
+	Close    float64
+	Currency string
+	Provider string
+}
+
+// PriceProvider источник цен инструментов
+type PriceProvider interface {
+	// Name возвращает имя источника, которое сохраняется вместе с ценой
+	Name() string
+	// FetchPrices получает последние цены закрытия инструментов.
+	// Инструменты, которых источник не знает, в ответ не попадают.
+	FetchPrices(ctx context.Context, symbols []string) ([]PriceQuote, error)
+}
+
+// moexResponse ответ ISS Московской биржи: таблица securities в виде колонок и строк
+type moexResponse struct {
+	Securities struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"securities"`
+}
+
+// MOEXPriceProvider цены закрытия предыдущего дня с Московской биржи (ISS API)
+type MOEXPriceProvider struct {
+	client *http.Client
+	url    string
+}
+
+// NewMOEXPriceProvider создает источник цен Московской биржи; url — адрес securities.json режима торгов
+func NewMOEXPriceProvider(client *http.Client, url string) *MOEXPriceProvider {
+	return &MOEXPriceProvider{client: client, url: url}
+}
+
+// Name возвращает имя источника
+func (p *MOEXPriceProvider) Name() string {
+	return PriceProviderMOEX
+}
+
+// FetchPrices получает цены закрытия (PREVPRICE на PREVDATE) по списку инструментов
+func (p *MOEXPriceProvider) FetchPrices(ctx context.Context, symbols []string) ([]PriceQuote, error) {
+	query := url.Values{}
+	query.Set("iss.meta", "off")
+	query.Set("iss.only", "securities")
+	query.Set("securities.columns", "SECID,PREVPRICE,PREVDATE,CURRENCYID")
+	query.Set("securities", strings.Join(symbols, ","))
+
+	body, err := fetch(ctx, p.client, p.url+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	var resp moexResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range resp.Securities.Columns {
+		columns[column] = i
+	}
+	for _, column := range []string{"SECID", "PREVPRICE", "PREVDATE", "CURRENCYID"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("response has no %s column", column)
+		}
+	}
+
+	var quotes []PriceQuote
+	for _, row := range resp.Securities.Data {
+		if len(row) < len(resp.Securities.Columns) {
+			continue
+		}
+		symbol, _ := row[columns["SECID"]].(string)
+		closePrice, ok := row[columns["PREVPRICE"]].(float64)
+		if symbol == "" || !ok {
+			continue // нет торгов по инструменту
+		}
+		dateStr, _ := row[columns["PREVDATE"]].(string)
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date for %s: %w", symbol, err)
+		}
+
+		// Рубль в ISS обозначается устаревшим кодом SUR
+		currency, _ := row[columns["CURRENCYID"]].(string)
+		if currency == "SUR" || currency == "" {
+			currency = "RUB"
+		}
+
+		quotes = append(quotes, PriceQuote{Symbol: symbol, Date: date, Close: closePrice, Currency: currency, Provider: p.Name()})
+	}
+
+	return quotes, nil
+}
+
+// FilePriceProvider цены из локального CSV-файла с заголовком symbol,date,close,currency.
+// Отдаются все строки по запрошенным инструментам, так что файл годится и для загрузки истории.
+// Файл перечитывается при каждом запросе.
+type FilePriceProvider struct {
+	path string
+}
+
+// NewFilePriceProvider создает источник цен из файла
+func NewFilePriceProvider(path string) *FilePriceProvider {
+	return &FilePriceProvider{path: path}
+}
+
+// Name возвращает имя источника
+func (p *FilePriceProvider) Name() string {
+	return PriceProviderFile
+}
+
+// FetchPrices читает цены запрошенных инструментов из файла
+func (p *FilePriceProvider) FetchPrices(ctx context.Context, symbols []string) ([]PriceQuote, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prices file: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prices file: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("prices file is empty")
+	}
+
+	wanted := map[string]bool{}
+	for _, symbol := range symbols {
+		wanted[symbol] = true
+	}
+
+	var quotes []PriceQuote
+	for i, record := range records[1:] {
+		if len(record) != 4 {
+			return nil, fmt.Errorf("line %d: expected symbol,date,close,currency", i+2)
+		}
+		symbol := strings.ToUpper(strings.TrimSpace(record[0]))
+		if !wanted[symbol] {
+			continue
+		}
+
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date: %w", i+2, err)
+		}
+		closePrice, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil || closePrice < 0 {
+			return nil, fmt.Errorf("line %d: invalid close price", i+2)
+		}
+
+		quotes = append(quotes, PriceQuote{
+			Symbol:   symbol,
+			Date:     date,
+			Close:    closePrice,
+			Currency: strings.ToUpper(strings.TrimSpace(record[3])),
+			Provider: p.Name(),
+		})
+	}
+
+	return quotes, nil
+}
+
+// FailoverPriceProvider опрашивает источники по порядку; следующий источник
+// запрашивается только по инструментам, для которых предыдущие не вернули цену
+type FailoverPriceProvider struct {
+	providers []PriceProvider
+}
+
+// NewFailoverPriceProvider создает источник цен с переключением при ошибках
+func NewFailoverPriceProvider(providers ...PriceProvider) *FailoverPriceProvider {
+	return &FailoverPriceProvider{providers: providers}
+}
+
+// Name возвращает имена источников в порядке опроса
+func (p *FailoverPriceProvider) Name() string {
+	names := make([]string, len(p.providers))
+	for i, provider := range p.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// FetchPrices собирает цены из источников по порядку
+func (p *FailoverPriceProvider) FetchPrices(ctx context.Context, symbols []string) ([]PriceQuote, error) {
+	var quotes []PriceQuote
+	var errs []error
+	missing := symbols
+
+	for _, provider := range p.providers {
+		if len(missing) == 0 {
+			break
+		}
+
+		fetched, err := provider.FetchPrices(ctx, missing)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+		quotes = append(quotes, fetched...)
+
+		found := map[string]bool{}
+		for _, quote := range fetched {
+			found[quote.Symbol] = true
+		}
+		var rest []string
+		for _, symbol := range missing {
+			if !found[symbol] {
+				rest = append(rest, symbol)
+			}
+		}
+		missing = rest
+	}
+
+	// Ошибка возвращается, только если ни один источник ничего не дал
+	if len(quotes) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return quotes, nil
+}
+
+// NewPriceProviderFromEnv собирает источник цен по переменным окружения:
+//
+//	PRICE_PROVIDERS — источники через запятую в порядке опроса (по умолчанию none — цены не загружаются, источники включаются явно)
+//	MOEX_PRICES_URL — адрес securities.json режима торгов Московской биржи
+//	PRICE_FILE — путь к CSV-файлу для источника file
+//	PRICE_HTTP_TIMEOUT — таймаут HTTP-запросов (по умолчанию 10s)
+//
+// Возвращает nil, если загрузка цен отключена.
+func NewPriceProviderFromEnv() (PriceProvider, error) {
+	timeout, err := time.ParseDuration(config.GetEnv("PRICE_HTTP_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PRICE_HTTP_TIMEOUT: %w", err)
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var providers []PriceProvider
+	for _, name := range strings.Split(config.GetEnv("PRICE_PROVIDERS", "none"), ",") {
+		switch strings.TrimSpace(name) {
+		case PriceProviderMOEX:
+			providers = append(providers, NewMOEXPriceProvider(client,
+				config.GetEnv("MOEX_PRICES_URL", "https://iss.moex.com/iss/engines/stock/markets/shares/boards/TQBR/securities.json")))
+		case PriceProviderFile:
+			path := config.GetEnv("PRICE_FILE", "")
+			if path == "" {
+				return nil, errors.New("PRICE_FILE is required for the file provider")
+			}
+			providers = append(providers, NewFilePriceProvider(path))
+		case "none":
+			return nil, nil
+		case "":
+			continue
+		default:
+			return nil, fmt.Errorf("unknown price provider: %s", name)
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, nil
+	}
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return NewFailoverPriceProvider(providers...), nil
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMOEXPriceProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if got := query.Get("securities"); got != "SBER,GAZP,YNDX" {
+			t.Errorf("securities = %q, want SBER,GAZP,YNDX", got)
+		}
+		if got := query.Get("iss.only"); got != "securities" {
+			t.Errorf("iss.only = %q, want securities", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"securities": {
+			"columns": ["SECID", "PREVPRICE", "PREVDATE", "CURRENCYID"],
+			"data": [
+				["SBER", 301.5, "2024-03-01", "SUR"],
+				["GAZP", 160.25, "2024-03-01", "USD"],
+				["YNDX", null, "2024-03-01", "SUR"]
+			]
+		}}`))
+	}))
+	defer server.Close()
+
+	provider := NewMOEXPriceProvider(server.Client(), server.URL)
+	quotes, err := provider.FetchPrices(context.Background(), []string{"SBER", "GAZP", "YNDX"})
+	if err != nil {
+		t.Fatalf("FetchPrices: %v", err)
+	}
+	if len(quotes) != 2 {
+		t.Fatalf("FetchPrices returned %d quotes, want 2 (instrument without trades is skipped): %+v", len(quotes), quotes)
+	}
+
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	want := []PriceQuote{
+		{Symbol: "SBER", Date: date, Close: 301.5, Currency: "RUB", Provider: PriceProviderMOEX},
+		{Symbol: "GAZP", Date: date, Close: 160.25, Currency: "USD", Provider: PriceProviderMOEX},
+	}
+	for i := range want {
+		if quotes[i] != want[i] {
+			t.Errorf("quote %d = %+v, want %+v", i, quotes[i], want[i])
+		}
+	}
+}
+
+func TestMOEXPriceProviderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "missing column", status: http.StatusOK, body: `{"securities": {"columns": ["SECID", "PREVPRICE"], "data": []}}`, want: "PREVDATE"},
+		{name: "invalid json", status: http.StatusOK, body: `not json`, want: "failed to parse response"},
+		{name: "server error", status: http.StatusBadGateway, body: ``, want: "502"},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			_, _ = w.Write([]byte(tt.body))
+		}))
+
+		_, err := NewMOEXPriceProvider(server.Client(), server.URL).FetchPrices(context.Background(), []string{"SBER"})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want it to mention %q", tt.name, err, tt.want)
+		}
+		server.Close()
+	}
+}
+
+func TestFilePriceProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.csv")
+	content := "symbol,date,close,currency\n" +
+		"sber,2024-03-01,301.5,rub\n" +
+		"SBER,2024-03-04,305,RUB\n" +
+		"GAZP,2024-03-01,160,RUB\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	quotes, err := NewFilePriceProvider(path).FetchPrices(context.Background(), []string{"SBER"})
+	if err != nil {
+		t.Fatalf("FetchPrices: %v", err)
+	}
+	want := []PriceQuote{
+		{Symbol: "SBER", Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Close: 301.5, Currency: "RUB", Provider: PriceProviderFile},
+		{Symbol: "SBER", Date: time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC), Close: 305, Currency: "RUB", Provider: PriceProviderFile},
+	}
+	if len(quotes) != len(want) {
+		t.Fatalf("FetchPrices = %+v, want %+v", quotes, want)
+	}
+	for i := range want {
+		if quotes[i] != want[i] {
+			t.Errorf("quote %d = %+v, want %+v", i, quotes[i], want[i])
+		}
+	}
+
+	// Некорректная строка по запрошенному инструменту — ошибка с номером строки
+	if err := os.WriteFile(path, []byte("symbol,date,close,currency\nSBER,01.03.2024,301.5,RUB\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFilePriceProvider(path).FetchPrices(context.Background(), []string{"SBER"}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("FetchPrices with invalid date: error = %v, want line 2", err)
+	}
+
+	if _, err := NewFilePriceProvider(filepath.Join(t.TempDir(), "missing.csv")).FetchPrices(context.Background(), []string{"SBER"}); err == nil {
+		t.Error("FetchPrices with missing file: expected error")
+	}
+}
+
+// stubPriceProvider источник цен для тестов: отдает заданные цены или ошибку и запоминает запросы
+type stubPriceProvider struct {
+	name      string
+	prices    map[string]float64
+	err       error
+	requested [][]string
+}
+
+func (p *stubPriceProvider) Name() string {
+	return p.name
+}
+
+func (p *stubPriceProvider) FetchPrices(ctx context.Context, symbols []string) ([]PriceQuote, error) {
+	p.requested = append(p.requested, symbols)
+	if p.err != nil {
+		return nil, p.err
+	}
+	var quotes []PriceQuote
+	for _, symbol := range symbols {
+		if price, ok := p.prices[symbol]; ok {
+			quotes = append(quotes, PriceQuote{Symbol: symbol, Close: price, Currency: "RUB", Provider: p.name})
+		}
+	}
+	return quotes, nil
+}
+
+func TestFailoverPriceProvider(t *testing.T) {
+	failing := &stubPriceProvider{name: "down", err: errors.New("unavailable")}
+	partial := &stubPriceProvider{name: "partial", prices: map[string]float64{"SBER": 300}}
+	full := &stubPriceProvider{name: "full", prices: map[string]float64{"SBER": 1, "GAZP": 160}}
+
+	provider := NewFailoverPriceProvider(failing, partial, full)
+	if got := provider.Name(); got != "down,partial,full" {
+		t.Errorf("Name() = %q, want down,partial,full", got)
+	}
+
+	quotes, err := provider.FetchPrices(context.Background(), []string{"SBER", "GAZP", "LKOH"})
+	if err != nil {
+		t.Fatalf("FetchPrices: %v", err)
+	}
+	got := map[string]PriceQuote{}
+	for _, quote := range quotes {
+		got[quote.Symbol] = quote
+	}
+	if len(quotes) != 2 || got["SBER"].Provider != "partial" || got["GAZP"].Provider != "full" {
+		t.Errorf("FetchPrices = %+v, want SBER from partial and GAZP from full", quotes)
+	}
+
+	// Следующий источник запрашивается только по недостающим инструментам
+	if len(full.requested) != 1 || strings.Join(full.requested[0], ",") != "GAZP,LKOH" {
+		t.Errorf("full provider was asked for %v, want [[GAZP LKOH]]", full.requested)
+	}
+
+	// Ошибка возвращается, только если не ответил ни один источник
+	_, err = NewFailoverPriceProvider(failing, &stubPriceProvider{name: "down2", err: errors.New("timeout")}).
+		FetchPrices(context.Background(), []string{"SBER"})
+	if err == nil || !strings.Contains(err.Error(), "down: unavailable") || !strings.Contains(err.Error(), "down2: timeout") {
+		t.Errorf("FetchPrices with all providers down: error = %v, want both errors", err)
+	}
+}
+
+func TestNewPriceProviderFromEnv(t *testing.T) {
+	t.Setenv("PRICE_PROVIDERS", "")
+	provider, err := NewPriceProviderFromEnv()
+	if err != nil || provider != nil {
+		t.Errorf("default: provider = %v, err = %v, want price loading disabled", provider, err)
+	}
+
+	t.Setenv("PRICE_PROVIDERS", "moex")
+	provider, err = NewPriceProviderFromEnv()
+	if err != nil {
+		t.Fatalf("moex: %v", err)
+	}
+	if _, ok := provider.(*MOEXPriceProvider); !ok {
+		t.Errorf("moex: provider = %T, want *MOEXPriceProvider", provider)
+	}
+
+	t.Setenv("PRICE_PROVIDERS", "moex,file")
+	t.Setenv("PRICE_FILE", "")
+	if _, err := NewPriceProviderFromEnv(); err == nil {
+		t.Error("file without PRICE_FILE: expected error")
+	}
+
+	t.Setenv("PRICE_FILE", "prices.csv")
+	provider, err = NewPriceProviderFromEnv()
+	if err != nil {
+		t.Fatalf("moex,file: %v", err)
+	}
+	if got := provider.Name(); got != "moex,file" {
+		t.Errorf("moex,file: Name() = %q", got)
+	}
+
+	t.Setenv("PRICE_PROVIDERS", "yahoo")
+	if _, err := NewPriceProviderFromEnv(); err == nil {
+		t.Error("unknown provider: expected error")
+	}
+}
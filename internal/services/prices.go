@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+)
+
+var (
+	// ErrPriceNotFound для инструмента нет сохраненных цен
+	ErrPriceNotFound = errors.New("price not found")
+	// ErrAssetNotPriced актив не привязан к инструменту
+	ErrAssetNotPriced = errors.New("asset is not linked to a symbol")
+	// ErrNoPriceProvider загрузка цен отключена
+	ErrNoPriceProvider = errors.New("price provider is not configured")
+)
+
+// StalePriceAge возраст рыночной цены, после которого она считается устаревшей
+const StalePriceAge = 7 * 24 * time.Hour
+
+// PriceService загрузка цен инструментов и переоценка активов по рынку
+type PriceService struct {
+	storage  storage.Storage
+	provider PriceProvider // может быть nil, если загрузка цен отключена
+	exchange *ExchangeRateService
+}
+
+// NewPriceService создает новый сервис цен
+func NewPriceService(storage storage.Storage, provider PriceProvider, exchange *ExchangeRateService) *PriceService {
+	return &PriceService{
+		storage:  storage,
+		provider: provider,
+		exchange: exchange,
+	}
+}
+
+// IsPriceMark проверяет, создана ли переоценка по рыночной цене инструмента
+func IsPriceMark(tx models.Transaction) bool {
+	return tx.Type == "revaluation" && tx.Ticker != ""
+}
+
+// Refresh загружает цены и переоценивает все привязанные к инструментам активы
+func (s *PriceService) Refresh(ctx context.Context) (*models.PriceUpdateReport, error) {
+	report, err := s.UpdatePrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.storage.AssetsWithSymbol(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+	for _, asset := range assets {
+		result, err := s.MarkToMarket(ctx, asset)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("asset %s: %v", asset.ID, err))
+			continue
+		}
+		report.Marked = append(report.Marked, *result)
+	}
+
+	return report, nil
+}
+
+// UpdatePrices загружает последние цены закрытия инструментов, которые используются в активах и сделках
+func (s *PriceService) UpdatePrices(ctx context.Context) (*models.PriceUpdateReport, error) {
+	if s.provider == nil {
+		return nil, ErrNoPriceProvider
+	}
+
+	symbols, err := s.storage.PriceSymbols(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get symbols: %w", err)
+	}
+
+	report := &models.PriceUpdateReport{
+		Symbols: symbols,
+		Missing: []string{},
+		Marked:  []models.MarkToMarket{},
+	}
+	if len(symbols) == 0 {
+		return report, nil
+	}
+
+	quotes, err := s.provider.FetchPrices(ctx, symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+	}
+
+	found := map[string]bool{}
+	for _, quote := range quotes {
+		if !models.IsCurrencySupported(quote.Currency) {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: unsupported currency %s", quote.Symbol, quote.Currency))
+			continue
+		}
+
+		price := models.Price{
+			Symbol:   quote.Symbol,
+			Date:     quote.Date,
+			Close:    quote.Close,
+			Currency: quote.Currency,
+			Provider: quote.Provider,
+		}
+		if err := s.storage.SavePrice(ctx, price); err != nil {
+			return nil, fmt.Errorf("failed to save price %s: %w", quote.Symbol, err)
+		}
+		report.Saved++
+		found[quote.Symbol] = true
+	}
+
+	for _, symbol := range symbols {
+		if !found[symbol] {
+			report.Missing = append(report.Missing, symbol)
+		}
+	}
+
+	return report, nil
+}
+
+// LatestPrice последняя цена инструмента в валюте currency (по курсу на дату цены)
+func (s *PriceService) LatestPrice(ctx context.Context, symbol, currency string) (*models.Price, error) {
+	price, err := s.storage.GetLatestPrice(ctx, symbol)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrPriceNotFound, symbol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if price.Currency != currency {
+		rate, err := s.exchange.GetExchangeRate(ctx, price.Currency, currency, price.Date)
+		if err != nil {
+			return nil, err
+		}
+		price.Close *= rate
+		price.Currency = currency
+	}
+
+	return price, nil
+}
+
+// ApplyMarketPrices оценивает позиции по последним рыночным ценам вместо цены последней сделки.
+// Открытые позиции без сохраненной цены или курса остаются по цене сделки и, как и позиции
+// с ценой старше StalePriceAge, помечаются PriceStale. Ошибка по одной позиции не мешает
+// оценить остальные, ошибки возвращаются вместе.
+func (s *PriceService) ApplyMarketPrices(ctx context.Context, positions []models.Position, currency string) error {
+	var errs []error
+	for i := range positions {
+		p := &positions[i]
+		if p.Ticker == "" {
+			continue
+		}
+
+		price, err := s.LatestPrice(ctx, p.Ticker, currency)
+		if err != nil {
+			p.PriceStale = p.Quantity > 0
+			if !errors.Is(err, ErrPriceNotFound) && !errors.Is(err, ErrRateNotFound) {
+				errs = append(errs, fmt.Errorf("%s: %w", p.Ticker, err))
+			}
+			continue
+		}
+
+		date := price.Date
+		p.LastPrice = price.Close
+		p.PriceDate = &date
+		p.MarketValue = p.Quantity * p.LastPrice
+		p.UnrealizedProfit = p.MarketValue - p.CostBasis
+		p.PriceStale = p.Quantity > 0 && time.Since(date) > StalePriceAge
+	}
+	return errors.Join(errs...)
+}
+
+// MarkToMarket переоценивает актив по последней цене его инструмента: рыночная стоимость —
+// количество × цена, а переоценка записывается на разницу с уже учтенными рыночными переоценками.
+// Если стоимость не изменилась, транзакция не создается.
+func (s *PriceService) MarkToMarket(ctx context.Context, asset models.Asset) (*models.MarkToMarket, error) {
+	if asset.Symbol == nil {
+		return nil, ErrAssetNotPriced
+	}
+	symbol := *asset.Symbol
+
+	price, err := s.LatestPrice(ctx, symbol, asset.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *models.MarkToMarket
+	err = s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		// Блокируем актив, чтобы параллельная переоценка не учла разницу дважды
+		if _, err := s.storage.AssetBalanceForUpdateTx(ctx, tx, asset.ID); err != nil {
+			return err
+		}
+		transactions, err := s.storage.GetTransactionsByAssetIDTx(ctx, tx, asset.ID)
+		if err != nil {
+			return err
+		}
+		matches, err := s.storage.GetLotMatchesByAssetIDTx(ctx, tx, asset.ID)
+		if err != nil {
+			return err
+		}
+
+		var quantity float64
+		for _, p := range CalculatePositions(transactions, matches) {
+			if p.Ticker == symbol {
+				quantity = p.Quantity
+			}
+		}
+
+		var recognized float64
+		for _, t := range transactions {
+			if IsPriceMark(t) && t.Ticker == symbol {
+				recognized += t.Amount
+			}
+		}
+
+		marketValue := math.Round(quantity*price.Close*100) / 100
+		adjustment := math.Round((marketValue-recognized)*100) / 100
+		result = &models.MarkToMarket{
+			AssetID:     asset.ID,
+			Symbol:      symbol,
+			Quantity:    quantity,
+			Price:       price.Close,
+			PriceDate:   price.Date,
+			MarketValue: marketValue,
+			Adjustment:  adjustment,
+		}
+		if math.Abs(adjustment) < balanceTolerance {
+			result.Adjustment = 0
+			return nil
+		}
+
+		mark := models.Transaction{
+			ID:          uuid.New().String(),
+			AssetID:     asset.ID,
+			Amount:      adjustment,
+			Currency:    asset.Currency,
+			Type:        "revaluation",
+			Description: fmt.Sprintf("Переоценка %s по цене закрытия %s", symbol, price.Date.Format(time.DateOnly)),
+			Timestamp:   time.Now(),
+			Ticker:      symbol,
+			Quantity:    quantity,
+			Price:       price.Close,
+		}
+		if err := s.storage.CreateTransactionTx(ctx, tx, mark); err != nil {
+			return err
+		}
+		result.TransactionID = &mark.ID
+
+		return s.storage.UpdateAssetBalanceTx(ctx, tx, asset.ID, adjustment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,743 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"brok/config"
+	"brok/internal/models"
+)
+
+// Имена источников курсов
+const (
+	ProviderExchangeRateAPI = "exchangerate-api"
+	ProviderECB             = "ecb"
+	ProviderCBR             = "cbr"
+	ProviderFile            = "file"
+	ProviderCSV             = "csv" // импорт истории из файла
+)
+
+// RateSet курсы одной базовой валюты на дату: сколько целевой валюты за 1 базовую
+type RateSet struct {
+	Base     string
+	Date     time.Time
+	Rates    map[string]float64
+	Provider string
+}
+
+// RateProvider источник курсов валют
+type RateProvider interface {
+	// Name возвращает имя источника, которое сохраняется вместе с курсом
+	Name() string
+	// FetchRates получает актуальные курсы для базовой валюты
+	FetchRates(ctx context.Context, base string) (*RateSet, error)
+}
+
+// crossRates пересчитывает таблицу курсов с одной базовой валютой в курсы для другой.
+// table — сколько единиц валюты приходится на 1 единицу tableBase.
+func crossRates(provider string, date time.Time, tableBase string, table map[string]float64, base string) (*RateSet, error) {
+	rates := make(map[string]float64, len(table)+1)
+	for currency, rate := range table {
+		rates[currency] = rate
+	}
+	rates[tableBase] = 1
+
+	baseRate, ok := rates[base]
+	if !ok || baseRate == 0 {
+		return nil, fmt.Errorf("%s does not provide rates for %s", provider, base)
+	}
+
+	result := &RateSet{Base: base, Date: date, Rates: map[string]float64{}, Provider: provider}
+	for currency, rate := range rates {
+		if currency == base {
+			continue
+		}
+		result.Rates[currency] = rate / baseRate
+	}
+	return result, nil
+}
+
+// fetch выполняет GET-запрос и возвращает тело ответа
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+// ExchangeRateAPIResponse ответ от API курсов валют
+type ExchangeRateAPIResponse struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// ExchangeRateAPIProvider курсы с exchangerate-api.com (формат v4)
+type ExchangeRateAPIProvider struct {
+	client *http.Client
+	url    string
+}
+
+// NewExchangeRateAPIProvider создает источник exchangerate-api; url — префикс, к которому добавляется код валюты
+func NewExchangeRateAPIProvider(client *http.Client, url string) *ExchangeRateAPIProvider {
+	return &ExchangeRateAPIProvider{client: client, url: url}
+}
+
+// Name возвращает имя источника
+func (p *ExchangeRateAPIProvider) Name() string {
+	return ProviderExchangeRateAPI
+}
+
+// FetchRates получает курсы для базовой валюты
+func (p *ExchangeRateAPIProvider) FetchRates(ctx context.Context, base string) (*RateSet, error) {
+	body, err := fetch(ctx, p.client, p.url+base)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp ExchangeRateAPIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	date, err := time.Parse("2006-01-02", apiResp.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %w", err)
+	}
+
+	return &RateSet{Base: base, Date: date, Rates: apiResp.Rates, Provider: p.Name()}, nil
+}
+
+// ecbEnvelope ежедневные курсы ЕЦБ (eurofxref-daily.xml)
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ECBProvider курсы Европейского центрального банка (базовая валюта — EUR)
+type ECBProvider struct {
+	client *http.Client
+	url    string
+}
+
+// NewECBProvider создает источник ЕЦБ
+func NewECBProvider(client *http.Client, url string) *ECBProvider {
+	return &ECBProvider{client: client, url: url}
+}
+
+// Name возвращает имя источника
+func (p *ECBProvider) Name() string {
+	return ProviderECB
+}
+
+// FetchRates получает курсы ЕЦБ и пересчитывает их для базовой валюты
+func (p *ECBProvider) FetchRates(ctx context.Context, base string) (*RateSet, error) {
+	body, err := fetch(ctx, p.client, p.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(envelope.Days) == 0 {
+		return nil, errors.New("ECB response contains no rates")
+	}
+
+	// В ежедневном файле одна дата
+	day := envelope.Days[0]
+	date, err := time.Parse("2006-01-02", day.Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %w", err)
+	}
+
+	table := make(map[string]float64, len(day.Rates))
+	for _, r := range day.Rates {
+		table[r.Currency] = r.Rate
+	}
+
+	return crossRates(p.Name(), date, "EUR", table, base)
+}
+
+// cbrValCurs ежедневные курсы ЦБ РФ (XML_daily.asp)
+type cbrValCurs struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// CBRProvider курсы Центрального банка РФ (базовая валюта — RUB)
+type CBRProvider struct {
+	client *http.Client
+	url    string
+}
+
+// NewCBRProvider создает источник ЦБ РФ
+func NewCBRProvider(client *http.Client, url string) *CBRProvider {
+	return &CBRProvider{client: client, url: url}
+}
+
+// Name возвращает имя источника
+func (p *CBRProvider) Name() string {
+	return ProviderCBR
+}
+
+// parseCBRNumber разбирает число с запятой в качестве разделителя
+func parseCBRNumber(s string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), 64)
+}
+
+// FetchRates получает курсы ЦБ РФ и пересчитывает их для базовой валюты
+func (p *CBRProvider) FetchRates(ctx context.Context, base string) (*RateSet, error) {
+	table, err := p.fetchTable(ctx, p.url)
+	if err != nil {
+		return nil, err
+	}
+	return crossRates(p.Name(), table.Date, table.Base, table.Rates, base)
+}
+
+// decodeCBR разбирает XML ЦБ РФ, который отдается в windows-1251
+func decodeCBR(body []byte, v any) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "windows-1251") {
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset: %s", charset)
+	}
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// fetchTable загружает таблицу курсов ЦБ РФ к рублю
+func (p *CBRProvider) fetchTable(ctx context.Context, url string) (*RateSet, error) {
+	body, err := fetch(ctx, p.client, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var valCurs cbrValCurs
+	if err := decodeCBR(body, &valCurs); err != nil {
+		return nil, err
+	}
+
+	date, err := time.Parse("02.01.2006", valCurs.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %w", err)
+	}
+
+	// Value — сколько рублей стоит Nominal единиц валюты; переводим в единицы валюты за 1 рубль
+	table := &RateSet{Base: "RUB", Date: date, Rates: map[string]float64{}, Provider: p.Name()}
+	for _, v := range valCurs.Valutes {
+		nominal, err := parseCBRNumber(v.Nominal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse nominal for %s: %w", v.CharCode, err)
+		}
+		value, err := parseCBRNumber(v.Value)
+		if err != nil || value == 0 {
+			return nil, fmt.Errorf("failed to parse value for %s: %v", v.CharCode, err)
+		}
+		table.Rates[v.CharCode] = nominal / value
+	}
+
+	return table, nil
+}
+
+// FileProvider курсы из локального JSON-файла в формате exchangerate-api
+// ({"base": "USD", "date": "2024-01-15", "rates": {...}}). Файл перечитывается при каждом запросе.
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider создает источник курсов из файла
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Name возвращает имя источника
+func (p *FileProvider) Name() string {
+	return ProviderFile
+}
+
+// FetchRates читает курсы из файла и пересчитывает их для базовой валюты
+func (p *FileProvider) FetchRates(ctx context.Context, base string) (*RateSet, error) {
+	body, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var fileRates ExchangeRateAPIResponse
+	if err := json.Unmarshal(body, &fileRates); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+
+	date, err := time.Parse("2006-01-02", fileRates.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %w", err)
+	}
+
+	return crossRates(p.Name(), date, fileRates.Base, fileRates.Rates, base)
+}
+
+// FailoverProvider опрашивает источники по порядку и возвращает первый успешный ответ
+type FailoverProvider struct {
+	providers []RateProvider
+}
+
+// NewFailoverProvider создает источник с переключением при ошибках
+func NewFailoverProvider(providers ...RateProvider) *FailoverProvider {
+	return &FailoverProvider{providers: providers}
+}
+
+// Name возвращает имена источников в порядке опроса
+func (p *FailoverProvider) Name() string {
+	names := make([]string, len(p.providers))
+	for i, provider := range p.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// FetchRates получает курсы из первого доступного источника
+func (p *FailoverProvider) FetchRates(ctx context.Context, base string) (*RateSet, error) {
+	var errs []error
+	for _, provider := range p.providers {
+		rates, err := provider.FetchRates(ctx, base)
+		if err == nil {
+			return rates, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// NewRateProviderFromEnv собирает источник курсов по переменным окружения:
+//
+//	EXCHANGE_RATE_PROVIDERS — источники через запятую в порядке опроса (по умолчанию exchangerate-api)
+//	EXCHANGE_RATE_API_URL, ECB_RATES_URL, CBR_RATES_URL — адреса источников
+//	EXCHANGE_RATE_FILE — путь к файлу для источника file
+//	EXCHANGE_RATE_HTTP_TIMEOUT — таймаут HTTP-запросов (по умолчанию 10s)
+func NewRateProviderFromEnv() (RateProvider, error) {
+	timeout, err := time.ParseDuration(config.GetEnv("EXCHANGE_RATE_HTTP_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXCHANGE_RATE_HTTP_TIMEOUT: %w", err)
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var providers []RateProvider
+	for _, name := range strings.Split(config.GetEnv("EXCHANGE_RATE_PROVIDERS", ProviderExchangeRateAPI), ",") {
+		switch strings.TrimSpace(name) {
+		case ProviderExchangeRateAPI:
+			providers = append(providers, NewExchangeRateAPIProvider(client,
+				config.GetEnv("EXCHANGE_RATE_API_URL", "https://api.exchangerate-api.com/v4/latest/")))
+		case ProviderECB:
+			providers = append(providers, NewECBProvider(client,
+				config.GetEnv("ECB_RATES_URL", "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml")))
+		case ProviderCBR:
+			providers = append(providers, NewCBRProvider(client,
+				config.GetEnv("CBR_RATES_URL", "https://www.cbr.ru/scripts/XML_daily.asp")))
+		case ProviderFile:
+			path := config.GetEnv("EXCHANGE_RATE_FILE", "")
+			if path == "" {
+				return nil, errors.New("EXCHANGE_RATE_FILE is required for the file provider")
+			}
+			providers = append(providers, NewFileProvider(path))
+		case "":
+			continue
+		default:
+			return nil, fmt.Errorf("unknown exchange rate provider: %s", name)
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, errors.New("no exchange rate providers configured")
+	}
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return NewFailoverProvider(providers...), nil
+}
+
+// HistoricalRateProvider источник, умеющий отдавать курсы за прошлые даты
+type HistoricalRateProvider interface {
+	// Name возвращает имя источника, которое сохраняется вместе с курсом
+	Name() string
+	// FetchHistory возвращает таблицы курсов за каждый доступный день периода
+	// в собственной базовой валюте источника
+	FetchHistory(ctx context.Context, from, to time.Time) ([]*RateSet, error)
+}
+
+// ecbTables переводит дни ЕЦБ в таблицы курсов к евро, отбирая даты периода
+func ecbTables(provider string, envelope ecbEnvelope, from, to time.Time) ([]*RateSet, error) {
+	var tables []*RateSet
+	for _, day := range envelope.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date: %w", err)
+		}
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+
+		table := &RateSet{Base: "EUR", Date: date, Rates: map[string]float64{}, Provider: provider}
+		for _, r := range day.Rates {
+			table.Rates[r.Currency] = r.Rate
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// ECBHistoryProvider полная история курсов ЕЦБ (eurofxref-hist.xml)
+type ECBHistoryProvider struct {
+	client *http.Client
+	url    string
+}
+
+// NewECBHistoryProvider создает источник истории курсов ЕЦБ
+func NewECBHistoryProvider(client *http.Client, url string) *ECBHistoryProvider {
+	return &ECBHistoryProvider{client: client, url: url}
+}
+
+// Name возвращает имя источника
+func (p *ECBHistoryProvider) Name() string {
+	return ProviderECB
+}
+
+// FetchHistory загружает историю ЕЦБ и отбирает дни периода
+func (p *ECBHistoryProvider) FetchHistory(ctx context.Context, from, to time.Time) ([]*RateSet, error) {
+	body, err := fetch(ctx, p.client, p.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return ecbTables(p.Name(), envelope, from, to)
+}
+
+// cbrValFull справочник валют ЦБ РФ (XML_valFull.asp)
+type cbrValFull struct {
+	Items []struct {
+		ID       string `xml:"ID,attr"`
+		CharCode string `xml:"ISO_Char_Code"`
+	} `xml:"Item"`
+}
+
+// cbrDynamic динамика курса одной валюты ЦБ РФ за период (XML_dynamic.asp)
+type cbrDynamic struct {
+	Records []struct {
+		Date    string `xml:"Date,attr"`
+		Nominal string `xml:"Nominal"`
+		Value   string `xml:"Value"`
+	} `xml:"Record"`
+}
+
+// CBRHistoryProvider история курсов ЦБ РФ: динамика каждой валюты запрашивается
+// за весь период одним запросом, поэтому число запросов не зависит от длины периода.
+// ЦБ публикует курсы только на рабочие дни, выходных и праздников в ответе нет.
+type CBRHistoryProvider struct {
+	client     *http.Client
+	dynamicURL string
+	codesURL   string
+}
+
+// NewCBRHistoryProvider создает источник истории курсов ЦБ РФ
+func NewCBRHistoryProvider(client *http.Client, dynamicURL, codesURL string) *CBRHistoryProvider {
+	return &CBRHistoryProvider{client: client, dynamicURL: dynamicURL, codesURL: codesURL}
+}
+
+// Name возвращает имя источника
+func (p *CBRHistoryProvider) Name() string {
+	return ProviderCBR
+}
+
+// codes загружает внутренние коды ЦБ для поддерживаемых валют
+func (p *CBRHistoryProvider) codes(ctx context.Context) (map[string]string, error) {
+	body, err := fetch(ctx, p.client, p.codesURL)
+	if err != nil {
+		return nil, err
+	}
+	var valFull cbrValFull
+	if err := decodeCBR(body, &valFull); err != nil {
+		return nil, err
+	}
+
+	codes := map[string]string{}
+	for _, item := range valFull.Items {
+		code := strings.TrimSpace(item.CharCode)
+		if _, ok := codes[code]; !ok && models.IsCurrencySupported(code) {
+			codes[code] = strings.TrimSpace(item.ID)
+		}
+	}
+	return codes, nil
+}
+
+// FetchHistory запрашивает динамику курсов поддерживаемых валют за период
+// и собирает из нее таблицы курсов к рублю по дням публикации
+func (p *CBRHistoryProvider) FetchHistory(ctx context.Context, from, to time.Time) ([]*RateSet, error) {
+	codes, err := p.codes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get currency codes: %w", err)
+	}
+
+	byDate := map[time.Time]*RateSet{}
+	for currency, id := range codes {
+		url := p.dynamicURL
+		if strings.Contains(url, "?") {
+			url += "&"
+		} else {
+			url += "?"
+		}
+		url += "date_req1=" + from.Format("02/01/2006") + "&date_req2=" + to.Format("02/01/2006") + "&VAL_NM_RQ=" + id
+
+		body, err := fetch(ctx, p.client, url)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", currency, err)
+		}
+		var dynamic cbrDynamic
+		if err := decodeCBR(body, &dynamic); err != nil {
+			return nil, fmt.Errorf("%s: %w", currency, err)
+		}
+
+		for _, record := range dynamic.Records {
+			date, err := time.Parse("02.01.2006", record.Date)
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to parse date: %w", currency, err)
+			}
+			if date.Before(from) || date.After(to) {
+				continue
+			}
+			nominal, err := parseCBRNumber(record.Nominal)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse nominal for %s: %w", currency, err)
+			}
+			value, err := parseCBRNumber(record.Value)
+			if err != nil || value == 0 {
+				return nil, fmt.Errorf("failed to parse value for %s: %v", currency, err)
+			}
+
+			table, ok := byDate[date]
+			if !ok {
+				table = &RateSet{Base: "RUB", Date: date, Rates: map[string]float64{}, Provider: p.Name()}
+				byDate[date] = table
+			}
+			// Value — сколько рублей стоит Nominal единиц валюты
+			table.Rates[currency] = nominal / value
+		}
+	}
+
+	tables := make([]*RateSet, 0, len(byDate))
+	for _, table := range byDate {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Date.Before(tables[j].Date) })
+	return tables, nil
+}
+
+// HistoricalFailoverProvider опрашивает источники истории по порядку
+type HistoricalFailoverProvider struct {
+	providers []HistoricalRateProvider
+}
+
+// NewHistoricalFailoverProvider создает источник истории с переключением при ошибках
+func NewHistoricalFailoverProvider(providers ...HistoricalRateProvider) *HistoricalFailoverProvider {
+	return &HistoricalFailoverProvider{providers: providers}
+}
+
+// Name возвращает имена источников в порядке опроса
+func (p *HistoricalFailoverProvider) Name() string {
+	names := make([]string, len(p.providers))
+	for i, provider := range p.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// FetchHistory получает историю из первого доступного источника
+func (p *HistoricalFailoverProvider) FetchHistory(ctx context.Context, from, to time.Time) ([]*RateSet, error) {
+	var errs []error
+	for _, provider := range p.providers {
+		tables, err := provider.FetchHistory(ctx, from, to)
+		if err == nil {
+			return tables, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// NewHistoricalRateProviderFromEnv собирает источник истории курсов из тех источников
+// EXCHANGE_RATE_PROVIDERS, которые ее поддерживают (ecb, cbr). Адрес истории ЕЦБ — ECB_HISTORY_URL,
+// ЦБ РФ — CBR_HISTORY_URL (динамика курса) и CBR_CODES_URL (справочник кодов валют).
+// Возвращает nil, если ни один из настроенных источников историю не отдает.
+func NewHistoricalRateProviderFromEnv() (HistoricalRateProvider, error) {
+	timeout, err := time.ParseDuration(config.GetEnv("EXCHANGE_RATE_HTTP_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXCHANGE_RATE_HTTP_TIMEOUT: %w", err)
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var providers []HistoricalRateProvider
+	for _, name := range strings.Split(config.GetEnv("EXCHANGE_RATE_PROVIDERS", ProviderExchangeRateAPI), ",") {
+		switch strings.TrimSpace(name) {
+		case ProviderECB:
+			providers = append(providers, NewECBHistoryProvider(client,
+				config.GetEnv("ECB_HISTORY_URL", "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml")))
+		case ProviderCBR:
+			providers = append(providers, NewCBRHistoryProvider(client,
+				config.GetEnv("CBR_HISTORY_URL", "https://www.cbr.ru/scripts/XML_dynamic.asp"),
+				config.GetEnv("CBR_CODES_URL", "https://www.cbr.ru/scripts/XML_valFull.asp")))
+		}
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return providers[0], nil
+	}
+	return NewHistoricalFailoverProvider(providers...), nil
+}
+
+// ParseRateHistoryCSV разбирает файл истории курсов. Поддерживаются два формата:
+//   - date,from_currency,to_currency,rate — по строке на курс;
+//   - выгрузка ЕЦБ eurofxref-hist.csv: Date,USD,JPY,... — курсы к евро, N/A для пропусков.
+func ParseRateHistoryCSV(r io.Reader, provider string) ([]*RateSet, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	if len(header) >= 4 && strings.EqualFold(header[1], "from_currency") {
+		return parsePairsCSV(records, provider)
+	}
+	if len(header) >= 2 && strings.EqualFold(header[0], "date") {
+		return parseECBHistoryCSV(header, records, provider)
+	}
+	return nil, errors.New("unknown CSV format: expected date,from_currency,to_currency,rate or ECB history header")
+}
+
+// parsePairsCSV строки date,from_currency,to_currency,rate
+func parsePairsCSV(records [][]string, provider string) ([]*RateSet, error) {
+	type key struct {
+		date time.Time
+		base string
+	}
+	tables := map[key]*RateSet{}
+	var order []key
+
+	for i, record := range records {
+		if len(record) < 4 {
+			return nil, fmt.Errorf("row %d: expected 4 columns", i+2)
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid date: %w", i+2, err)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("row %d: invalid rate %q", i+2, record[3])
+		}
+
+		k := key{date: date, base: strings.ToUpper(strings.TrimSpace(record[1]))}
+		table, ok := tables[k]
+		if !ok {
+			table = &RateSet{Base: k.base, Date: date, Rates: map[string]float64{}, Provider: provider}
+			tables[k] = table
+			order = append(order, k)
+		}
+		table.Rates[strings.ToUpper(strings.TrimSpace(record[2]))] = rate
+	}
+
+	result := make([]*RateSet, 0, len(order))
+	for _, k := range order {
+		result = append(result, tables[k])
+	}
+	return result, nil
+}
+
+// parseECBHistoryCSV выгрузка ЕЦБ: дата и курсы к евро по столбцам
+func parseECBHistoryCSV(header []string, records [][]string, provider string) ([]*RateSet, error) {
+	var tables []*RateSet
+	for i, record := range records {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid date: %w", i+2, err)
+		}
+
+		table := &RateSet{Base: "EUR", Date: date, Rates: map[string]float64{}, Provider: provider}
+		for j := 1; j < len(record) && j < len(header); j++ {
+			value := strings.TrimSpace(record[j])
+			if header[j] == "" || value == "" || value == "N/A" {
+				continue
+			}
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid rate for %s: %w", i+2, header[j], err)
+			}
+			table.Rates[header[j]] = rate
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Ответы источников, записанные с реальных адресов (сокращены до нескольких валют)
+const (
+	exchangeRateAPIPayload = `{"provider":"https://www.exchangerate-api.com","base":"USD","date":"2024-03-01",` +
+		`"time_last_updated":1709251201,"rates":{"USD":1,"EUR":0.924,"RUB":91.2,"JPY":150.1}}`
+
+	ecbDailyPayload = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time='2024-03-01'>
+			<Cube currency='USD' rate='1.0830'/>
+			<Cube currency='JPY' rate='162.45'/>
+			<Cube currency='GBP' rate='0.85553'/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+	cbrDailyPayload = `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs Date="01.03.2024" name="Foreign Currency Market">
+	<Valute ID="R01235">
+		<NumCode>840</NumCode>
+		<CharCode>USD</CharCode>
+		<Nominal>1</Nominal>
+		<Name>Доллар США</Name>
+		<Value>91,3336</Value>
+		<VunitRate>91,3336</VunitRate>
+	</Valute>
+	<Valute ID="R01820">
+		<NumCode>392</NumCode>
+		<CharCode>JPY</CharCode>
+		<Nominal>100</Nominal>
+		<Name>Японских иен</Name>
+		<Value>60,8707</Value>
+		<VunitRate>0,608707</VunitRate>
+	</Valute>
+</ValCurs>`
+
+	cbrCodesPayload = `<?xml version="1.0" encoding="windows-1251"?>
+<Valuta name="Foreign Currency Market Lib">
+	<Item ID="R01235">
+		<Name>Доллар США</Name>
+		<EngName>US Dollar</EngName>
+		<Nominal>1</Nominal>
+		<ParentCode>R01235    </ParentCode>
+		<ISO_Num_Code>840</ISO_Num_Code>
+		<ISO_Char_Code>USD</ISO_Char_Code>
+	</Item>
+	<Item ID="R01820">
+		<Name>Японская иена</Name>
+		<EngName>Japanese Yen</EngName>
+		<Nominal>100</Nominal>
+		<ParentCode>R01820    </ParentCode>
+		<ISO_Num_Code>392</ISO_Num_Code>
+		<ISO_Char_Code>JPY</ISO_Char_Code>
+	</Item>
+	<Item ID="R01090B">
+		<Name>Белорусский рубль</Name>
+		<EngName>Belarussian Ruble</EngName>
+		<Nominal>1</Nominal>
+		<ParentCode>R01090    </ParentCode>
+		<ISO_Num_Code>933</ISO_Num_Code>
+		<ISO_Char_Code>BYN</ISO_Char_Code>
+	</Item>
+</Valuta>`
+
+	cbrDynamicUSDPayload = `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs ID="R01235" DateRange1="29.02.2024" DateRange2="04.03.2024" name="Foreign Currency Market Dynamic">
+	<Record Date="29.02.2024" Id="R01235"><Nominal>1</Nominal><Value>91,1174</Value><VunitRate>91,1174</VunitRate></Record>
+	<Record Date="01.03.2024" Id="R01235"><Nominal>1</Nominal><Value>91,3336</Value><VunitRate>91,3336</VunitRate></Record>
+	<Record Date="02.03.2024" Id="R01235"><Nominal>1</Nominal><Value>91,6359</Value><VunitRate>91,6359</VunitRate></Record>
+</ValCurs>`
+
+	cbrDynamicJPYPayload = `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs ID="R01820" DateRange1="29.02.2024" DateRange2="04.03.2024" name="Foreign Currency Market Dynamic">
+	<Record Date="01.03.2024" Id="R01820"><Nominal>100</Nominal><Value>60,8707</Value><VunitRate>0,608707</VunitRate></Record>
+	<Record Date="02.03.2024" Id="R01820"><Nominal>100</Nominal><Value>61,0420</Value><VunitRate>0,61042</VunitRate></Record>
+</ValCurs>`
+)
+
+// windows1251 кодирует ответ ЦБ РФ так, как его отдает сервер
+func windows1251(t *testing.T, s string) []byte {
+	t.Helper()
+	encoded, err := charmap.Windows1251.NewEncoder().String(s)
+	if err != nil {
+		t.Fatalf("failed to encode payload: %v", err)
+	}
+	return []byte(encoded)
+}
+
+// serve запускает заглушку источника, отдающую body с кодом status
+func serve(t *testing.T, status int, body []byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// assertRates сравнивает курсы с точностью до относительной погрешности вычислений
+func assertRates(t *testing.T, name string, got, want map[string]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: rates = %v, want %v", name, got, want)
+		return
+	}
+	for currency, rate := range want {
+		if math.Abs(got[currency]-rate) > 1e-9*math.Max(1, math.Abs(rate)) {
+			t.Errorf("%s: rate %s = %v, want %v", name, currency, got[currency], rate)
+		}
+	}
+}
+
+func TestRateProviders(t *testing.T) {
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		provider func(url string) RateProvider
+		path     string // путь, который должен запросить источник
+		body     []byte
+		base     string
+		want     map[string]float64
+	}{
+		{
+			name: "exchangerate-api",
+			provider: func(url string) RateProvider {
+				return NewExchangeRateAPIProvider(http.DefaultClient, url+"/v4/latest/")
+			},
+			path: "/v4/latest/USD",
+			body: []byte(exchangeRateAPIPayload),
+			base: "USD",
+			want: map[string]float64{"USD": 1, "EUR": 0.924, "RUB": 91.2, "JPY": 150.1},
+		},
+		{
+			name:     "ecb in its own base",
+			provider: func(url string) RateProvider { return NewECBProvider(http.DefaultClient, url+"/eurofxref-daily.xml") },
+			path:     "/eurofxref-daily.xml",
+			body:     []byte(ecbDailyPayload),
+			base:     "EUR",
+			want:     map[string]float64{"USD": 1.0830, "JPY": 162.45, "GBP": 0.85553},
+		},
+		{
+			name:     "ecb cross rates through EUR",
+			provider: func(url string) RateProvider { return NewECBProvider(http.DefaultClient, url+"/eurofxref-daily.xml") },
+			path:     "/eurofxref-daily.xml",
+			body:     []byte(ecbDailyPayload),
+			base:     "USD",
+			want:     map[string]float64{"EUR": 1 / 1.0830, "JPY": 162.45 / 1.0830, "GBP": 0.85553 / 1.0830},
+		},
+		{
+			name:     "cbr with nominal 100",
+			provider: func(url string) RateProvider { return NewCBRProvider(http.DefaultClient, url+"/scripts/XML_daily.asp") },
+			path:     "/scripts/XML_daily.asp",
+			body:     windows1251(t, cbrDailyPayload),
+			base:     "RUB",
+			want:     map[string]float64{"USD": 1 / 91.3336, "JPY": 100 / 60.8707},
+		},
+		{
+			name:     "cbr cross rates through RUB",
+			provider: func(url string) RateProvider { return NewCBRProvider(http.DefaultClient, url+"/scripts/XML_daily.asp") },
+			path:     "/scripts/XML_daily.asp",
+			body:     windows1251(t, cbrDailyPayload),
+			base:     "USD",
+			want:     map[string]float64{"RUB": 91.3336, "JPY": 100 * 91.3336 / 60.8707},
+		},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != tt.path {
+				t.Errorf("%s: requested %s, want %s", tt.name, r.URL.Path, tt.path)
+			}
+			_, _ = w.Write(tt.body)
+		}))
+
+		rates, err := tt.provider(server.URL).FetchRates(context.Background(), tt.base)
+		server.Close()
+		if err != nil {
+			t.Errorf("%s: FetchRates: %v", tt.name, err)
+			continue
+		}
+		if rates.Base != tt.base || !rates.Date.Equal(date) {
+			t.Errorf("%s: base %s date %s, want %s %s", tt.name, rates.Base, rates.Date, tt.base, date)
+		}
+		assertRates(t, tt.name, rates.Rates, tt.want)
+	}
+}
+
+func TestRateProviderErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider func(url string) RateProvider
+		status   int
+		body     []byte
+		base     string
+		want     string
+	}{
+		{
+			name:     "server error",
+			provider: func(url string) RateProvider { return NewExchangeRateAPIProvider(http.DefaultClient, url+"/") },
+			status:   http.StatusInternalServerError,
+			base:     "USD",
+			want:     "status: 500",
+		},
+		{
+			name:     "ecb without the requested base",
+			provider: func(url string) RateProvider { return NewECBProvider(http.DefaultClient, url) },
+			status:   http.StatusOK,
+			body:     []byte(ecbDailyPayload),
+			base:     "KRW",
+			want:     "does not provide rates for KRW",
+		},
+		{
+			name:     "ecb empty response",
+			provider: func(url string) RateProvider { return NewECBProvider(http.DefaultClient, url) },
+			status:   http.StatusOK,
+			body:     []byte(`<Envelope><Cube></Cube></Envelope>`),
+			base:     "EUR",
+			want:     "contains no rates",
+		},
+		{
+			name:     "cbr unknown charset",
+			provider: func(url string) RateProvider { return NewCBRProvider(http.DefaultClient, url) },
+			status:   http.StatusOK,
+			body:     []byte(`<?xml version="1.0" encoding="koi8-r"?><ValCurs Date="01.03.2024"></ValCurs>`),
+			base:     "RUB",
+			want:     "unsupported charset",
+		},
+		{
+			name:     "cbr invalid value",
+			provider: func(url string) RateProvider { return NewCBRProvider(http.DefaultClient, url) },
+			status:   http.StatusOK,
+			body: windows1251(t, `<?xml version="1.0" encoding="windows-1251"?><ValCurs Date="01.03.2024">`+
+				`<Valute><CharCode>USD</CharCode><Nominal>1</Nominal><Value>нет</Value></Valute></ValCurs>`),
+			base: "RUB",
+			want: "failed to parse value for USD",
+		},
+	}
+
+	for _, tt := range tests {
+		server := serve(t, tt.status, tt.body)
+		_, err := tt.provider(server.URL).FetchRates(context.Background(), tt.base)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want it to mention %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(exchangeRateAPIPayload), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := NewFileProvider(path).FetchRates(context.Background(), "EUR")
+	if err != nil {
+		t.Fatalf("FetchRates: %v", err)
+	}
+	assertRates(t, "file", rates.Rates, map[string]float64{"USD": 1 / 0.924, "RUB": 91.2 / 0.924, "JPY": 150.1 / 0.924})
+}
+
+func TestFailoverProvider(t *testing.T) {
+	down := serve(t, http.StatusServiceUnavailable, nil)
+	ecb := serve(t, http.StatusOK, []byte(ecbDailyPayload))
+
+	provider := NewFailoverProvider(
+		NewExchangeRateAPIProvider(http.DefaultClient, down.URL+"/"),
+		NewECBProvider(http.DefaultClient, ecb.URL),
+	)
+	if got := provider.Name(); got != "exchangerate-api,ecb" {
+		t.Errorf("Name() = %q, want exchangerate-api,ecb", got)
+	}
+
+	rates, err := provider.FetchRates(context.Background(), "EUR")
+	if err != nil {
+		t.Fatalf("FetchRates: %v", err)
+	}
+	if rates.Provider != ProviderECB {
+		t.Errorf("rates from %s, want ecb after exchangerate-api returned 503", rates.Provider)
+	}
+
+	// Ошибки всех источников возвращаются вместе
+	_, err = NewFailoverProvider(
+		NewExchangeRateAPIProvider(http.DefaultClient, down.URL+"/"),
+		NewCBRProvider(http.DefaultClient, down.URL),
+	).FetchRates(context.Background(), "USD")
+	if err == nil || !strings.Contains(err.Error(), "exchangerate-api: API returned status: 503") ||
+		!strings.Contains(err.Error(), "cbr: API returned status: 503") {
+		t.Errorf("FetchRates with all providers down: error = %v, want both errors", err)
+	}
+}
+
+func TestCBRHistoryProvider(t *testing.T) {
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/XML_valFull.asp":
+			_, _ = w.Write(windows1251(t, cbrCodesPayload))
+		case "/XML_dynamic.asp":
+			query := r.URL.Query()
+			if query.Get("date_req1") != "01/03/2024" || query.Get("date_req2") != "04/03/2024" {
+				t.Errorf("period = %s..%s, want 01/03/2024..04/03/2024", query.Get("date_req1"), query.Get("date_req2"))
+			}
+			id := query.Get("VAL_NM_RQ")
+			requests[id]++
+			switch id {
+			case "R01235":
+				_, _ = w.Write(windows1251(t, cbrDynamicUSDPayload))
+			case "R01820":
+				_, _ = w.Write(windows1251(t, cbrDynamicJPYPayload))
+			default:
+				t.Errorf("unexpected currency id %s", id)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewCBRHistoryProvider(http.DefaultClient, server.URL+"/XML_dynamic.asp", server.URL+"/XML_valFull.asp")
+	tables, err := provider.FetchHistory(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("FetchHistory: %v", err)
+	}
+
+	// По одному запросу динамики на поддерживаемую валюту; неподдерживаемые не запрашиваются
+	if len(requests) != 2 || requests["R01235"] != 1 || requests["R01820"] != 1 {
+		t.Errorf("dynamic requests = %v, want one per supported currency", requests)
+	}
+
+	// Запись за 29.02 вне периода отбрасывается, дни упорядочены
+	if len(tables) != 2 {
+		t.Fatalf("FetchHistory returned %d tables, want 2", len(tables))
+	}
+	if !tables[0].Date.Equal(from) || !tables[1].Date.Equal(from.AddDate(0, 0, 1)) {
+		t.Errorf("dates = %s, %s", tables[0].Date, tables[1].Date)
+	}
+	for _, table := range tables {
+		if table.Base != "RUB" || table.Provider != ProviderCBR {
+			t.Errorf("table %s: base %s provider %s", table.Date, table.Base, table.Provider)
+		}
+	}
+	assertRates(t, "01.03.2024", tables[0].Rates, map[string]float64{"USD": 1 / 91.3336, "JPY": 100 / 60.8707})
+	assertRates(t, "02.03.2024", tables[1].Rates, map[string]float64{"USD": 1 / 91.6359, "JPY": 100 / 61.0420})
+}
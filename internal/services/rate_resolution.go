@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"brok/config"
+	"brok/internal/models"
+)
+
+// ErrRateNotFound курс не найден ни напрямую, ни через промежуточные валюты
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// RateResolutionConfig настройки поиска курса
+type RateResolutionConfig struct {
+	// MaxStaleness насколько старым может быть курс относительно запрошенной даты
+	MaxStaleness time.Duration
+	// Pivots промежуточные валюты для кросс-курса, в порядке предпочтения
+	Pivots []string
+}
+
+// RateResolutionFromEnv читает настройки поиска курса:
+//
+//	EXCHANGE_RATE_MAX_STALENESS — максимальная давность курса (по умолчанию 168h)
+//	EXCHANGE_RATE_PIVOTS — промежуточные валюты через запятую (по умолчанию USD,EUR)
+func RateResolutionFromEnv() (RateResolutionConfig, error) {
+	staleness, err := time.ParseDuration(config.GetEnv("EXCHANGE_RATE_MAX_STALENESS", "168h"))
+	if err != nil || staleness < 0 {
+		return RateResolutionConfig{}, fmt.Errorf("invalid EXCHANGE_RATE_MAX_STALENESS: %v", err)
+	}
+
+	var pivots []string
+	for _, pivot := range strings.Split(config.GetEnv("EXCHANGE_RATE_PIVOTS", "USD,EUR"), ",") {
+		pivot = strings.ToUpper(strings.TrimSpace(pivot))
+		if pivot == "" {
+			continue
+		}
+		if !models.IsCurrencySupported(pivot) {
+			return RateResolutionConfig{}, fmt.Errorf("unsupported pivot currency in EXCHANGE_RATE_PIVOTS: %s", pivot)
+		}
+		pivots = append(pivots, pivot)
+	}
+
+	return RateResolutionConfig{MaxStaleness: staleness, Pivots: pivots}, nil
+}
+
+// findLeg ищет курс пары на дату или раньше — прямой или обратный, берется более свежий.
+// maxStaleness < 0 означает, что давность не ограничена.
+func (s *ExchangeRateService) findLeg(ctx context.Context, from, to string, date time.Time, maxStaleness time.Duration) (*models.RateLeg, bool) {
+	var best *models.RateLeg
+
+	if rate, err := s.storage.GetExchangeRateOnOrBefore(ctx, from, to, date); err == nil && rate.Rate != 0 {
+		best = &models.RateLeg{FromCurrency: from, ToCurrency: to, Rate: rate.Rate, Date: rate.Timestamp, Provider: rate.Provider}
+	}
+	if rate, err := s.storage.GetExchangeRateOnOrBefore(ctx, to, from, date); err == nil && rate.Rate != 0 {
+		if best == nil || rate.Timestamp.After(best.Date) {
+			best = &models.RateLeg{FromCurrency: from, ToCurrency: to, Rate: 1.0 / rate.Rate, Date: rate.Timestamp, Inverted: true, Provider: rate.Provider}
+		}
+	}
+
+	if best == nil {
+		return nil, false
+	}
+	if maxStaleness >= 0 && date.Sub(best.Date) > maxStaleness {
+		return nil, false
+	}
+	return best, true
+}
+
+// resolve ищет курс: сначала прямой (или обратный) на дату или раньше, затем кросс-курс
+// через промежуточные валюты
+func (s *ExchangeRateService) resolve(ctx context.Context, from, to string, date time.Time, maxStaleness time.Duration) (*models.RateResolution, error) {
+	resolution := &models.RateResolution{FromCurrency: from, ToCurrency: to, Rate: 1.0, Legs: []models.RateLeg{}}
+
+	// Если валюты одинаковые, курс равен 1
+	if from == to {
+		return resolution, nil
+	}
+
+	if leg, ok := s.findLeg(ctx, from, to, date, maxStaleness); ok {
+		resolution.Rate = leg.Rate
+		resolution.Legs = append(resolution.Legs, *leg)
+		return resolution, nil
+	}
+
+	for _, pivot := range s.resolution.Pivots {
+		if pivot == from || pivot == to {
+			continue
+		}
+		first, ok := s.findLeg(ctx, from, pivot, date, maxStaleness)
+		if !ok {
+			continue
+		}
+		second, ok := s.findLeg(ctx, pivot, to, date, maxStaleness)
+		if !ok {
+			continue
+		}
+
+		resolution.Rate = first.Rate * second.Rate
+		resolution.Pivot = pivot
+		resolution.Legs = append(resolution.Legs, *first, *second)
+		return resolution, nil
+	}
+
+	return nil, fmt.Errorf("%w for %s->%s", ErrRateNotFound, from, to)
+}
+
+// ResolveExchangeRate находит курс на дату: ближайший не старше MaxStaleness,
+// при отсутствии пары — через промежуточную валюту. Возвращает использованные курсы.
+func (s *ExchangeRateService) ResolveExchangeRate(ctx context.Context, fromCurrency, toCurrency string, date time.Time) (*models.RateResolution, error) {
+	// Курс на дату — это курс, установленный не позже конца этого дня
+	dayEnd := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1).Add(-time.Nanosecond)
+
+	resolution, err := s.resolve(ctx, fromCurrency, toCurrency, dayEnd, s.resolution.MaxStaleness)
+	if err != nil {
+		return nil, fmt.Errorf("%w on %s", err, date.Format("2006-01-02"))
+	}
+	requested := date
+	resolution.RequestedDate = &requested
+	return resolution, nil
+}
+
+// ResolveLatestExchangeRate находит последний известный курс, при отсутствии пары — через промежуточную валюту
+func (s *ExchangeRateService) ResolveLatestExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (*models.RateResolution, error) {
+	return s.resolve(ctx, fromCurrency, toCurrency, time.Now().UTC(), -1)
+}
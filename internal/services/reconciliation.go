@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+)
+
+// balanceTolerance расхождения меньше копейки не считаются
+const balanceTolerance = 0.005
+
+// ReconciliationService сверка сохраненных балансов активов с журналом транзакций
+type ReconciliationService struct {
+	storage storage.Storage
+}
+
+// NewReconciliationService создает новый сервис сверки
+func NewReconciliationService(storage storage.Storage) *ReconciliationService {
+	return &ReconciliationService{storage: storage}
+}
+
+// LedgerBalance баланс актива по журналу — сумма эффектов всех транзакций
+func LedgerBalance(transactions []models.Transaction) float64 {
+	var balance float64
+	for _, tx := range transactions {
+		balance += BalanceEffect(tx)
+	}
+	return math.Round(balance*100) / 100
+}
+
+// ReconcileUser сверяет активы пользователя; пустой assetID — все активы
+func (s *ReconciliationService) ReconcileUser(ctx context.Context, userID, assetID, mode string) (*models.ReconciliationReport, error) {
+	assets, err := s.storage.AssetsByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	if assetID != "" {
+		var selected []models.Asset
+		for _, asset := range assets {
+			if asset.ID == assetID {
+				selected = append(selected, asset)
+			}
+		}
+		assets = selected
+	}
+
+	return s.reconcile(ctx, assets, mode)
+}
+
+// ReconcileAll сверяет активы всех пользователей
+func (s *ReconciliationService) ReconcileAll(ctx context.Context, mode string) (*models.ReconciliationReport, error) {
+	assets, err := s.storage.AllAssets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	return s.reconcile(ctx, assets, mode)
+}
+
+// reconcile сверяет активы и в режимах fix/adjust устраняет расхождения
+func (s *ReconciliationService) reconcile(ctx context.Context, assets []models.Asset, mode string) (*models.ReconciliationReport, error) {
+	if !models.IsReconcileModeSupported(mode) {
+		return nil, fmt.Errorf("unsupported reconcile mode: %s", mode)
+	}
+
+	report := &models.ReconciliationReport{
+		Mode:          mode,
+		Discrepancies: []models.BalanceDiscrepancy{},
+	}
+
+	for _, asset := range assets {
+		discrepancy, err := s.reconcileAsset(ctx, asset, mode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reconcile asset %s: %w", asset.ID, err)
+		}
+		report.Checked++
+		if discrepancy != nil {
+			report.Discrepancies = append(report.Discrepancies, *discrepancy)
+		}
+	}
+
+	return report, nil
+}
+
+// reconcileAsset сверяет один актив в транзакции БД, заблокировав его баланс
+func (s *ReconciliationService) reconcileAsset(ctx context.Context, asset models.Asset, mode string) (*models.BalanceDiscrepancy, error) {
+	var discrepancy *models.BalanceDiscrepancy
+
+	err := s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		stored, err := s.storage.AssetBalanceForUpdateTx(ctx, tx, asset.ID)
+		if err != nil {
+			return err
+		}
+		transactions, err := s.storage.GetTransactionsByAssetIDTx(ctx, tx, asset.ID)
+		if err != nil {
+			return err
+		}
+
+		ledger := LedgerBalance(transactions)
+		difference := math.Round((stored-ledger)*100) / 100
+		if math.Abs(difference) < balanceTolerance {
+			return nil
+		}
+
+		discrepancy = &models.BalanceDiscrepancy{
+			AssetID:       asset.ID,
+			UserID:        asset.UserID,
+			Name:          asset.Name,
+			Currency:      asset.Currency,
+			StoredBalance: stored,
+			LedgerBalance: ledger,
+			Difference:    difference,
+		}
+
+		switch mode {
+		case models.ReconcileModeFix:
+			if err := s.storage.SetAssetBalanceTx(ctx, tx, asset.ID, ledger); err != nil {
+				return err
+			}
+			discrepancy.Action = "balance set to ledger"
+		case models.ReconcileModeAdjust:
+			// Переоценка на разницу: журнал начинает совпадать с сохраненным балансом
+			adjustment := models.Transaction{
+				ID:          uuid.New().String(),
+				AssetID:     asset.ID,
+				Amount:      difference,
+				Currency:    asset.Currency,
+				Type:        "revaluation",
+				Description: "Корректировка баланса по результатам сверки",
+				Timestamp:   time.Now(),
+			}
+			if err := s.storage.CreateTransactionTx(ctx, tx, adjustment); err != nil {
+				return err
+			}
+			discrepancy.Action = "revaluation recorded"
+			discrepancy.AdjustmentID = adjustment.ID
+		}
+
+		return nil
+	})
+
+	return discrepancy, err
+}
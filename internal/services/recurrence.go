@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Частоты повторения
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// ErrInvalidRecurrence правило повторения не разобрано
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// Recurrence правило повторения в стиле RRULE (RFC 5545), поддерживается подмножество:
+// FREQ=DAILY|WEEKLY|MONTHLY|YEARLY, INTERVAL=n, BYMONTHDAY=d (1..31 или -1 — последний день)
+type Recurrence struct {
+	Freq       string
+	Interval   int
+	ByMonthDay int // 0 — день даты начала
+}
+
+// ParseRecurrence разбирает правило вида FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=15
+func ParseRecurrence(rule string) (Recurrence, error) {
+	r := Recurrence{Interval: 1}
+
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	for _, part := range strings.Split(rule, ";") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("%w: %q", ErrInvalidRecurrence, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 || interval > 1000 {
+				return r, fmt.Errorf("%w: INTERVAL must be 1..1000", ErrInvalidRecurrence)
+			}
+			r.Interval = interval
+		case "BYMONTHDAY":
+			day, err := strconv.Atoi(value)
+			if err != nil || day == 0 || day > 31 || day < -1 {
+				return r, fmt.Errorf("%w: BYMONTHDAY must be 1..31 or -1", ErrInvalidRecurrence)
+			}
+			r.ByMonthDay = day
+		default:
+			return r, fmt.Errorf("%w: unsupported part %s", ErrInvalidRecurrence, key)
+		}
+	}
+
+	switch r.Freq {
+	case FreqDaily, FreqWeekly:
+		if r.ByMonthDay != 0 {
+			return r, fmt.Errorf("%w: BYMONTHDAY is only allowed for MONTHLY and YEARLY", ErrInvalidRecurrence)
+		}
+	case FreqMonthly, FreqYearly:
+	case "":
+		return r, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	default:
+		return r, fmt.Errorf("%w: unsupported FREQ %s", ErrInvalidRecurrence, r.Freq)
+	}
+
+	return r, nil
+}
+
+// Occurrence n-е наступление (n с нуля), отсчитанное от даты начала.
+// Для месяцев без нужного дня берется последний день месяца (31 января -> 28 февраля -> 31 марта).
+func (r Recurrence) Occurrence(start time.Time, n int) time.Time {
+	step := n * r.Interval
+
+	switch r.Freq {
+	case FreqDaily:
+		return start.AddDate(0, 0, step)
+	case FreqWeekly:
+		return start.AddDate(0, 0, 7*step)
+	}
+
+	months := step
+	if r.Freq == FreqYearly {
+		months = 12 * step
+	}
+	year, month := start.Year(), start.Month()+time.Month(months)
+	// Нормализуем месяц через первое число, чтобы не было переполнения дня
+	first := time.Date(year, month, 1, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+
+	day := start.Day()
+	if r.ByMonthDay > 0 {
+		day = r.ByMonthDay
+	} else if r.ByMonthDay == -1 {
+		day = lastDay
+	}
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
+
+// Occurrences наступления в интервале (after, until] не раньше start и не позже end.
+// after == nil — с самого начала. Возвращает не больше limit дат.
+func (r Recurrence) Occurrences(start time.Time, end, after *time.Time, until time.Time, limit int) []time.Time {
+	var result []time.Time
+	for n := 0; len(result) < limit; n++ {
+		occurrence := r.Occurrence(start, n)
+		if occurrence.After(until) || (end != nil && occurrence.After(*end)) {
+			break
+		}
+		if occurrence.Before(start) || (after != nil && !occurrence.After(*after)) {
+			continue
+		}
+		result = append(result, occurrence)
+	}
+	return result
+}
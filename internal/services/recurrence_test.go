@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"brok/internal/models"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    Recurrence
+		wantErr bool
+	}{
+		{rule: "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=15", want: Recurrence{Freq: FreqMonthly, Interval: 1, ByMonthDay: 15}},
+		{rule: "RRULE:freq=weekly;interval=2", want: Recurrence{Freq: FreqWeekly, Interval: 2}},
+		{rule: " FREQ=YEARLY; BYMONTHDAY=-1 ", want: Recurrence{Freq: FreqYearly, Interval: 1, ByMonthDay: -1}},
+		{rule: "FREQ=DAILY;", want: Recurrence{Freq: FreqDaily, Interval: 1}},
+		{rule: "", wantErr: true},
+		{rule: "INTERVAL=2", wantErr: true},
+		{rule: "FREQ=HOURLY", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=1001", wantErr: true},
+		{rule: "FREQ=DAILY;BYMONTHDAY=1", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=-2", wantErr: true},
+		{rule: "FREQ=MONTHLY;COUNT=3", wantErr: true},
+		{rule: "FREQ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRecurrence(tt.rule)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRecurrence) {
+				t.Errorf("ParseRecurrence(%q) error = %v, want ErrInvalidRecurrence", tt.rule, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRecurrence(%q) unexpected error: %v", tt.rule, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRecurrence(%q) = %+v, want %+v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestOccurrence(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("timezone data is not available: %v", err)
+	}
+	date := func(year int, month time.Month, day, hour int, loc *time.Location) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name  string
+		rule  Recurrence
+		start time.Time
+		n     int
+		want  time.Time
+	}{
+		{
+			name:  "daily with interval",
+			rule:  Recurrence{Freq: FreqDaily, Interval: 3},
+			start: date(2024, time.January, 30, 9, time.UTC),
+			n:     2,
+			want:  date(2024, time.February, 5, 9, time.UTC),
+		},
+		{
+			name:  "weekly",
+			rule:  Recurrence{Freq: FreqWeekly, Interval: 1},
+			start: date(2024, time.March, 1, 0, time.UTC),
+			n:     4,
+			want:  date(2024, time.March, 29, 0, time.UTC),
+		},
+		{
+			name:  "monthly clamps to the last day and comes back",
+			rule:  Recurrence{Freq: FreqMonthly, Interval: 1},
+			start: date(2024, time.January, 31, 0, time.UTC),
+			n:     1,
+			want:  date(2024, time.February, 29, 0, time.UTC),
+		},
+		{
+			name:  "monthly keeps the start day after a short month",
+			rule:  Recurrence{Freq: FreqMonthly, Interval: 1},
+			start: date(2024, time.January, 31, 0, time.UTC),
+			n:     2,
+			want:  date(2024, time.March, 31, 0, time.UTC),
+		},
+		{
+			name:  "monthly by month day",
+			rule:  Recurrence{Freq: FreqMonthly, Interval: 2, ByMonthDay: 15},
+			start: date(2024, time.November, 1, 12, time.UTC),
+			n:     1,
+			want:  date(2025, time.January, 15, 12, time.UTC),
+		},
+		{
+			name:  "last day of month",
+			rule:  Recurrence{Freq: FreqMonthly, Interval: 1, ByMonthDay: -1},
+			start: date(2023, time.January, 10, 0, time.UTC),
+			n:     1,
+			want:  date(2023, time.February, 28, 0, time.UTC),
+		},
+		{
+			name:  "yearly from leap day",
+			rule:  Recurrence{Freq: FreqYearly, Interval: 1},
+			start: date(2024, time.February, 29, 0, time.UTC),
+			n:     1,
+			want:  date(2025, time.February, 28, 0, time.UTC),
+		},
+		{
+			name:  "month days are counted in the start location",
+			rule:  Recurrence{Freq: FreqMonthly, Interval: 1, ByMonthDay: -1},
+			start: date(2024, time.January, 31, 0, moscow),
+			n:     1,
+			want:  date(2024, time.February, 29, 0, moscow),
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rule.Occurrence(tt.start, tt.n); !got.Equal(tt.want) {
+			t.Errorf("%s: Occurrence(%s, %d) = %s, want %s", tt.name, tt.start, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleOccurrences(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("timezone data is not available: %v", err)
+	}
+
+	// Начало 31 января 00:00 по Москве приходит из базы как 30 января 21:00 UTC
+	start := time.Date(2024, time.January, 31, 0, 0, 0, 0, moscow).UTC()
+	schedule := models.Schedule{
+		Frequency: "FREQ=MONTHLY;BYMONTHDAY=-1",
+		StartDate: start,
+		Timezone:  "Europe/Moscow",
+	}
+
+	until := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+	got, err := scheduleOccurrences(schedule, until, 10)
+	if err != nil {
+		t.Fatalf("scheduleOccurrences: %v", err)
+	}
+	want := []time.Time{
+		time.Date(2024, time.January, 31, 0, 0, 0, 0, moscow),
+		time.Date(2024, time.February, 29, 0, 0, 0, 0, moscow),
+		time.Date(2024, time.March, 31, 0, 0, 0, 0, moscow),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("scheduleOccurrences = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	// После возобновления пропущенные на паузе наступления не создаются
+	last := want[0]
+	resumed := time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)
+	schedule.LastOccurrence = &last
+	schedule.ResumedAt = &resumed
+	got, err = scheduleOccurrences(schedule, until, 10)
+	if err != nil {
+		t.Fatalf("scheduleOccurrences: %v", err)
+	}
+	if len(got) != 1 || !got[0].Equal(want[2]) {
+		t.Errorf("scheduleOccurrences after resume = %v, want [%s]", got, want[2])
+	}
+}
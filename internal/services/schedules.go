@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+)
+
+// maxCatchUpOccurrences сколько наступлений одного расписания создается за один проход;
+// остальные догоняются при следующих запусках
+const maxCatchUpOccurrences = 1000
+
+// errOccurrenceDone наступление уже обработано другим запуском
+var errOccurrenceDone = errors.New("schedule occurrence already materialized")
+
+// farFuture верхняя граница для поиска следующего наступления
+var farFuture = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// ScheduleService создание транзакций по расписаниям
+type ScheduleService struct {
+	storage         storage.Storage
+	exchangeService *ExchangeRateService
+}
+
+// NewScheduleService создает новый сервис расписаний
+func NewScheduleService(storage storage.Storage, exchangeService *ExchangeRateService) *ScheduleService {
+	return &ScheduleService{
+		storage:         storage,
+		exchangeService: exchangeService,
+	}
+}
+
+// ScheduleLocation часовой пояс, в котором считаются наступления расписания; пустой — UTC
+func ScheduleLocation(schedule models.Schedule) (*time.Location, error) {
+	if schedule.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(schedule.Timezone)
+}
+
+// scheduleOccurrences наступления расписания до until, не больше limit. Считаются в часовом
+// поясе расписания (из базы даты приходят в UTC), после последнего обработанного
+// и не раньше возобновления: пропущенные на паузе наступления не создаются.
+func scheduleOccurrences(schedule models.Schedule, until time.Time, limit int) ([]time.Time, error) {
+	rule, err := ParseRecurrence(schedule.Frequency)
+	if err != nil {
+		return nil, err
+	}
+	location, err := ScheduleLocation(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	after := schedule.LastOccurrence
+	if schedule.ResumedAt != nil {
+		resumed := schedule.ResumedAt.Add(-time.Nanosecond)
+		if after == nil || resumed.After(*after) {
+			after = &resumed
+		}
+	}
+
+	return rule.Occurrences(schedule.StartDate.In(location), schedule.EndDate, after, until, limit), nil
+}
+
+// NextOccurrence следующее необработанное наступление расписания, nil — расписание закончилось
+func NextOccurrence(schedule models.Schedule) (*time.Time, error) {
+	next, err := scheduleOccurrences(schedule, farFuture, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(next) == 0 {
+		return nil, nil
+	}
+	return &next[0], nil
+}
+
+// RunDue создает транзакции по всем наступившим и пропущенным наступлениям активных расписаний.
+// Возвращает количество созданных транзакций.
+func (s *ScheduleService) RunDue(ctx context.Context, now time.Time) (int, error) {
+	schedules, err := s.storage.ActiveSchedules(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schedules: %w", err)
+	}
+
+	var created int
+	for _, schedule := range schedules {
+		count, err := s.runSchedule(ctx, schedule, now)
+		created += count
+		if err != nil {
+			// Ошибка одного расписания не мешает остальным, оно догонится при следующем запуске
+			log.Printf("Error running schedule %s: %v", schedule.ID, err)
+		}
+	}
+
+	return created, nil
+}
+
+// runSchedule создает транзакции по наступлениям расписания до now по порядку
+func (s *ScheduleService) runSchedule(ctx context.Context, schedule models.Schedule, now time.Time) (int, error) {
+	occurrences, err := scheduleOccurrences(schedule, now, maxCatchUpOccurrences)
+	if err != nil {
+		return 0, err
+	}
+	if len(occurrences) == 0 {
+		return 0, nil
+	}
+
+	asset, err := s.storage.AssetByID(ctx, schedule.AssetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get asset: %w", err)
+	}
+
+	var created int
+	for _, occurrence := range occurrences {
+		transaction := models.Transaction{
+			ID:          uuid.New().String(),
+			AssetID:     schedule.AssetID,
+			Amount:      schedule.Amount,
+			Currency:    schedule.Currency,
+			Type:        schedule.Type,
+			Description: schedule.Description,
+			Timestamp:   occurrence,
+		}
+		if transaction.Type == "dividend" {
+			transaction.IncomeKind = models.IncomeKindDividend
+		}
+
+		// Курс берется на дату наступления, как для транзакции, введенной вручную; если его нет,
+		// останавливаемся, чтобы наступления не создавались вне очереди
+		if err := s.exchangeService.ConvertToAsset(ctx, &transaction, asset.Currency, nil); err != nil {
+			return created, fmt.Errorf("failed to get exchange rate for %s: %w", occurrence.Format(time.DateOnly), err)
+		}
+
+		err := s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+			if err := s.storage.CreateTransactionTx(ctx, tx, transaction); err != nil {
+				return err
+			}
+
+			inserted, err := s.storage.CreateScheduleRunTx(ctx, tx, schedule.ID, occurrence, transaction.ID)
+			if err != nil {
+				return err
+			}
+			if !inserted {
+				return errOccurrenceDone
+			}
+
+			balanceChange := BalanceEffect(transaction)
+			if err := s.storage.UpdateAssetBalanceTx(ctx, tx, transaction.AssetID, balanceChange); err != nil {
+				return err
+			}
+
+			return s.storage.SetScheduleLastOccurrenceTx(ctx, tx, schedule.ID, occurrence)
+		})
+		if errors.Is(err, errOccurrenceDone) {
+			continue
+		}
+		if err != nil {
+			return created, fmt.Errorf("failed to materialize %s: %w", occurrence.Format(time.DateOnly), err)
+		}
+		created++
+	}
+
+	return created, nil
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+	"brok/internal/utils"
+)
+
+var (
+	// ErrInvalidRefreshToken refresh-токен неизвестен, отозван, истек или уже был заменен
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrSessionNotFound сессия не найдена или уже отозвана
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// SessionConfig сроки действия токенов
+type SessionConfig struct {
+	AccessTTL  time.Duration // access-токен (JWT)
+	RefreshTTL time.Duration // refresh-токен; продлевается при каждом обновлении
+}
+
+// SessionConfigFromEnv читает сроки из ACCESS_TOKEN_TTL (по умолчанию 15 минут)
+// и REFRESH_TOKEN_TTL (по умолчанию 30 дней)
+func SessionConfigFromEnv() (SessionConfig, error) {
+	config := SessionConfig{
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: 30 * 24 * time.Hour,
+	}
+
+	for name, target := range map[string]*time.Duration{
+		"ACCESS_TOKEN_TTL":  &config.AccessTTL,
+		"REFRESH_TOKEN_TTL": &config.RefreshTTL,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return config, fmt.Errorf("invalid %s: %q", name, value)
+		}
+		*target = ttl
+	}
+	if config.RefreshTTL <= config.AccessTTL {
+		return config, errors.New("REFRESH_TOKEN_TTL must be longer than ACCESS_TOKEN_TTL")
+	}
+
+	return config, nil
+}
+
+// SessionService сервис сессий: выдача, ротация и отзыв токенов
+type SessionService struct {
+	storage storage.Storage
+	config  SessionConfig
+}
+
+// NewSessionService создает новый сервис сессий
+func NewSessionService(storage storage.Storage, config SessionConfig) *SessionService {
+	return &SessionService{
+		storage: storage,
+		config:  config,
+	}
+}
+
+// Start открывает сессию пользователя и выдает первую пару токенов
+func (s *SessionService) Start(ctx context.Context, userID, userAgent, ip string) (*models.LoginResponse, error) {
+	refreshToken, refreshHash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        userAgent,
+		IP:               ip,
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.config.RefreshTTL),
+	}
+	if err := s.storage.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.tokens(session, refreshToken)
+}
+
+// Refresh заменяет refresh-токен новым и выдает новый access-токен.
+// Повторное предъявление уже замененного токена означает его утечку: сессия отзывается.
+func (s *SessionService) Refresh(ctx context.Context, refreshToken, userAgent, ip string) (*models.LoginResponse, error) {
+	hash := hashToken(refreshToken)
+
+	session, err := s.storage.SessionByTokenHash(ctx, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.RefreshTokenHash != hash {
+		if _, err := s.storage.RevokeSession(ctx, session.ID, session.UserID); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		log.Printf("⚠️  Повторное использование refresh-токена, сессия %s отозвана", session.ID)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rotatedToken, newHash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	session.ExpiresAt = time.Now().Add(s.config.RefreshTTL)
+	rotated, err := s.storage.RotateSession(ctx, session.ID, hash, newHash, session.ExpiresAt, userAgent, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	if !rotated {
+		// Токен заменили параллельным запросом
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.tokens(*session, rotatedToken)
+}
+
+// Logout отзывает сессию и access-токен, с которым выполнен запрос
+func (s *SessionService) Logout(ctx context.Context, userID string, claims *utils.Claims) error {
+	if _, err := s.storage.RevokeSession(ctx, claims.SessionID, userID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return s.revokeAccessToken(ctx, claims)
+}
+
+// LogoutAll отзывает все сессии пользователя; их access-токены перестают приниматься
+func (s *SessionService) LogoutAll(ctx context.Context, userID string, claims *utils.Claims) (int64, error) {
+	revoked, err := s.storage.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return revoked, s.revokeAccessToken(ctx, claims)
+}
+
+// Sessions возвращает активные сессии пользователя, отмечая текущую
+func (s *SessionService) Sessions(ctx context.Context, userID, currentSessionID string) ([]models.Session, error) {
+	sessions, err := s.storage.ActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// Revoke отзывает одну сессию пользователя
+func (s *SessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+	revoked, err := s.storage.RevokeSession(ctx, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// tokens выпускает access-токен сессии и собирает ответ
+func (s *SessionService) tokens(session models.Session, refreshToken string) (*models.LoginResponse, error) {
+	accessToken, claims, err := utils.GenerateJWT(session.UserID, session.ID, s.config.AccessTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &models.LoginResponse{
+		Token:            accessToken,
+		ExpiresAt:        claims.ExpiresAt.Time,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// revokeAccessToken запоминает jti токена до истечения его срока
+func (s *SessionService) revokeAccessToken(ctx context.Context, claims *utils.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	if err := s.storage.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// newToken создает случайный непрозрачный токен (refresh, второй шаг входа) и его хэш для хранения
+func newToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+// hashToken SHA-256 токена в hex: refresh-токены хранятся только в таком виде
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"brok/internal/models"
+)
+
+// MaxImportRows максимальное количество строк в одной выписке
+const MaxImportRows = 10000
+
+// ErrInvalidStatement файл выписки не соответствует профилю
+var ErrInvalidStatement = errors.New("invalid statement file")
+
+// ParseStatementCSV разбирает CSV-выписку по профилю: колонки ищутся по заголовкам
+// (без учета регистра), каждая строка превращается в запрос на создание транзакции.
+// Ошибки отдельных строк возвращаются в ImportRow.Err, ошибка всего файла — в error.
+func ParseStatementCSV(r io.Reader, profile models.ImportProfile) ([]models.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if profile.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(profile.Delimiter)
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidStatement, err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel добавляет BOM в начало файла
+		name = strings.TrimPrefix(name, "\ufeff")
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	column := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, fmt.Errorf("%w: column %q not found", ErrInvalidStatement, name)
+		}
+		return i, nil
+	}
+
+	dateCol, err := column(profile.DateColumn)
+	if err != nil {
+		return nil, err
+	}
+	amountCol, err := column(profile.AmountColumn)
+	if err != nil {
+		return nil, err
+	}
+	typeCol, err := column(profile.TypeColumn)
+	if err != nil {
+		return nil, err
+	}
+	currencyCol, err := column(profile.CurrencyColumn)
+	if err != nil {
+		return nil, err
+	}
+	descriptionCol, err := column(profile.DescriptionColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	dateFormat := profile.DateFormat
+	if dateFormat == "" {
+		dateFormat = "2006-01-02"
+	}
+
+	typeMap := make(map[string]string, len(profile.TypeMap))
+	for from, to := range profile.TypeMap {
+		typeMap[strings.ToLower(strings.TrimSpace(from))] = to
+	}
+
+	var rows []models.ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if isBlankRecord(record) {
+			continue
+		}
+		if len(rows) >= MaxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidStatement, MaxImportRows)
+		}
+
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := models.ImportRow{Row: line}
+		row.Request, row.Err = parseStatementRecord(field(dateCol), dateFormat, field(amountCol), field(typeCol), typeCol >= 0, typeMap)
+		row.Request.Currency = strings.ToUpper(field(currencyCol))
+		row.Request.Description = field(descriptionCol)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseStatementRecord разбирает дату, сумму и тип строки выписки
+func parseStatementRecord(date, dateFormat, amount, rawType string, hasType bool, typeMap map[string]string) (models.CreateTransactionRequest, error) {
+	var req models.CreateTransactionRequest
+
+	timestamp, err := time.ParseInLocation(dateFormat, date, time.UTC)
+	if err != nil {
+		return req, fmt.Errorf("invalid date %q, expected format %s", date, dateFormat)
+	}
+	req.Timestamp = &timestamp
+
+	value, err := ParseStatementAmount(amount)
+	if err != nil {
+		return req, err
+	}
+	if value == 0 {
+		return req, errors.New("amount is required")
+	}
+
+	// Без колонки типа ввод и вывод различаются знаком суммы
+	if !hasType {
+		req.Type = "deposit"
+		if value < 0 {
+			req.Type = "withdrawal"
+		}
+		req.Amount = math.Abs(value)
+		return req, nil
+	}
+
+	key := strings.ToLower(rawType)
+	req.Type = key
+	if mapped, ok := typeMap[key]; ok {
+		req.Type = mapped
+	}
+
+	// Знак важен только для переоценки, в остальных типах направление задает сам тип
+	req.Amount = value
+	if req.Type != "revaluation" {
+		req.Amount = math.Abs(value)
+	}
+	return req, nil
+}
+
+// ParseStatementAmount разбирает сумму из выписки: пробелы между разрядами
+// и запятая как десятичный разделитель допускаются
+func ParseStatementAmount(value string) (float64, error) {
+	cleaned := strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "'", "").Replace(value)
+	if strings.Contains(cleaned, ",") {
+		if strings.Contains(cleaned, ".") {
+			// 1,234.56 — запятая разделяет разряды
+			cleaned = strings.ReplaceAll(cleaned, ",", "")
+		} else {
+			cleaned = strings.ReplaceAll(cleaned, ",", ".")
+		}
+	}
+
+	amount, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return amount, nil
+}
+
+// isBlankRecord проверяет, что в строке нет ни одного значения
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportKey ключ для поиска дубликатов при импорте: тип, время, сумма в исходной валюте,
+// инструмент, количество и описание
+func ImportKey(tx models.Transaction) string {
+	amount, currency := tx.Amount, tx.Currency
+	if tx.OriginalAmount != nil && tx.OriginalCurrency != nil {
+		amount, currency = *tx.OriginalAmount, *tx.OriginalCurrency
+	}
+	return fmt.Sprintf("%s|%d|%.2f|%s|%s|%g|%s",
+		tx.Type, tx.Timestamp.Unix(), amount, currency, tx.Ticker, tx.Quantity, tx.Description)
+}
+
+// ImportDuplicates отмечает строки выписки, которые уже записаны в актив. Совпадения
+// считаются поштучно: ключ, который встречается в файле N раз, а среди existing M раз,
+// дает N−M новых строк, поэтому одинаковые операции за один день не теряются.
+func ImportDuplicates(existing, rows []models.Transaction) []bool {
+	stored := make(map[string]int, len(existing))
+	for _, tx := range existing {
+		stored[ImportKey(tx)]++
+	}
+
+	duplicates := make([]bool, len(rows))
+	for i, tx := range rows {
+		key := ImportKey(tx)
+		if stored[key] > 0 {
+			stored[key]--
+			duplicates[i] = true
+		}
+	}
+	return duplicates
+}
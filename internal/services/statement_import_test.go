@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"brok/internal/models"
+)
+
+func TestImportDuplicates(t *testing.T) {
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	coffee := models.Transaction{Type: "withdrawal", Amount: 250, Currency: "RUB", Description: "Кофе", Timestamp: day}
+	buy := models.Transaction{Type: "buy", Amount: 3015, Currency: "RUB", Ticker: "SBER", Quantity: 10, Timestamp: day}
+	buyMore := buy
+	buyMore.Quantity = 20
+	lunch := coffee
+	lunch.Description = "Обед"
+
+	tests := []struct {
+		name     string
+		existing []models.Transaction
+		rows     []models.Transaction
+		want     []bool
+	}{
+		{
+			name: "nothing stored",
+			rows: []models.Transaction{coffee, coffee},
+			want: []bool{false, false},
+		},
+		{
+			name:     "file has more copies than the database",
+			existing: []models.Transaction{coffee},
+			rows:     []models.Transaction{coffee, coffee, coffee},
+			want:     []bool{true, false, false},
+		},
+		{
+			name:     "file re-imported in full",
+			existing: []models.Transaction{coffee, coffee, buy},
+			rows:     []models.Transaction{coffee, buy, coffee},
+			want:     []bool{true, true, true},
+		},
+		{
+			name:     "quantity and description are part of the key",
+			existing: []models.Transaction{buy, coffee},
+			rows:     []models.Transaction{buyMore, lunch},
+			want:     []bool{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		got := ImportDuplicates(tt.existing, tt.rows)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: ImportDuplicates = %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: ImportDuplicates = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
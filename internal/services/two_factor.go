@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+	"brok/internal/storage"
+	"brok/internal/utils"
+)
+
+const (
+	// challengeTTL время на ввод кода 2FA после проверки пароля
+	challengeTTL = 5 * time.Minute
+	// challengeMaxAttempts количество неверных кодов, после которого вход нужно начинать заново
+	challengeMaxAttempts = 5
+	// userMaxFailures количество неверных кодов подряд во всех входах, после которого коды не принимаются
+	userMaxFailures = 10
+	// userLockout время, на которое блокируется ввод кодов после userMaxFailures неверных
+	userLockout = 15 * time.Minute
+	// recoveryCodeCount количество кодов восстановления в наборе
+	recoveryCodeCount = 10
+)
+
+var (
+	// ErrTwoFactorEnabled 2FA уже включена
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled 2FA не включена
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorNotEnrolled подключение 2FA не начато
+	ErrTwoFactorNotEnrolled = errors.New("two-factor enrollment not started")
+	// ErrInvalidTwoFactorCode неверный или уже использованный код
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrInvalidChallenge токен второго шага входа неизвестен, истек или исчерпал попытки
+	ErrInvalidChallenge = errors.New("invalid or expired challenge token")
+	// ErrTwoFactorLocked слишком много неверных кодов, ввод временно заблокирован
+	ErrTwoFactorLocked = errors.New("too many invalid two-factor codes, try again later")
+	// ErrInvalidPassword неверный пароль при повторной аутентификации
+	ErrInvalidPassword = errors.New("invalid password")
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorService сервис двухфакторной аутентификации (TOTP)
+type TwoFactorService struct {
+	storage  storage.Storage
+	sessions *SessionService
+	issuer   string // название в приложении-аутентификаторе
+}
+
+// NewTwoFactorService создает новый сервис 2FA; название задается TOTP_ISSUER (по умолчанию brok)
+func NewTwoFactorService(storage storage.Storage, sessions *SessionService) *TwoFactorService {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "brok"
+	}
+	return &TwoFactorService{
+		storage:  storage,
+		sessions: sessions,
+		issuer:   issuer,
+	}
+}
+
+// Status возвращает состояние 2FA пользователя
+func (s *TwoFactorService) Status(ctx context.Context, userID string) (*models.TwoFactorStatus, error) {
+	twoFactor, err := s.storage.TwoFactorByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor state: %w", err)
+	}
+
+	status := &models.TwoFactorStatus{
+		Enabled:   twoFactor.EnabledAt != nil,
+		EnabledAt: twoFactor.EnabledAt,
+		Pending:   twoFactor.EnabledAt == nil && twoFactor.Secret != nil,
+	}
+	if status.Enabled {
+		status.RecoveryCodesLeft, err = s.storage.RecoveryCodesLeft(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// Enroll начинает подключение 2FA: создает новый секрет, который действует после Confirm
+func (s *TwoFactorService) Enroll(ctx context.Context, userID string) (*models.TwoFactorEnrollment, error) {
+	twoFactor, err := s.storage.TwoFactorByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor state: %w", err)
+	}
+	if twoFactor.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.storage.SetTwoFactorSecret(ctx, userID, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	if !saved {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPProvisioningURI(s.issuer, twoFactor.Email, secret),
+	}, nil
+}
+
+// Confirm включает 2FA после проверки первого кода и выдает коды восстановления
+func (s *TwoFactorService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	twoFactor, err := s.storage.TwoFactorByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor state: %w", err)
+	}
+	if twoFactor.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if twoFactor.Secret == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := utils.ValidateTOTP(*twoFactor.Secret, code, time.Now(), twoFactor.LastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		enabled, err := s.storage.EnableTwoFactorTx(ctx, tx, userID, *twoFactor.Secret, step)
+		if err != nil {
+			return fmt.Errorf("failed to enable two-factor: %w", err)
+		}
+		if !enabled {
+			// Секрет заменен или 2FA включена параллельным запросом
+			return ErrInvalidTwoFactorCode
+		}
+		if err := s.storage.ReplaceRecoveryCodesTx(ctx, tx, userID, hashes); err != nil {
+			return fmt.Errorf("failed to save recovery codes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Enabled сообщает, что для входа пользователя нужен второй шаг
+func (s *TwoFactorService) Enabled(ctx context.Context, userID string) (bool, error) {
+	twoFactor, err := s.storage.TwoFactorByUserID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get two-factor state: %w", err)
+	}
+	return twoFactor.EnabledAt != nil, nil
+}
+
+// StartChallenge выдает короткоживущий токен второго шага входа после проверки пароля
+func (s *TwoFactorService) StartChallenge(ctx context.Context, userID string) (*models.TwoFactorChallengeResponse, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	challenge := models.TwoFactorChallenge{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(challengeTTL),
+	}
+	if err := s.storage.CreateTwoFactorChallenge(ctx, challenge); err != nil {
+		return nil, fmt.Errorf("failed to create challenge: %w", err)
+	}
+
+	return &models.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         challenge.ExpiresAt,
+	}, nil
+}
+
+// CompleteLogin обменивает токен второго шага и код (TOTP или восстановления) на сессию.
+// Неверный код расходует попытку; токен одноразовый.
+func (s *TwoFactorService) CompleteLogin(ctx context.Context, challengeToken, code, userAgent, ip string) (*models.LoginResponse, error) {
+	challenge, err := s.storage.TwoFactorChallengeByHash(ctx, hashToken(challengeToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	if !challenge.ExpiresAt.After(time.Now()) || challenge.Attempts >= challengeMaxAttempts {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.VerifyCode(ctx, challenge.UserID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.storage.FailTwoFactorChallenge(ctx, challenge.ID, challengeMaxAttempts); err != nil {
+				return nil, fmt.Errorf("failed to update challenge: %w", err)
+			}
+		}
+		return nil, err
+	}
+
+	deleted, err := s.storage.DeleteTwoFactorChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete challenge: %w", err)
+	}
+	if !deleted {
+		return nil, ErrInvalidChallenge
+	}
+
+	return s.sessions.Start(ctx, challenge.UserID, userAgent, ip)
+}
+
+// Disable выключает 2FA после повторной проверки пароля и кода
+func (s *TwoFactorService) Disable(ctx context.Context, userID, password, code string) error {
+	if err := s.reauthenticate(ctx, userID, password, code); err != nil {
+		return err
+	}
+
+	return s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		if err := s.storage.DisableTwoFactorTx(ctx, tx, userID); err != nil {
+			return fmt.Errorf("failed to disable two-factor: %w", err)
+		}
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления после повторной проверки пароля и кода
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID, password, code string) ([]string, error) {
+	if err := s.reauthenticate(ctx, userID, password, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.storage.Transaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		if err := s.storage.ReplaceRecoveryCodesTx(ctx, tx, userID, hashes); err != nil {
+			return fmt.Errorf("failed to save recovery codes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// reauthenticate проверяет пароль и код 2FA пользователя
+func (s *TwoFactorService) reauthenticate(ctx context.Context, userID, password, code string) error {
+	user, err := s.storage.UserWithPasswordByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !models.CheckPassword(user.PasswordHash, password) {
+		return ErrInvalidPassword
+	}
+	return s.VerifyCode(ctx, userID, code)
+}
+
+// VerifyCode проверяет код TOTP или погашает код восстановления.
+// Неверные коды считаются по пользователю во всех входах и повторных проверках:
+// после userMaxFailures подряд ввод блокируется на userLockout.
+func (s *TwoFactorService) VerifyCode(ctx context.Context, userID, code string) error {
+	twoFactor, err := s.storage.TwoFactorByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get two-factor state: %w", err)
+	}
+	if twoFactor.EnabledAt == nil || twoFactor.Secret == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	if twoFactor.LockedUntil != nil && twoFactor.LockedUntil.After(time.Now()) {
+		return ErrTwoFactorLocked
+	}
+
+	err = s.checkCode(ctx, twoFactor, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if err := s.storage.FailTwoFactorCode(ctx, userID, userMaxFailures, userLockout); err != nil {
+			return fmt.Errorf("failed to count invalid code: %w", err)
+		}
+		return ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.ResetTwoFactorFailures(ctx, userID); err != nil {
+		return fmt.Errorf("failed to reset invalid codes: %w", err)
+	}
+	return nil
+}
+
+// checkCode принимает код TOTP (шаг запоминается) или погашает код восстановления
+func (s *TwoFactorService) checkCode(ctx context.Context, twoFactor *models.TwoFactor, code string) error {
+	userID := twoFactor.UserID
+
+	if step, ok := utils.ValidateTOTP(*twoFactor.Secret, code, time.Now(), twoFactor.LastStep); ok {
+		used, err := s.storage.UseTOTPStep(ctx, userID, step)
+		if err != nil {
+			return fmt.Errorf("failed to save totp step: %w", err)
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := s.storage.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// newRecoveryCodes создает набор кодов восстановления вида XXXX-XXXX-XXXX-XXXX (80 бит) и их хэши
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := recoveryEncoding.EncodeToString(buf)
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode приводит введенный код восстановления к виду, от которого считается хэш
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
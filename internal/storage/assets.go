@@ -9,7 +9,7 @@ import (
 func (s *PqStorage) AssetsByUserId(ctx context.Context, userID string) ([]models.Asset, error) {
 	rows, err := s.db.QueryxContext(
 		ctx,
-		`SELECT id, user_id, name, type, balance, currency FROM assets WHERE user_id = $1`,
+		`SELECT id, user_id, name, type, legacy_type, balance, currency, lot_method, symbol, instrument_id, created_at FROM assets WHERE user_id = $1`,
 		userID,
 	)
 	if err != nil {
@@ -41,11 +41,12 @@ func (s *PqStorage) AssetsByUserId(ctx context.Context, userID string) ([]models
 
 func (s *PqStorage) AssetSet(ctx context.Context, asset models.Asset) error {
 	const query = `
-		insert into assets(id, user_id, name, type, balance, currency, created_at)
-		values (:id, :user_id, :name, :type, :balance, :currency, :created_at)
+		insert into assets(id, user_id, name, type, legacy_type, balance, currency, lot_method, symbol, instrument_id, created_at)
+		values (:id, :user_id, :name, :type, :legacy_type, :balance, :currency, :lot_method, :symbol, :instrument_id, :created_at)
         on conflict(id) do update
 		set name=excluded.name,
                 type=excluded.type,
+                legacy_type=excluded.legacy_type,
 		    balance=excluded.balance,
 		    currency=excluded.currency
 	`
@@ -58,8 +59,58 @@ func (s *PqStorage) AssetSet(ctx context.Context, asset models.Asset) error {
 	return nil
 }
 
-func (s *PqStorage) DeleteAsset(ctx context.Context, assetID string) error {
+// AssetByID получает актив по ID
+func (s *PqStorage) AssetByID(ctx context.Context, assetID string) (*models.Asset, error) {
+	var asset models.Asset
+	err := s.db.GetContext(
+		ctx,
+		&asset,
+		`SELECT id, user_id, name, type, legacy_type, balance, currency, lot_method, symbol, instrument_id, created_at FROM assets WHERE id = $1`,
+		assetID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// SetAssetSymbol привязывает актив к инструменту (nil — отвязывает)
+func (s *PqStorage) SetAssetSymbol(ctx context.Context, assetID string, symbol *string) error {
 	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE assets SET symbol = $1 WHERE id = $2`,
+		symbol, assetID,
+	)
+	return err
+}
+
+// SetAssetInstrument привязывает актив к инструменту справочника (nil — отвязывает)
+func (s *PqStorage) SetAssetInstrument(ctx context.Context, assetID string, instrumentID *string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE assets SET instrument_id = $1 WHERE id = $2`,
+		instrumentID, assetID,
+	)
+	return err
+}
+
+// SetAssetLotMethod задает метод подбора лотов для актива (nil — метод пользователя)
+func (s *PqStorage) SetAssetLotMethod(ctx context.Context, assetID string, method *string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE assets SET lot_method = $1 WHERE id = $2`,
+		method, assetID,
+	)
+	return err
+}
+
+func (s *PqStorage) DeleteAsset(ctx context.Context, assetID string) error {
+	return s.DeleteAssetTx(ctx, s.db, assetID)
+}
+
+// DeleteAssetTx удаляет актив через транзакцию
+func (s *PqStorage) DeleteAssetTx(ctx context.Context, tx Tx, assetID string) error {
+	_, err := tx.ExecContext(
 		ctx,
 		`delete from assets where id=$1`,
 		assetID,
@@ -94,3 +145,45 @@ func (s *PqStorage) UpdateAssetBalanceTx(ctx context.Context, tx Tx, assetID str
 	)
 	return err
 }
+
+// CreateAssetTx создает актив через транзакцию
+func (s *PqStorage) CreateAssetTx(ctx context.Context, tx Tx, asset models.Asset) error {
+	_, err := tx.NamedExecContext(
+		ctx,
+		`INSERT INTO assets (id, user_id, name, type, legacy_type, balance, currency, lot_method, symbol, instrument_id, created_at)
+		VALUES (:id, :user_id, :name, :type, :legacy_type, :balance, :currency, :lot_method, :symbol, :instrument_id, :created_at)`,
+		asset,
+	)
+	return err
+}
+
+// AllAssets получает активы всех пользователей
+func (s *PqStorage) AllAssets(ctx context.Context) ([]models.Asset, error) {
+	assets := []models.Asset{}
+	err := s.db.SelectContext(
+		ctx,
+		&assets,
+		`SELECT id, user_id, name, type, legacy_type, balance, currency, lot_method, symbol, instrument_id, created_at FROM assets ORDER BY user_id, created_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// AssetBalanceForUpdateTx получает баланс актива и блокирует строку до конца транзакции
+func (s *PqStorage) AssetBalanceForUpdateTx(ctx context.Context, tx Tx, assetID string) (float64, error) {
+	var balance float64
+	err := tx.GetContext(ctx, &balance, `SELECT balance FROM assets WHERE id = $1 FOR UPDATE`, assetID)
+	return balance, err
+}
+
+// SetAssetBalanceTx устанавливает баланс актива через транзакцию
+func (s *PqStorage) SetAssetBalanceTx(ctx context.Context, tx Tx, assetID string, balance float64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE assets SET balance = $1 WHERE id = $2`,
+		balance, assetID,
+	)
+	return err
+}
@@ -11,10 +11,10 @@ import (
 // SaveExchangeRate сохраняет курс валют в базу данных
 func (s *PqStorage) SaveExchangeRate(ctx context.Context, rate models.ExchangeRate) error {
 	const query = `
-		INSERT INTO exchange_rates (from_currency, to_currency, rate, timestamp)
-		VALUES (:from_currency, :to_currency, :rate, :timestamp)
+		INSERT INTO exchange_rates (from_currency, to_currency, rate, timestamp, provider)
+		VALUES (:from_currency, :to_currency, :rate, :timestamp, :provider)
 		ON CONFLICT (from_currency, to_currency, timestamp) 
-		DO UPDATE SET rate = EXCLUDED.rate, created_at = NOW()
+		DO UPDATE SET rate = EXCLUDED.rate, provider = EXCLUDED.provider, created_at = NOW()
 	`
 
 	_, err := s.db.NamedExecContext(ctx, query, rate)
@@ -79,3 +79,57 @@ func (s *PqStorage) GetLastExchangeRateUpdate(ctx context.Context) (*time.Time,
 
 	return &lastUpdate.Time, nil
 }
+
+// GetExchangeRatesInRange получает курсы валют за период (включительно)
+func (s *PqStorage) GetExchangeRatesInRange(ctx context.Context, from, to time.Time) ([]models.ExchangeRate, error) {
+	const query = `
+		SELECT id, from_currency, to_currency, rate, timestamp, provider, created_at
+		FROM exchange_rates
+		WHERE timestamp >= $1 AND timestamp <= $2
+		ORDER BY timestamp
+	`
+
+	rates := []models.ExchangeRate{}
+	err := s.db.SelectContext(ctx, &rates, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// GetExchangeRateOnOrBefore получает ближайший курс на дату или раньше
+func (s *PqStorage) GetExchangeRateOnOrBefore(ctx context.Context, fromCurrency, toCurrency string, date time.Time) (*models.ExchangeRate, error) {
+	const query = `
+		SELECT id, from_currency, to_currency, rate, timestamp, provider, created_at
+		FROM exchange_rates
+		WHERE from_currency = $1 AND to_currency = $2 AND timestamp <= $3
+		ORDER BY timestamp DESC, created_at DESC
+		LIMIT 1
+	`
+
+	var rate models.ExchangeRate
+	err := s.db.GetContext(ctx, &rate, query, fromCurrency, toCurrency, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rate, nil
+}
+
+// SaveExchangeRateIfMissingTx сохраняет курс, только если на эту дату его еще нет.
+// Возвращает true, если курс добавлен.
+func (s *PqStorage) SaveExchangeRateIfMissingTx(ctx context.Context, tx Tx, rate models.ExchangeRate) (bool, error) {
+	const query = `
+		INSERT INTO exchange_rates (from_currency, to_currency, rate, timestamp, provider)
+		VALUES (:from_currency, :to_currency, :rate, :timestamp, :provider)
+		ON CONFLICT (from_currency, to_currency, timestamp) DO NOTHING
+	`
+
+	result, err := tx.NamedExecContext(ctx, query, rate)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
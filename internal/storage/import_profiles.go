@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+
+	"brok/internal/models"
+)
+
+// CreateImportProfile сохраняет профиль импорта выписок
+func (s *PqStorage) CreateImportProfile(ctx context.Context, profile models.ImportProfile) error {
+	_, err := s.db.NamedExecContext(
+		ctx,
+		`INSERT INTO import_profiles (id, user_id, name, delimiter, date_column, date_format, type_column,
+			amount_column, currency_column, description_column, type_map, created_at)
+		VALUES (:id, :user_id, :name, :delimiter, :date_column, :date_format, :type_column,
+			:amount_column, :currency_column, :description_column, :type_map, :created_at)`,
+		profile,
+	)
+	return err
+}
+
+// ImportProfilesByUserID получает профили импорта пользователя
+func (s *PqStorage) ImportProfilesByUserID(ctx context.Context, userID string) ([]models.ImportProfile, error) {
+	rows, err := s.db.QueryxContext(
+		ctx,
+		`SELECT id, user_id, name, delimiter, date_column, date_format, type_column,
+			amount_column, currency_column, description_column, type_map, created_at
+		FROM import_profiles
+		WHERE user_id = $1
+		ORDER BY name`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []models.ImportProfile{}
+	for rows.Next() {
+		var profile models.ImportProfile
+		if err := rows.StructScan(&profile); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, rows.Err()
+}
+
+// ImportProfileByID получает профиль импорта пользователя
+func (s *PqStorage) ImportProfileByID(ctx context.Context, profileID, userID string) (*models.ImportProfile, error) {
+	var profile models.ImportProfile
+	err := s.db.GetContext(
+		ctx,
+		&profile,
+		`SELECT id, user_id, name, delimiter, date_column, date_format, type_column,
+			amount_column, currency_column, description_column, type_map, created_at
+		FROM import_profiles
+		WHERE id = $1 AND user_id = $2`,
+		profileID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// DeleteImportProfile удаляет профиль импорта пользователя, возвращает false, если его нет
+func (s *PqStorage) DeleteImportProfile(ctx context.Context, profileID, userID string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM import_profiles WHERE id = $1 AND user_id = $2`,
+		profileID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
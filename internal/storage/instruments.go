@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"brok/internal/models"
+)
+
+const instrumentColumns = `id, symbol, isin, name, asset_class, exchange, currency, lot_size, created_at, updated_at`
+
+// CreateInstrument добавляет инструмент в справочник
+func (s *PqStorage) CreateInstrument(ctx context.Context, instrument models.Instrument) error {
+	return s.CreateInstrumentTx(ctx, s.db, instrument)
+}
+
+// CreateInstrumentTx добавляет инструмент в справочник через транзакцию
+func (s *PqStorage) CreateInstrumentTx(ctx context.Context, tx Tx, instrument models.Instrument) error {
+	_, err := tx.NamedExecContext(
+		ctx,
+		`INSERT INTO instruments (`+instrumentColumns+`)
+		VALUES (:id, :symbol, :isin, :name, :asset_class, :exchange, :currency, :lot_size, :created_at, :updated_at)`,
+		instrument,
+	)
+	return err
+}
+
+// UpdateInstrument сохраняет изменяемые поля инструмента
+func (s *PqStorage) UpdateInstrument(ctx context.Context, instrument models.Instrument) error {
+	return s.UpdateInstrumentTx(ctx, s.db, instrument)
+}
+
+// UpdateInstrumentTx сохраняет изменяемые поля инструмента через транзакцию
+func (s *PqStorage) UpdateInstrumentTx(ctx context.Context, tx Tx, instrument models.Instrument) error {
+	_, err := tx.NamedExecContext(
+		ctx,
+		`UPDATE instruments
+		SET symbol = :symbol, isin = :isin, name = :name, asset_class = :asset_class, exchange = :exchange,
+			currency = :currency, lot_size = :lot_size, updated_at = :updated_at
+		WHERE id = :id`,
+		instrument,
+	)
+	return err
+}
+
+// InstrumentByID получает инструмент по ID
+func (s *PqStorage) InstrumentByID(ctx context.Context, instrumentID string) (*models.Instrument, error) {
+	var instrument models.Instrument
+	err := s.db.GetContext(
+		ctx,
+		&instrument,
+		`SELECT `+instrumentColumns+` FROM instruments WHERE id = $1`,
+		instrumentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &instrument, nil
+}
+
+// AllInstruments получает весь справочник (для сверки при загрузке из файла)
+func (s *PqStorage) AllInstruments(ctx context.Context) ([]models.Instrument, error) {
+	instruments := []models.Instrument{}
+	err := s.db.SelectContext(
+		ctx,
+		&instruments,
+		`SELECT `+instrumentColumns+` FROM instruments ORDER BY symbol, exchange`,
+	)
+	return instruments, err
+}
+
+// Instruments ищет инструменты по фильтру
+func (s *PqStorage) Instruments(ctx context.Context, filter models.InstrumentFilter) ([]models.Instrument, error) {
+	var (
+		conditions = []string{"TRUE"}
+		args       []any
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+		placeholder := arg(pattern)
+		conditions = append(conditions, fmt.Sprintf("(symbol ILIKE %[1]s OR isin ILIKE %[1]s OR name ILIKE %[1]s)", placeholder))
+	}
+	if filter.AssetClass != "" {
+		conditions = append(conditions, "asset_class = "+arg(filter.AssetClass))
+	}
+	if filter.Exchange != "" {
+		conditions = append(conditions, "exchange = "+arg(filter.Exchange))
+	}
+
+	query := fmt.Sprintf(
+		`SELECT `+instrumentColumns+`
+		FROM instruments
+		WHERE %s
+		ORDER BY symbol, exchange
+		LIMIT %s`,
+		strings.Join(conditions, " AND "), arg(filter.Limit),
+	)
+
+	instruments := []models.Instrument{}
+	err := s.db.SelectContext(ctx, &instruments, query, args...)
+	return instruments, err
+}
+
+// DeleteInstrument удаляет инструмент; ссылки активов и транзакций на него обнуляются.
+// Возвращает false, если инструмента нет.
+func (s *PqStorage) DeleteInstrument(ctx context.Context, instrumentID string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM instruments WHERE id = $1`,
+		instrumentID,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// IsInstrumentConflict проверяет, занят ли тикер на бирже или ISIN другим инструментом
+func (s *PqStorage) IsInstrumentConflict(ctx context.Context, instrument models.Instrument) (bool, error) {
+	var exists bool
+	err := s.db.GetContext(
+		ctx,
+		&exists,
+		`SELECT EXISTS (
+			SELECT 1 FROM instruments
+			WHERE id <> $1 AND ((symbol = $2 AND exchange = $3) OR isin = $4)
+		)`,
+		instrument.ID, instrument.Symbol, instrument.Exchange, instrument.ISIN,
+	)
+	return exists, err
+}
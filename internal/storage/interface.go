@@ -18,33 +18,132 @@ type Storage interface {
 	UserByID(ctx context.Context, userID string) (*models.User, error)
 	UserCreate(ctx context.Context, user *models.UserWithPassword) error
 	UserSet(ctx context.Context, user *models.User) error
+	UserDelete(ctx context.Context, userID string) (bool, error)
+	AdminsCount(ctx context.Context) (int, error)
+	UserSettingsSetTx(ctx context.Context, tx Tx, userID, baseCurrency, lotMethod string) error
+	UserWithPasswordByID(ctx context.Context, userID string) (*models.UserWithPassword, error)
+	UserPasswordSet(ctx context.Context, userID, passwordHash string) error
+	UserPasswordSetTx(ctx context.Context, tx Tx, userID, passwordHash string) error
+	VerifyUserEmail(ctx context.Context, userID, email string) (bool, error)
+
+	// sessions
+	CreateSession(ctx context.Context, session models.Session) error
+	SessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error)
+	RotateSession(ctx context.Context, sessionID, oldHash, newHash string, expiresAt time.Time, userAgent, ip string) (bool, error)
+	ActiveSessions(ctx context.Context, userID string) ([]models.Session, error)
+	RevokeSession(ctx context.Context, sessionID, userID string) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID string) (int64, error)
+	RevokeUserSessionsTx(ctx context.Context, tx Tx, userID string) (int64, error)
+	RevokeUserSessionsExcept(ctx context.Context, userID, sessionID string) (int64, error)
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+	UseOneTimeToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
+	UseOneTimeTokenTx(ctx context.Context, tx Tx, jti string, expiresAt time.Time) (bool, error)
+
+	// two-factor
+	TwoFactorByUserID(ctx context.Context, userID string) (*models.TwoFactor, error)
+	SetTwoFactorSecret(ctx context.Context, userID, secret string) (bool, error)
+	EnableTwoFactorTx(ctx context.Context, tx Tx, userID, secret string, step int64) (bool, error)
+	DisableTwoFactorTx(ctx context.Context, tx Tx, userID string) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	FailTwoFactorCode(ctx context.Context, userID string, maxFailures int, lockout time.Duration) error
+	ResetTwoFactorFailures(ctx context.Context, userID string) error
+	ReplaceRecoveryCodesTx(ctx context.Context, tx Tx, userID string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	RecoveryCodesLeft(ctx context.Context, userID string) (int, error)
+	CreateTwoFactorChallenge(ctx context.Context, challenge models.TwoFactorChallenge) error
+	TwoFactorChallengeByHash(ctx context.Context, tokenHash string) (*models.TwoFactorChallenge, error)
+	FailTwoFactorChallenge(ctx context.Context, challengeID string, maxAttempts int) error
+	DeleteTwoFactorChallenge(ctx context.Context, challengeID string) (bool, error)
 
 	// asset
 	AssetsByUserId(ctx context.Context, userID string) ([]models.Asset, error)
+	AssetByID(ctx context.Context, assetID string) (*models.Asset, error)
 	AssetSet(ctx context.Context, asset models.Asset) error
+	CreateAssetTx(ctx context.Context, tx Tx, asset models.Asset) error
+	SetAssetLotMethod(ctx context.Context, assetID string, method *string) error
+	SetAssetSymbol(ctx context.Context, assetID string, symbol *string) error
+	SetAssetInstrument(ctx context.Context, assetID string, instrumentID *string) error
 	DeleteAsset(ctx context.Context, assetID string) error
+	DeleteAssetTx(ctx context.Context, tx Tx, assetID string) error
 	IsAssetOwnedByUser(ctx context.Context, assetID string, userID string) (bool, error)
 	UpdateAssetBalance(ctx context.Context, assetID string, balanceChange float64) error
 	UpdateAssetBalanceTx(ctx context.Context, tx Tx, assetID string, balanceChange float64) error
+	AllAssets(ctx context.Context) ([]models.Asset, error)
+	AssetBalanceForUpdateTx(ctx context.Context, tx Tx, assetID string) (float64, error)
+	SetAssetBalanceTx(ctx context.Context, tx Tx, assetID string, balance float64) error
 
 	// transaction
 	GetTransactionsByAssetID(ctx context.Context, assetID string) ([]models.Transaction, error)
 	GetTransactionsByAssetIDTx(ctx context.Context, tx Tx, assetID string) ([]models.Transaction, error)
+	ListTransactions(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error)
 	CreateTransaction(ctx context.Context, transaction models.Transaction) error
 	DeleteTransaction(ctx context.Context, transactionID string) error
 	IsTransactionOwnedByUser(ctx context.Context, transactionID string, userID string) (bool, error)
 	DeleteTransactionsByAssetID(ctx context.Context, assetID string) error
 	CreateTransactionTx(ctx context.Context, tx Tx, transaction models.Transaction) error
 	DeleteTransactionTx(ctx context.Context, tx Tx, transactionID string) error
+	UpdateTransactionTx(ctx context.Context, tx Tx, transaction models.Transaction) error
 	GetTransactionByID(ctx context.Context, transactionID string) (*models.Transaction, error)
 	GetTransactionByIDTx(ctx context.Context, tx Tx, transactionID string) (*models.Transaction, error)
+	GetTransactionByIDForUpdateTx(ctx context.Context, tx Tx, transactionID string) (*models.Transaction, error)
 	DeleteTransactionsByAssetIDTx(ctx context.Context, tx Tx, assetID string) error
 
+	// transfers
+	CreateTransferTx(ctx context.Context, tx Tx, transfer models.Transfer) error
+	DeleteTransferTx(ctx context.Context, tx Tx, transferID string) error
+	GetTransactionsByTransferIDTx(ctx context.Context, tx Tx, transferID string) ([]models.Transaction, error)
+	GetTransfersByUserID(ctx context.Context, userID string) ([]models.Transfer, error)
+
+	// import profiles
+	CreateImportProfile(ctx context.Context, profile models.ImportProfile) error
+	ImportProfilesByUserID(ctx context.Context, userID string) ([]models.ImportProfile, error)
+	ImportProfileByID(ctx context.Context, profileID, userID string) (*models.ImportProfile, error)
+	DeleteImportProfile(ctx context.Context, profileID, userID string) (bool, error)
+
+	// schedules
+	CreateSchedule(ctx context.Context, schedule models.Schedule) error
+	SchedulesByUserID(ctx context.Context, userID string) ([]models.Schedule, error)
+	ActiveSchedules(ctx context.Context) ([]models.Schedule, error)
+	ScheduleByID(ctx context.Context, scheduleID, userID string) (*models.Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule models.Schedule) error
+	DeleteSchedule(ctx context.Context, scheduleID, userID string) (bool, error)
+	CreateScheduleRunTx(ctx context.Context, tx Tx, scheduleID string, occurrence time.Time, transactionID string) (bool, error)
+	SetScheduleLastOccurrenceTx(ctx context.Context, tx Tx, scheduleID string, occurrence time.Time) error
+
+	// lots
+	CreateLotMatchTx(ctx context.Context, tx Tx, match models.LotMatch) error
+	GetLotMatchesByAssetID(ctx context.Context, assetID string) ([]models.LotMatch, error)
+	GetLotMatchesByAssetIDTx(ctx context.Context, tx Tx, assetID string) ([]models.LotMatch, error)
+	IsLotMatchedTx(ctx context.Context, tx Tx, buyTransactionID string) (bool, error)
+	DeleteLotMatchesBySellTx(ctx context.Context, tx Tx, sellTransactionID string) error
+
 	// exchange rates
 	SaveExchangeRate(ctx context.Context, rate models.ExchangeRate) error
 	GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string, date time.Time) (float64, error)
 	GetLatestExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error)
+	SaveExchangeRateIfMissingTx(ctx context.Context, tx Tx, rate models.ExchangeRate) (bool, error)
+	GetExchangeRateOnOrBefore(ctx context.Context, fromCurrency, toCurrency string, date time.Time) (*models.ExchangeRate, error)
 	GetLastExchangeRateUpdate(ctx context.Context) (*time.Time, error)
+	GetExchangeRatesInRange(ctx context.Context, from, to time.Time) ([]models.ExchangeRate, error)
+
+	// instruments
+	CreateInstrument(ctx context.Context, instrument models.Instrument) error
+	CreateInstrumentTx(ctx context.Context, tx Tx, instrument models.Instrument) error
+	UpdateInstrument(ctx context.Context, instrument models.Instrument) error
+	UpdateInstrumentTx(ctx context.Context, tx Tx, instrument models.Instrument) error
+	InstrumentByID(ctx context.Context, instrumentID string) (*models.Instrument, error)
+	AllInstruments(ctx context.Context) ([]models.Instrument, error)
+	Instruments(ctx context.Context, filter models.InstrumentFilter) ([]models.Instrument, error)
+	DeleteInstrument(ctx context.Context, instrumentID string) (bool, error)
+	IsInstrumentConflict(ctx context.Context, instrument models.Instrument) (bool, error)
+
+	// prices
+	SavePrice(ctx context.Context, price models.Price) error
+	GetLatestPrice(ctx context.Context, symbol string) (*models.Price, error)
+	GetPrices(ctx context.Context, symbol string, from, to time.Time) ([]models.Price, error)
+	PriceSymbols(ctx context.Context) ([]string, error)
+	AssetsWithSymbol(ctx context.Context) ([]models.Asset, error)
 
 	// служебные
 	Transaction(ctx context.Context, f TxFunc) (err error)
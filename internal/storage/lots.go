@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+
+	"brok/internal/models"
+)
+
+// CreateLotMatchTx сохраняет закрытие лота через транзакцию
+func (s *PqStorage) CreateLotMatchTx(ctx context.Context, tx Tx, match models.LotMatch) error {
+	_, err := tx.NamedExecContext(
+		ctx,
+		`INSERT INTO lot_matches (id, asset_id, sell_transaction_id, buy_transaction_id, ticker, method,
+			quantity, cost_basis, proceeds, realized_gain, acquired_at, disposed_at)
+		VALUES (:id, :asset_id, :sell_transaction_id, :buy_transaction_id, :ticker, :method,
+			:quantity, :cost_basis, :proceeds, :realized_gain, :acquired_at, :disposed_at)`,
+		match,
+	)
+	return err
+}
+
+// GetLotMatchesByAssetID получает все закрытия лотов актива
+func (s *PqStorage) GetLotMatchesByAssetID(ctx context.Context, assetID string) ([]models.LotMatch, error) {
+	return s.GetLotMatchesByAssetIDTx(ctx, s.db, assetID)
+}
+
+// GetLotMatchesByAssetIDTx получает все закрытия лотов актива через транзакцию
+func (s *PqStorage) GetLotMatchesByAssetIDTx(ctx context.Context, tx Tx, assetID string) ([]models.LotMatch, error) {
+	rows, err := tx.QueryxContext(
+		ctx,
+		`SELECT id, asset_id, sell_transaction_id, buy_transaction_id, ticker, method,
+			quantity, cost_basis, proceeds, realized_gain, acquired_at, disposed_at
+		FROM lot_matches
+		WHERE asset_id = $1
+		ORDER BY disposed_at, acquired_at`,
+		assetID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []models.LotMatch{}
+	for rows.Next() {
+		var match models.LotMatch
+		if err := rows.StructScan(&match); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+
+	return matches, rows.Err()
+}
+
+// IsLotMatchedTx проверяет, закрыта ли продажами часть лота (покупки)
+func (s *PqStorage) IsLotMatchedTx(ctx context.Context, tx Tx, buyTransactionID string) (bool, error) {
+	var exists bool
+	err := tx.GetContext(
+		ctx,
+		&exists,
+		`SELECT EXISTS (SELECT 1 FROM lot_matches WHERE buy_transaction_id = $1)`,
+		buyTransactionID,
+	)
+	return exists, err
+}
+
+// DeleteLotMatchesBySellTx удаляет закрытия лотов продажи через транзакцию
+func (s *PqStorage) DeleteLotMatchesBySellTx(ctx context.Context, tx Tx, sellTransactionID string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM lot_matches WHERE sell_transaction_id = $1`, sellTransactionID)
+	return err
+}
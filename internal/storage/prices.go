@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"brok/internal/models"
+)
+
+// SavePrice сохраняет цену закрытия; повторная загрузка за ту же дату перезаписывает цену
+func (s *PqStorage) SavePrice(ctx context.Context, price models.Price) error {
+	_, err := s.db.NamedExecContext(
+		ctx,
+		`INSERT INTO prices (symbol, date, close, currency, provider)
+		VALUES (:symbol, :date, :close, :currency, :provider)
+		ON CONFLICT (symbol, date) DO UPDATE
+		SET close = excluded.close, currency = excluded.currency,
+			provider = excluded.provider, created_at = now()`,
+		price,
+	)
+	return err
+}
+
+// GetLatestPrice получает последнюю известную цену инструмента
+func (s *PqStorage) GetLatestPrice(ctx context.Context, symbol string) (*models.Price, error) {
+	var price models.Price
+	err := s.db.GetContext(
+		ctx,
+		&price,
+		`SELECT symbol, date, close, currency, provider, created_at
+		FROM prices
+		WHERE symbol = $1
+		ORDER BY date DESC
+		LIMIT 1`,
+		symbol,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+// GetPrices получает цены инструмента за период по возрастанию даты
+func (s *PqStorage) GetPrices(ctx context.Context, symbol string, from, to time.Time) ([]models.Price, error) {
+	prices := []models.Price{}
+	err := s.db.SelectContext(
+		ctx,
+		&prices,
+		`SELECT symbol, date, close, currency, provider, created_at
+		FROM prices
+		WHERE symbol = $1 AND date BETWEEN $2 AND $3
+		ORDER BY date`,
+		symbol, from, to,
+	)
+	return prices, err
+}
+
+// PriceSymbols инструменты, цены которых нужны: привязанные к активам и встречающиеся в сделках
+func (s *PqStorage) PriceSymbols(ctx context.Context) ([]string, error) {
+	symbols := []string{}
+	err := s.db.SelectContext(
+		ctx,
+		&symbols,
+		`SELECT symbol FROM assets WHERE symbol IS NOT NULL
+		UNION
+		SELECT ticker FROM transactions WHERE ticker <> '' AND quantity > 0 AND type IN ('buy', 'sell')
+		ORDER BY 1`,
+	)
+	return symbols, err
+}
+
+// AssetsWithSymbol получает активы всех пользователей, привязанные к инструменту
+func (s *PqStorage) AssetsWithSymbol(ctx context.Context) ([]models.Asset, error) {
+	assets := []models.Asset{}
+	err := s.db.SelectContext(
+		ctx,
+		&assets,
+		`SELECT id, user_id, name, type, legacy_type, balance, currency, lot_method, symbol, instrument_id, created_at
+		FROM assets
+		WHERE symbol IS NOT NULL
+		ORDER BY user_id, created_at`,
+	)
+	return assets, err
+}
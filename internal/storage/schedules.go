@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"brok/internal/models"
+)
+
+const scheduleColumns = `id, user_id, asset_id, amount, currency, type, description, frequency,
+	start_date, end_date, timezone, active, last_occurrence, resumed_at, created_at`
+
+// CreateSchedule сохраняет расписание регулярной транзакции
+func (s *PqStorage) CreateSchedule(ctx context.Context, schedule models.Schedule) error {
+	_, err := s.db.NamedExecContext(
+		ctx,
+		`INSERT INTO schedules (id, user_id, asset_id, amount, currency, type, description, frequency,
+			start_date, end_date, timezone, active, last_occurrence, resumed_at, created_at)
+		VALUES (:id, :user_id, :asset_id, :amount, :currency, :type, :description, :frequency,
+			:start_date, :end_date, :timezone, :active, :last_occurrence, :resumed_at, :created_at)`,
+		schedule,
+	)
+	return err
+}
+
+// SchedulesByUserID получает расписания пользователя
+func (s *PqStorage) SchedulesByUserID(ctx context.Context, userID string) ([]models.Schedule, error) {
+	schedules := []models.Schedule{}
+	err := s.db.SelectContext(
+		ctx,
+		&schedules,
+		`SELECT `+scheduleColumns+`
+		FROM schedules
+		WHERE user_id = $1
+		ORDER BY created_at, id`,
+		userID,
+	)
+	return schedules, err
+}
+
+// ActiveSchedules получает активные расписания всех пользователей
+func (s *PqStorage) ActiveSchedules(ctx context.Context) ([]models.Schedule, error) {
+	schedules := []models.Schedule{}
+	err := s.db.SelectContext(
+		ctx,
+		&schedules,
+		`SELECT `+scheduleColumns+`
+		FROM schedules
+		WHERE active
+		ORDER BY start_date, id`,
+	)
+	return schedules, err
+}
+
+// ScheduleByID получает расписание пользователя
+func (s *PqStorage) ScheduleByID(ctx context.Context, scheduleID, userID string) (*models.Schedule, error) {
+	var schedule models.Schedule
+	err := s.db.GetContext(
+		ctx,
+		&schedule,
+		`SELECT `+scheduleColumns+`
+		FROM schedules
+		WHERE id = $1 AND user_id = $2`,
+		scheduleID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// UpdateSchedule сохраняет изменяемые поля расписания
+func (s *PqStorage) UpdateSchedule(ctx context.Context, schedule models.Schedule) error {
+	_, err := s.db.NamedExecContext(
+		ctx,
+		`UPDATE schedules
+		SET amount = :amount, currency = :currency, type = :type, description = :description,
+			frequency = :frequency, end_date = :end_date, timezone = :timezone, active = :active,
+			resumed_at = :resumed_at
+		WHERE id = :id AND user_id = :user_id`,
+		schedule,
+	)
+	return err
+}
+
+// DeleteSchedule удаляет расписание пользователя, возвращает false, если его нет.
+// Уже созданные по расписанию транзакции остаются.
+func (s *PqStorage) DeleteSchedule(ctx context.Context, scheduleID, userID string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM schedules WHERE id = $1 AND user_id = $2`,
+		scheduleID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// CreateScheduleRunTx отмечает наступление расписания как выполненное созданной транзакцией.
+// Возвращает false, если наступление уже было обработано.
+func (s *PqStorage) CreateScheduleRunTx(ctx context.Context, tx Tx, scheduleID string, occurrence time.Time, transactionID string) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO schedule_runs (schedule_id, occurrence, transaction_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (schedule_id, occurrence) DO NOTHING`,
+		scheduleID, occurrence, transactionID,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// SetScheduleLastOccurrenceTx запоминает последнее обработанное наступление расписания
+func (s *PqStorage) SetScheduleLastOccurrenceTx(ctx context.Context, tx Tx, scheduleID string, occurrence time.Time) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE schedules
+		SET last_occurrence = GREATEST(COALESCE(last_occurrence, $2), $2)
+		WHERE id = $1`,
+		scheduleID, occurrence,
+	)
+	return err
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"brok/internal/models"
+)
+
+// CreateSession сохраняет новую сессию
+func (s *PqStorage) CreateSession(ctx context.Context, session models.Session) error {
+	_, err := s.db.NamedExecContext(
+		ctx,
+		`INSERT INTO sessions (id, user_id, refresh_token_hash, user_agent, ip, created_at, last_used_at, expires_at)
+		VALUES (:id, :user_id, :refresh_token_hash, :user_agent, :ip, :created_at, :last_used_at, :expires_at)`,
+		session,
+	)
+	return err
+}
+
+// SessionByTokenHash ищет сессию по хэшу текущего или предыдущего refresh-токена
+func (s *PqStorage) SessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	var session models.Session
+	err := s.db.GetContext(
+		ctx,
+		&session,
+		`SELECT id, user_id, refresh_token_hash, previous_token_hash, user_agent, ip, created_at, last_used_at, expires_at, revoked_at
+		FROM sessions
+		WHERE refresh_token_hash = $1 OR previous_token_hash = $1
+		LIMIT 1`,
+		tokenHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateSession заменяет refresh-токен сессии, если он не был заменен параллельно
+func (s *PqStorage) RotateSession(ctx context.Context, sessionID, oldHash, newHash string, expiresAt time.Time, userAgent, ip string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE sessions
+		SET previous_token_hash = refresh_token_hash, refresh_token_hash = $3,
+			last_used_at = now(), expires_at = $4, user_agent = $5, ip = $6
+		WHERE id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL`,
+		sessionID, oldHash, newHash, expiresAt, userAgent, ip,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ActiveSessions возвращает неотозванные и не истекшие сессии пользователя, последние — первыми
+func (s *PqStorage) ActiveSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := s.db.SelectContext(
+		ctx,
+		&sessions,
+		`SELECT id, user_id, refresh_token_hash, previous_token_hash, user_agent, ip, created_at, last_used_at, expires_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_used_at DESC`,
+		userID,
+	)
+	return sessions, err
+}
+
+// RevokeSession отзывает сессию пользователя
+func (s *PqStorage) RevokeSession(ctx context.Context, sessionID, userID string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		sessionID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RevokeUserSessions отзывает все сессии пользователя и возвращает их количество
+func (s *PqStorage) RevokeUserSessions(ctx context.Context, userID string) (int64, error) {
+	return s.RevokeUserSessionsTx(ctx, s.db, userID)
+}
+
+// RevokeUserSessionsTx отзывает все сессии пользователя через транзакцию
+func (s *PqStorage) RevokeUserSessionsTx(ctx context.Context, tx Tx, userID string) (int64, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`,
+		userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RevokeUserSessionsExcept отзывает все сессии пользователя, кроме sessionID, и возвращает их количество
+func (s *PqStorage) RevokeUserSessionsExcept(ctx context.Context, userID, sessionID string) (int64, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`,
+		userID, sessionID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RevokeToken отзывает access-токен до истечения его срока; заодно удаляет истекшие записи
+func (s *PqStorage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return s.Transaction(ctx, func(ctx context.Context, tx Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < now()`); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`,
+			jti, expiresAt,
+		)
+		return err
+	})
+}
+
+// IsTokenRevoked проверяет, отозван ли access-токен или сессия, которой он выдан
+func (s *PqStorage) IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	var revoked bool
+	err := s.db.GetContext(
+		ctx,
+		&revoked,
+		`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+			OR NOT EXISTS (SELECT 1 FROM sessions WHERE id = $2 AND revoked_at IS NULL)`,
+		jti, sessionID,
+	)
+	return revoked, err
+}
+
+// UseOneTimeToken отмечает токен из письма использованным; false — он уже был использован
+func (s *PqStorage) UseOneTimeToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	return s.UseOneTimeTokenTx(ctx, s.db, jti, expiresAt)
+}
+
+// UseOneTimeTokenTx погашает одноразовый токен через транзакцию; false — токен уже использован
+func (s *PqStorage) UseOneTimeTokenTx(ctx context.Context, tx Tx, jti string, expiresAt time.Time) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`,
+		jti, expiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
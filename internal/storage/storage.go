@@ -1,9 +1,15 @@
 package storage
 
 import (
+	"errors"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+// pqUniqueViolation код ошибки PostgreSQL unique_violation
+const pqUniqueViolation = "23505"
+
 type PqStorage struct {
 	db *sqlx.DB
 }
@@ -21,3 +27,9 @@ func (s *PqStorage) Check() (any, error) {
 
 	return true, nil
 }
+
+// IsUniqueViolation сообщает, что запись нарушила ограничение уникальности
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
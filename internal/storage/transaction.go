@@ -3,6 +3,10 @@ package storage
 import (
 	"brok/internal/models"
 	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 func (s *PqStorage) DeleteTransactionsByAssetID(ctx context.Context, assetID string) error {
@@ -26,9 +30,13 @@ func (s *PqStorage) GetTransactionsByAssetIDTx(ctx context.Context, tx Tx, asset
 	transactions := []models.Transaction{}
 
 	rows, err := tx.QueryxContext(ctx,
-		`SELECT id, asset_id, amount, currency, type, description, timestamp 
-		FROM transactions 
-		WHERE asset_id = $1`,
+		`SELECT id, asset_id, amount, currency, type, description, timestamp, ticker, quantity, price,
+			original_amount, original_currency, exchange_rate, transfer_id, instrument_id,
+			income_kind, gross_amount, withholding_tax, ex_date, pay_date,
+			fee, original_fee, original_fee_currency
+		FROM transactions
+		WHERE asset_id = $1
+		ORDER BY timestamp, id`,
 		assetID)
 	if err != nil {
 		return nil, err
@@ -53,8 +61,14 @@ func (s *PqStorage) CreateTransaction(ctx context.Context, transaction models.Tr
 func (s *PqStorage) CreateTransactionTx(ctx context.Context, tx Tx, transaction models.Transaction) error {
 	_, err := tx.NamedExecContext(
 		ctx,
-		`INSERT INTO transactions (id, asset_id, amount, currency, type, description, timestamp)
-		VALUES (:id, :asset_id, :amount, :currency, :type, :description, :timestamp)`,
+		`INSERT INTO transactions (id, asset_id, amount, currency, type, description, timestamp, ticker, quantity, price,
+			original_amount, original_currency, exchange_rate, transfer_id, instrument_id,
+			income_kind, gross_amount, withholding_tax, ex_date, pay_date,
+			fee, original_fee, original_fee_currency)
+		VALUES (:id, :asset_id, :amount, :currency, :type, :description, :timestamp, :ticker, :quantity, :price,
+			:original_amount, :original_currency, :exchange_rate, :transfer_id, :instrument_id,
+			:income_kind, :gross_amount, :withholding_tax, :ex_date, :pay_date,
+			:fee, :original_fee, :original_fee_currency)`,
 		transaction,
 	)
 	return err
@@ -82,7 +96,31 @@ func (s *PqStorage) GetTransactionByIDTx(ctx context.Context, tx Tx, transaction
 	err := tx.GetContext(
 		ctx,
 		&transaction,
-		`SELECT id, asset_id, amount, currency, type, description, timestamp FROM transactions WHERE id = $1`,
+		`SELECT id, asset_id, amount, currency, type, description, timestamp, ticker, quantity, price,
+			original_amount, original_currency, exchange_rate, transfer_id, instrument_id,
+			income_kind, gross_amount, withholding_tax, ex_date, pay_date,
+			fee, original_fee, original_fee_currency
+		FROM transactions WHERE id = $1`,
+		transactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// GetTransactionByIDForUpdateTx получает транзакцию и блокирует ее строку до конца транзакции БД
+func (s *PqStorage) GetTransactionByIDForUpdateTx(ctx context.Context, tx Tx, transactionID string) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := tx.GetContext(
+		ctx,
+		&transaction,
+		`SELECT id, asset_id, amount, currency, type, description, timestamp, ticker, quantity, price,
+			original_amount, original_currency, exchange_rate, transfer_id, instrument_id,
+			income_kind, gross_amount, withholding_tax, ex_date, pay_date,
+			fee, original_fee, original_fee_currency
+		FROM transactions WHERE id = $1
+		FOR UPDATE`,
 		transactionID,
 	)
 	if err != nil {
@@ -104,3 +142,113 @@ func (s *PqStorage) IsTransactionOwnedByUser(ctx context.Context, transactionID
 	)
 	return exists, err
 }
+
+// ListTransactions получает транзакции пользователя по фильтру с постраничным выводом по ключу
+// (значение поля сортировки + id). Возвращает не больше filter.Limit записей, 0 — без ограничения.
+func (s *PqStorage) ListTransactions(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error) {
+	var (
+		conditions = []string{"a.user_id = $1"}
+		args       = []any{filter.UserID}
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.AssetID != "" {
+		conditions = append(conditions, "t.asset_id = "+arg(filter.AssetID))
+	}
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, "t.type = ANY("+arg(pq.Array(filter.Types))+")")
+	}
+	if len(filter.Currencies) > 0 {
+		conditions = append(conditions, "t.currency = ANY("+arg(pq.Array(filter.Currencies))+")")
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "t.timestamp >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "t.timestamp <= "+arg(*filter.To))
+	}
+	if filter.MinAmount != nil {
+		conditions = append(conditions, "t.amount >= "+arg(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, "t.amount <= "+arg(*filter.MaxAmount))
+	}
+	if filter.Description != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Description) + "%"
+		conditions = append(conditions, "t.description ILIKE "+arg(pattern))
+	}
+
+	column := "t.timestamp"
+	if filter.SortField == models.TransactionSortAmount {
+		column = "t.amount"
+	}
+	direction, compare := "ASC", ">"
+	if filter.Descending {
+		direction, compare = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var value any
+		if filter.SortField == models.TransactionSortAmount {
+			value = *filter.After.Amount
+		} else {
+			value = *filter.After.Timestamp
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, t.id) %s (%s, %s)", column, compare, arg(value), arg(filter.After.ID)))
+	}
+
+	query := fmt.Sprintf(
+		`SELECT t.id, t.asset_id, t.amount, t.currency, t.type, t.description, t.timestamp, t.ticker, t.quantity, t.price,
+			t.original_amount, t.original_currency, t.exchange_rate, t.transfer_id, t.instrument_id,
+			t.income_kind, t.gross_amount, t.withholding_tax, t.ex_date, t.pay_date,
+			t.fee, t.original_fee, t.original_fee_currency
+		FROM transactions t
+		JOIN assets a ON a.id = t.asset_id
+		WHERE %s
+		ORDER BY %s %s, t.id %s`,
+		strings.Join(conditions, " AND "), column, direction, direction,
+	)
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := s.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := rows.StructScan(&transaction); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rows.Err()
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// UpdateTransactionTx обновляет транзакцию через транзакцию
+func (s *PqStorage) UpdateTransactionTx(ctx context.Context, tx Tx, transaction models.Transaction) error {
+	_, err := tx.NamedExecContext(
+		ctx,
+		`UPDATE transactions
+		SET asset_id = :asset_id, amount = :amount, currency = :currency, type = :type, description = :description,
+			timestamp = :timestamp, ticker = :ticker, quantity = :quantity, price = :price,
+			original_amount = :original_amount, original_currency = :original_currency, exchange_rate = :exchange_rate,
+			income_kind = :income_kind, gross_amount = :gross_amount, withholding_tax = :withholding_tax,
+			ex_date = :ex_date, pay_date = :pay_date,
+			fee = :fee, original_fee = :original_fee, original_fee_currency = :original_fee_currency
+		WHERE id = :id`,
+		transaction,
+	)
+	return err
+}
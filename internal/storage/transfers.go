@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+
+	"brok/internal/models"
+)
+
+// CreateTransferTx сохраняет перевод через транзакцию (ноги создаются отдельно)
+func (s *PqStorage) CreateTransferTx(ctx context.Context, tx Tx, transfer models.Transfer) error {
+	_, err := tx.NamedExecContext(
+		ctx,
+		`INSERT INTO transfers (id, user_id, amount, currency, target_amount, target_currency, exchange_rate, fee, description, timestamp, created_at)
+		VALUES (:id, :user_id, :amount, :currency, :target_amount, :target_currency, :exchange_rate, :fee, :description, :timestamp, :created_at)`,
+		transfer,
+	)
+	return err
+}
+
+// DeleteTransferTx удаляет перевод вместе с его ногами
+func (s *PqStorage) DeleteTransferTx(ctx context.Context, tx Tx, transferID string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM transfers WHERE id = $1`, transferID)
+	return err
+}
+
+// GetTransactionsByTransferIDTx получает ноги перевода через транзакцию
+func (s *PqStorage) GetTransactionsByTransferIDTx(ctx context.Context, tx Tx, transferID string) ([]models.Transaction, error) {
+	transactions := []models.Transaction{}
+
+	rows, err := tx.QueryxContext(
+		ctx,
+		`SELECT id, asset_id, amount, currency, type, description, timestamp, ticker, quantity, price,
+			original_amount, original_currency, exchange_rate, transfer_id, instrument_id,
+			income_kind, gross_amount, withholding_tax, ex_date, pay_date,
+			fee, original_fee, original_fee_currency
+		FROM transactions
+		WHERE transfer_id = $1
+		ORDER BY type DESC`,
+		transferID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := rows.StructScan(&transaction); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, nil
+}
+
+// GetTransfersByUserID получает переводы пользователя
+func (s *PqStorage) GetTransfersByUserID(ctx context.Context, userID string) ([]models.Transfer, error) {
+	transfers := []models.Transfer{}
+	err := s.db.SelectContext(
+		ctx,
+		&transfers,
+		`SELECT id, user_id, amount, currency, target_amount, target_currency, exchange_rate, fee, description, timestamp, created_at
+		FROM transfers
+		WHERE user_id = $1
+		ORDER BY timestamp`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"brok/internal/models"
+)
+
+// TwoFactorByUserID возвращает состояние TOTP пользователя
+func (s *PqStorage) TwoFactorByUserID(ctx context.Context, userID string) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+	err := s.db.GetContext(
+		ctx,
+		&twoFactor,
+		`SELECT id, email, totp_secret, totp_enabled_at, totp_last_step, totp_locked_until FROM users WHERE id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+// SetTwoFactorSecret записывает неподтвержденный секрет TOTP, если 2FA еще не включена
+func (s *PqStorage) SetTwoFactorSecret(ctx context.Context, userID, secret string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET totp_secret = $2, totp_last_step = 0 WHERE id = $1 AND totp_enabled_at IS NULL`,
+		userID, secret,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// EnableTwoFactorTx включает 2FA с записанным секретом, запоминая шаг первого кода
+func (s *PqStorage) EnableTwoFactorTx(ctx context.Context, tx Tx, userID, secret string, step int64) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE users SET totp_enabled_at = now(), totp_last_step = $3
+		WHERE id = $1 AND totp_secret = $2 AND totp_enabled_at IS NULL`,
+		userID, secret, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// DisableTwoFactorTx выключает 2FA и удаляет коды восстановления
+func (s *PqStorage) DisableTwoFactorTx(ctx context.Context, tx Tx, userID string) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = $1`,
+		userID,
+	); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	return err
+}
+
+// UseTOTPStep отмечает шаг TOTP использованным; false — этот или более поздний шаг уже принят
+func (s *PqStorage) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2`,
+		userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// FailTwoFactorCode учитывает неверный код пользователя; после maxFailures кодов подряд
+// коды не принимаются в течение lockout, а счетчик начинается заново
+func (s *PqStorage) FailTwoFactorCode(ctx context.Context, userID string, maxFailures int, lockout time.Duration) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET
+			totp_failed_attempts = CASE WHEN totp_failed_attempts + 1 >= $2 THEN 0 ELSE totp_failed_attempts + 1 END,
+			totp_locked_until = CASE WHEN totp_failed_attempts + 1 >= $2 THEN now() + make_interval(secs => $3) ELSE totp_locked_until END
+		WHERE id = $1`,
+		userID, maxFailures, lockout.Seconds(),
+	)
+	return err
+}
+
+// ResetTwoFactorFailures сбрасывает счетчик неверных кодов после верного
+func (s *PqStorage) ResetTwoFactorFailures(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET totp_failed_attempts = 0 WHERE id = $1 AND totp_failed_attempts > 0`,
+		userID,
+	)
+	return err
+}
+
+// ReplaceRecoveryCodesTx заменяет коды восстановления пользователя новыми
+func (s *PqStorage) ReplaceRecoveryCodesTx(ctx context.Context, tx Tx, userID string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)`,
+			uuid.New().String(), userID, hash,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode погашает код восстановления; false — кода нет или он уже использован
+func (s *PqStorage) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RecoveryCodesLeft возвращает количество неиспользованных кодов восстановления
+func (s *PqStorage) RecoveryCodesLeft(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := s.db.GetContext(
+		ctx,
+		&count,
+		`SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`,
+		userID,
+	)
+	return count, err
+}
+
+// CreateTwoFactorChallenge сохраняет токен второго шага входа; заодно удаляет истекшие
+func (s *PqStorage) CreateTwoFactorChallenge(ctx context.Context, challenge models.TwoFactorChallenge) error {
+	return s.Transaction(ctx, func(ctx context.Context, tx Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM two_factor_challenges WHERE expires_at < now()`); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO two_factor_challenges (id, user_id, token_hash, attempts, created_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			challenge.ID, challenge.UserID, challenge.TokenHash, challenge.Attempts, challenge.CreatedAt, challenge.ExpiresAt,
+		)
+		return err
+	})
+}
+
+// TwoFactorChallengeByHash ищет незавершенный вход по хэшу токена
+func (s *PqStorage) TwoFactorChallengeByHash(ctx context.Context, tokenHash string) (*models.TwoFactorChallenge, error) {
+	var challenge models.TwoFactorChallenge
+	err := s.db.GetContext(
+		ctx,
+		&challenge,
+		`SELECT id, user_id, token_hash, attempts, created_at, expires_at
+		FROM two_factor_challenges
+		WHERE token_hash = $1`,
+		tokenHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// FailTwoFactorChallenge учитывает неверный код; после maxAttempts попыток вход удаляется
+func (s *PqStorage) FailTwoFactorChallenge(ctx context.Context, challengeID string, maxAttempts int) error {
+	return s.Transaction(ctx, func(ctx context.Context, tx Tx) error {
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE id = $1`,
+			challengeID,
+		); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM two_factor_challenges WHERE id = $1 AND attempts >= $2`,
+			challengeID, maxAttempts,
+		)
+		return err
+	})
+}
+
+// DeleteTwoFactorChallenge завершает вход; false — токен уже использован параллельно
+func (s *PqStorage) DeleteTwoFactorChallenge(ctx context.Context, challengeID string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM two_factor_challenges WHERE id = $1`, challengeID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
@@ -27,12 +27,13 @@ func (s *PqStorage) UserCreate(ctx context.Context, user *models.UserWithPasswor
 
 	_, err := s.db.NamedExecContext(
 		ctx,
-		`insert into users(id, email, password_hash, base_currency, created_at)
-        values (:id, :email, :password_hash, :base_currency, :created_at)
+		`insert into users(id, email, password_hash, base_currency, lot_method, created_at)
+        values (:id, :email, :password_hash, :base_currency, :lot_method, :created_at)
         on conflict(id) do update
             set email=excluded.email,
                 password_hash=excluded.password_hash,
-                base_currency=excluded.base_currency`,
+                base_currency=excluded.base_currency,
+                lot_method=excluded.lot_method`,
 		user)
 	if err != nil {
 		return err
@@ -43,22 +44,92 @@ func (s *PqStorage) UserCreate(ctx context.Context, user *models.UserWithPasswor
 
 func (s *PqStorage) UserByID(ctx context.Context, userID string) (*models.User, error) {
 	var user models.User
-	err := s.db.Get(&user, `SELECT id, email, base_currency, created_at FROM users WHERE id = $1`, userID)
+	err := s.db.Get(&user, `SELECT id, email, base_currency, lot_method, display_name, locale, timezone, is_admin,
+		totp_enabled_at IS NOT NULL AS two_factor_enabled, email_verified_at IS NOT NULL AS email_verified, created_at
+		FROM users WHERE id = $1`, userID)
 
 	return &user, err
 }
 
 func (s *PqStorage) UserByEmail(ctx context.Context, email string) (*models.UserWithPassword, error) {
 	var user models.UserWithPassword
-	err := s.db.GetContext(ctx, &user, `SELECT id, email, password_hash, base_currency, created_at FROM users WHERE email = $1`, email)
+	err := s.db.GetContext(ctx, &user, `SELECT id, email, password_hash, base_currency, lot_method, created_at, password_changed_at FROM users WHERE email = $1`, email)
 	return &user, err
 }
 
+// UserWithPasswordByID возвращает пользователя с хэшем пароля для повторной проверки пароля
+func (s *PqStorage) UserWithPasswordByID(ctx context.Context, userID string) (*models.UserWithPassword, error) {
+	var user models.UserWithPassword
+	err := s.db.GetContext(ctx, &user, `SELECT id, email, password_hash, base_currency, lot_method, created_at, password_changed_at FROM users WHERE id = $1`, userID)
+	return &user, err
+}
+
+// UserSet обновляет email, настройки и профиль пользователя; при смене email подтверждение сбрасывается
 func (s *PqStorage) UserSet(ctx context.Context, user *models.User) error {
 	_, err := s.db.NamedExecContext(
 		ctx,
-		`UPDATE users SET email = :email WHERE id = :id`,
+		`UPDATE users
+		SET email = :email, base_currency = :base_currency, lot_method = :lot_method,
+			display_name = :display_name, locale = :locale, timezone = :timezone,
+			email_verified_at = CASE WHEN email = :email THEN email_verified_at ELSE NULL END
+		WHERE id = :id`,
 		user,
 	)
 	return err
 }
+
+// UserDelete удаляет пользователя вместе с активами, транзакциями и остальными данными (ON DELETE CASCADE)
+func (s *PqStorage) UserDelete(ctx context.Context, userID string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// AdminsCount возвращает количество администраторов
+func (s *PqStorage) AdminsCount(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM users WHERE is_admin`)
+	return count, err
+}
+
+// UserSettingsSetTx обновляет базовую валюту и метод подбора лотов пользователя через транзакцию
+func (s *PqStorage) UserSettingsSetTx(ctx context.Context, tx Tx, userID, baseCurrency, lotMethod string) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE users SET base_currency = $1, lot_method = $2 WHERE id = $3`,
+		baseCurrency, lotMethod, userID,
+	)
+	return err
+}
+
+// UserPasswordSet записывает новый хэш пароля и время смены
+func (s *PqStorage) UserPasswordSet(ctx context.Context, userID, passwordHash string) error {
+	return s.UserPasswordSetTx(ctx, s.db, userID, passwordHash)
+}
+
+// UserPasswordSetTx сохраняет новый хэш пароля через транзакцию
+func (s *PqStorage) UserPasswordSetTx(ctx context.Context, tx Tx, userID, passwordHash string) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE users SET password_hash = $2, password_changed_at = now() WHERE id = $1`,
+		userID, passwordHash,
+	)
+	return err
+}
+
+// VerifyUserEmail отмечает email подтвержденным, если он не сменился после отправки письма
+func (s *PqStorage) VerifyUserEmail(ctx context.Context, userID, email string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id = $1 AND email = $2`,
+		userID, email,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
@@ -1,50 +1,152 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+// jwtKeys ключи подписи и проверки; задаются при запуске через SetJWTKeys
+var jwtKeys *JWTKeys
 
-// Claims для JWT
+// SetJWTKeys задает ключи, которыми подписываются и проверяются токены
+func SetJWTKeys(keys *JWTKeys) {
+	jwtKeys = keys
+}
+
+// Claims для JWT: jti (ID) и sid связывают токен с сессией, чтобы его можно было отозвать
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT Генерация JWT токена
-func GenerateJWT(userID string) (string, error) {
-	claims := Claims{
-		UserID: userID,
+// GenerateJWT Генерация access-токена сессии с уникальным jti и сроком действия ttl.
+// Токен подписывается текущим ключом, его kid записывается в заголовок.
+func GenerateJWT(userID, sessionID string, ttl time.Duration) (string, *Claims, error) {
+	if jwtKeys == nil {
+		return "", nil, errors.New("jwt keys are not configured")
+	}
+	key := jwtKeys.signing
+
+	now := time.Now()
+	claims := &Claims{
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
-// ParseJWT Разбор JWT токена
+// ParseJWT Разбор JWT токена. Ключ проверки выбирается по kid из заголовка,
+// алгоритм токена должен совпадать с алгоритмом ключа.
 func ParseJWT(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Подтверждаем, что токен подписан с использованием HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+	if jwtKeys == nil {
+		return nil, errors.New("jwt keys are not configured")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, verifyKey)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Токены из писем (с aud) не являются access-токенами
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
+
+// verifyKey выбирает ключ проверки по kid из заголовка токена
+func verifyKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := jwtKeys.verify[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %q", kid)
+	}
+	// Подтверждаем, что токен подписан алгоритмом этого ключа
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// Назначения токенов из писем
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// ActionClaims claims токена из письма: sub — пользователь, aud — назначение,
+// jti — для однократного использования
+type ActionClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// GenerateActionToken подписывает токен из письма для пользователя и адреса email
+func GenerateActionToken(userID, email, purpose string, ttl time.Duration) (string, *ActionClaims, error) {
+	if jwtKeys == nil {
+		return "", nil, errors.New("jwt keys are not configured")
+	}
+	key := jwtKeys.signing
+
+	now := time.Now()
+	claims := &ActionClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{actionAudience(purpose)},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// ParseActionToken проверяет подпись, срок и назначение токена из письма
+func ParseActionToken(tokenStr, purpose string) (*ActionClaims, error) {
+	if jwtKeys == nil {
+		return nil, errors.New("jwt keys are not configured")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenStr, &ActionClaims{}, verifyKey,
+		jwt.WithAudience(actionAudience(purpose)), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*ActionClaims); ok && token.Valid && claims.ID != "" && claims.Subject != "" {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid token")
+}
+
+// actionAudience значение aud для назначения токена
+func actionAudience(purpose string) string {
+	return "brok:" + purpose
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// minSecretLength минимальная длина JWT_SECRET для HS256, байт
+	minSecretLength = 32
+	// minRSABits минимальный размер ключа RSA
+	minRSABits = 2048
+	// secretKeyID kid токенов, подписанных JWT_SECRET
+	secretKeyID = "hs256"
+)
+
+// JWTKey ключ подписи или проверки токенов
+type JWTKey struct {
+	ID     string // kid: отпечаток открытого ключа (RFC 7638)
+	Method jwt.SigningMethod
+
+	private interface{} // ключ подписи; nil, если ключ только для проверки
+	public  interface{} // ключ проверки (для HS256 — тот же секрет)
+}
+
+// JWTKeys набор ключей: новые токены подписываются одним ключом,
+// а проверяются всеми активными, чтобы ключи можно было менять без разлогина пользователей
+type JWTKeys struct {
+	signing *JWTKey
+	verify  map[string]*JWTKey
+}
+
+// SigningKey возвращает ключ, которым подписываются новые токены
+func (k *JWTKeys) SigningKey() *JWTKey {
+	return k.signing
+}
+
+// VerifyKeys возвращает количество ключей, которыми принимаются токены
+func (k *JWTKeys) VerifyKeys() int {
+	return len(k.verify)
+}
+
+// LoadJWTKeysFromEnv загружает ключи из переменных окружения:
+//   - JWT_SIGNING_KEY_FILE — PEM-файл закрытого ключа RSA (RS256, от 2048 бит) или Ed25519 (EdDSA);
+//   - JWT_VERIFY_KEY_FILES — PEM-файлы (через запятую) прежних или будущих ключей,
+//     которыми токены только проверяются;
+//   - JWT_SECRET — секрет HS256 (от 32 байт), если ключ подписи не задан.
+//
+// Без ключа подписи и без достаточно длинного секрета запуск невозможен.
+func LoadJWTKeysFromEnv() (*JWTKeys, error) {
+	keys := &JWTKeys{verify: map[string]*JWTKey{}}
+
+	path := strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY_FILE"))
+	if path == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("JWT_SIGNING_KEY_FILE or JWT_SECRET must be set")
+		}
+		if len(secret) < minSecretLength {
+			return nil, fmt.Errorf("JWT_SECRET is too weak: at least %d bytes required", minSecretLength)
+		}
+		if os.Getenv("JWT_VERIFY_KEY_FILES") != "" {
+			return nil, errors.New("JWT_VERIFY_KEY_FILES requires JWT_SIGNING_KEY_FILE")
+		}
+
+		keys.signing = &JWTKey{
+			ID:      secretKeyID,
+			Method:  jwt.SigningMethodHS256,
+			private: []byte(secret),
+			public:  []byte(secret),
+		}
+		keys.verify[secretKeyID] = keys.signing
+		return keys, nil
+	}
+
+	signing, err := loadJWTKey(path)
+	if err != nil {
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE: %w", err)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE: %s contains a public key, private key required", path)
+	}
+	keys.signing = signing
+	keys.verify[signing.ID] = signing
+
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFY_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := loadJWTKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_VERIFY_KEY_FILES: %w", err)
+		}
+		// Закрытый ключ прежней подписи нужен только для проверки
+		key.private = nil
+		if _, ok := keys.verify[key.ID]; !ok {
+			keys.verify[key.ID] = key
+		}
+	}
+
+	return keys, nil
+}
+
+// loadJWTKey читает PEM-файл с закрытым или открытым ключом RSA или Ed25519
+func loadJWTKey(path string) (*JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key := &JWTKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.private, key.public = k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.public = k
+	case ed25519.PrivateKey:
+		key.private, key.public = k, k.Public()
+	case ed25519.PublicKey:
+		key.public = k
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T, RSA or Ed25519 required", path, parsed)
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("%s: RSA key is too weak: %d bits, at least %d required", path, public.N.BitLen(), minRSABits)
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	}
+	key.ID = key.JWK().thumbprint()
+
+	return key, nil
+}
+
+// JWK открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (OKP)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet набор открытых ключей для /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK возвращает открытую часть ключа
+func (k *JWTKey) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// thumbprint отпечаток ключа по RFC 7638: SHA-256 от обязательных полей в лексикографическом порядке
+func (j JWK) thumbprint() string {
+	var members interface{}
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWKS возвращает открытые ключи проверки токенов; секрет HS256 не публикуется
+func JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if jwtKeys == nil {
+		return set
+	}
+	for _, key := range jwtKeys.verify {
+		if key.Method == jwt.SigningMethodHS256 {
+			continue
+		}
+		set.Keys = append(set.Keys, key.JWK())
+	}
+	// Текущий ключ подписи — первым, остальные в постоянном порядке
+	sort.Slice(set.Keys, func(i, j int) bool {
+		if (set.Keys[i].Kid == jwtKeys.signing.ID) != (set.Keys[j].Kid == jwtKeys.signing.ID) {
+			return set.Keys[i].Kid == jwtKeys.signing.ID
+		}
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}